@@ -5,15 +5,18 @@ import (
 	"time"
 
 	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/clock"
 )
 
-func Poll(updateInterval time.Duration, accounter prosper.Accounter) error {
+func Poll(updateInterval time.Duration, accounter prosper.Accounter, clk clock.Timer) error {
 	log.Printf("starting account polling")
 	accountUpdates := make(chan prosper.AccountInformation)
 	logger, err := NewRedisLogger(accountUpdates)
 	if err != nil {
 		return err
 	}
+	logger.clock = clk
 	go logger.Run()
 	go func() {
 		for {
@@ -23,7 +26,7 @@ func Poll(updateInterval time.Duration, accounter prosper.Accounter) error {
 			} else {
 				accountUpdates <- a
 			}
-			time.Sleep(updateInterval)
+			clk.Sleep(updateInterval)
 		}
 	}()
 
@@ -1,7 +1,6 @@
 package account
 
 import (
-	"encoding/json"
 	"errors"
 	"log"
 
@@ -112,8 +111,7 @@ func (r redisLogger) getAccountInformation() (prosper.AccountInformation, error)
 	if len(accountSerialized) < 1 {
 		return prosper.AccountInformation{}, errAccountInformationEmpty
 	}
-	var record redis.AccountRecord
-	err = json.Unmarshal([]byte(accountSerialized[0]), &record)
+	record, err := redis.DecodeAccountRecord(accountSerialized[0])
 	if err != nil {
 		return prosper.AccountInformation{}, err
 	}
@@ -121,11 +119,11 @@ func (r redisLogger) getAccountInformation() (prosper.AccountInformation, error)
 }
 
 func (r redisLogger) saveAccountInformation(record redis.AccountRecord) error {
-	serialized, err := json.Marshal(record)
+	serialized, err := redis.EncodeAccountRecord(record)
 	if err != nil {
 		return err
 	}
-	_, err = r.redis.LPush(redis.KeyAccountInformation, string(serialized))
+	_, err = r.redis.LPush(redis.KeyAccountInformation, serialized)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,159 @@
+package backtest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/buyer"
+	"github.com/mtlynch/prosperbot/portfolio"
+	"github.com/mtlynch/prosperbot/redis"
+)
+
+// Sizing decides how much to bid on each listing.
+type Sizing struct {
+	BidAmount float64
+	// MaxBidsPerDay limits how many listings are bought on each calendar day
+	// (UTC), in order of listing start time. Zero means no limit.
+	MaxBidsPerDay int
+}
+
+// Strategy is a candidate set of buying rules to evaluate.
+type Strategy struct {
+	SearchFilter     prosper.SearchFilter
+	ClientSideFilter buyer.ClientSideFilter
+	Sizing           Sizing
+}
+
+// DefaultStrategy returns the rules the bot currently buys with.
+func DefaultStrategy() Strategy {
+	return Strategy{
+		SearchFilter:     buyer.DefaultSearchFilter(),
+		ClientSideFilter: buyer.DefaultClientSideFilter(),
+		Sizing:           Sizing{BidAmount: 25.0},
+	}
+}
+
+// LoadStrategy reads a strategy from a JSON file. Fields missing from the file
+// keep the values of the default strategy.
+func LoadStrategy(path string) (Strategy, error) {
+	s := DefaultStrategy()
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Strategy{}, err
+	}
+	if err = json.Unmarshal(file, &s); err != nil {
+		return Strategy{}, err
+	}
+	return s, nil
+}
+
+// Result describes what a strategy would have done with the saved listings.
+type Result struct {
+	ListingsEvaluated int
+	WouldBuy          int
+	AmountInvested    float64
+	// ActualPurchases is the number of listings the bot actually bought.
+	ActualPurchases int
+	// Overlap is the number of listings both bought and selected by the
+	// strategy.
+	Overlap      int
+	OnlyStrategy int
+	OnlyActual   int
+	// Performance covers the selected listings that the bot actually bought,
+	// since those are the only ones with known outcomes.
+	Performance portfolio.Returns
+}
+
+type byStartDate []prosper.Listing
+
+func (b byStartDate) Len() int      { return len(b) }
+func (b byStartDate) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b byStartDate) Less(i, j int) bool {
+	if b[i].ListingStartDate.Equal(b[j].ListingStartDate) {
+		return b[i].ListingNumber < b[j].ListingNumber
+	}
+	return b[i].ListingStartDate.Before(b[j].ListingStartDate)
+}
+
+// selectListings returns the listings the strategy would have bought.
+func selectListings(s Strategy, candidates []prosper.Listing) []prosper.Listing {
+	sort.Sort(byStartDate(candidates))
+	selected := []prosper.Listing{}
+	perDay := map[string]int{}
+	for _, l := range candidates {
+		day := l.ListingStartDate.UTC().Format("2006-01-02")
+		if s.Sizing.MaxBidsPerDay > 0 && perDay[day] >= s.Sizing.MaxBidsPerDay {
+			continue
+		}
+		perDay[day]++
+		selected = append(selected, l)
+	}
+	return selected
+}
+
+// purchasedListings returns the listings with a successful bid.
+func purchasedListings(orders []redis.OrderRecord) map[prosper.ListingNumber]bool {
+	purchased := map[prosper.ListingNumber]bool{}
+	for _, o := range orders {
+		for _, b := range o.Order.BidStatus {
+			if b.Result == prosper.BidSucceeded {
+				purchased[b.ListingID] = true
+			}
+		}
+	}
+	return purchased
+}
+
+// Run replays every saved listing through the strategy and compares the result
+// with what the bot actually bought. Performance is measured as of asOf.
+func Run(r redis.RedisHistoryScanner, s Strategy, asOf time.Time) (Result, error) {
+	var result Result
+	candidates := []prosper.Listing{}
+	err := redis.ForEachListing(r, func(l prosper.Listing) error {
+		result.ListingsEvaluated++
+		if buyer.MatchesSearchFilter(s.SearchFilter, l) && s.ClientSideFilter.Filter(l) {
+			candidates = append(candidates, l)
+		}
+		return nil
+	})
+	if err != nil {
+		return Result{}, err
+	}
+	selected := selectListings(s, candidates)
+	result.WouldBuy = len(selected)
+	result.AmountInvested = float64(len(selected)) * s.Sizing.BidAmount
+
+	orders, err := redis.Orders(r)
+	if err != nil {
+		return Result{}, err
+	}
+	purchased := purchasedListings(orders)
+	result.ActualPurchases = len(purchased)
+	selectedSet := map[prosper.ListingNumber]bool{}
+	for _, l := range selected {
+		selectedSet[l.ListingNumber] = true
+		if purchased[l.ListingNumber] {
+			result.Overlap++
+		} else {
+			result.OnlyStrategy++
+		}
+	}
+	result.OnlyActual = result.ActualPurchases - result.Overlap
+
+	histories, err := redis.AllNoteHistories(r)
+	if err != nil {
+		return Result{}, err
+	}
+	outcomes := map[string][]redis.NoteRecord{}
+	for id, h := range histories {
+		if len(h) > 0 && selectedSet[h[len(h)-1].Note.ListingNumber] {
+			outcomes[id] = h
+		}
+	}
+	result.Performance = portfolio.Calculate(outcomes, asOf).Overall
+	return result, nil
+}
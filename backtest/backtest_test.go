@@ -0,0 +1,155 @@
+package backtest
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/interval"
+	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/buyer"
+	"github.com/mtlynch/prosperbot/redis"
+)
+
+type mockRedisReader struct {
+	values map[string]string
+	lists  map[string][]string
+}
+
+func (r mockRedisReader) Get(key string) (string, error) {
+	return r.values[key], nil
+}
+
+func (r mockRedisReader) Keys(pattern string) ([]string, error) {
+	prefix := strings.TrimSuffix(pattern, "*")
+	keys := []string{}
+	for k := range r.values {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	for k := range r.lists {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+
+func (r mockRedisReader) Scan(cursor int64, arguments ...interface{}) (int64, []string, error) {
+	keys, err := r.Keys(arguments[1].(string))
+	sort.Strings(keys)
+	return 0, keys, err
+}
+
+func (r mockRedisReader) LRange(key string, start int64, stop int64) ([]string, error) {
+	return r.lists[key], nil
+}
+
+func mustMarshal(t *testing.T, v interface{}) string {
+	s, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("failed to serialize %v: %v", v, err)
+	}
+	return string(s)
+}
+
+var (
+	day1 = time.Date(2016, 3, 1, 9, 0, 0, 0, time.UTC)
+	day2 = time.Date(2016, 3, 2, 9, 0, 0, 0, time.UTC)
+)
+
+func newStore(t *testing.T) mockRedisReader {
+	listings := []prosper.Listing{
+		{ListingNumber: 1, ListingStartDate: day1, EstimatedReturn: 0.08},
+		{ListingNumber: 2, ListingStartDate: day1.Add(time.Hour), EstimatedReturn: 0.09},
+		{ListingNumber: 3, ListingStartDate: day2, EstimatedReturn: 0.03},
+		{ListingNumber: 4, ListingStartDate: day2, EstimatedReturn: 0.10, CurrentDelinquencies: 2},
+	}
+	r := mockRedisReader{
+		values: map[string]string{},
+		lists:  map[string][]string{},
+	}
+	for _, l := range listings {
+		r.values[redis.KeyPrefixListing+mustMarshal(t, l.ListingNumber)] = mustMarshal(t, l)
+	}
+	order := redis.OrderRecord{
+		Order: prosper.OrderResponse{
+			OrderID: "order-1",
+			BidStatus: []prosper.BidStatus{
+				{BidRequest: prosper.BidRequest{ListingID: 2, BidAmount: 25}, Result: prosper.BidSucceeded},
+				{BidRequest: prosper.BidRequest{ListingID: 3, BidAmount: 25}, Result: prosper.BidSucceeded},
+				{BidRequest: prosper.BidRequest{ListingID: 4, BidAmount: 25}, Result: prosper.BidFailed},
+			},
+			OrderStatus: prosper.OrderCompleted,
+			OrderDate:   day2,
+		},
+		Timestamp: day2,
+	}
+	r.values[redis.KeyPrefixOrders+"order-1"] = mustMarshal(t, order)
+	note := redis.NoteRecord{
+		Note: prosper.Note{
+			LoanNoteID:                   "2-1",
+			ListingNumber:                2,
+			NoteOwnershipAmount:          25,
+			PrincipalBalanceProRataShare: 25,
+			OriginationDate:              day2,
+		},
+		Timestamp: day2,
+	}
+	r.lists[redis.KeyPrefixNote+"2-1"] = []string{mustMarshal(t, note)}
+	return r
+}
+
+func TestRun(t *testing.T) {
+	s := Strategy{
+		SearchFilter: prosper.SearchFilter{
+			EstimatedReturn: interval.Float64Range{Min: interval.CreateFloat64(0.05)},
+		},
+		ClientSideFilter: buyer.ClientSideFilter{
+			CurrentDelinquencies: interval.Int32Range{Max: interval.CreateInt32(0)},
+		},
+		Sizing: Sizing{BidAmount: 50},
+	}
+	got, err := Run(newStore(t), s, day2.AddDate(0, 1, 0))
+	if err != nil {
+		t.Fatalf("backtest failed: %v", err)
+	}
+	want := Result{
+		ListingsEvaluated: 4,
+		WouldBuy:          2,
+		AmountInvested:    100,
+		ActualPurchases:   2,
+		Overlap:           1,
+		OnlyStrategy:      1,
+		OnlyActual:        1,
+	}
+	perf := got.Performance
+	got.Performance = want.Performance
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected backtest result. got: %+v, want: %+v", got, want)
+	}
+	if perf.Invested != 25 {
+		t.Errorf("expected performance to cover only the owned selected note. got invested: %v, want: %v", perf.Invested, 25)
+	}
+}
+
+func TestRunMaxBidsPerDay(t *testing.T) {
+	s := Strategy{
+		Sizing: Sizing{BidAmount: 25, MaxBidsPerDay: 1},
+	}
+	got, err := Run(newStore(t), s, day2)
+	if err != nil {
+		t.Fatalf("backtest failed: %v", err)
+	}
+	if got.WouldBuy != 2 {
+		t.Errorf("expected one listing per day. got: %d, want: %d", got.WouldBuy, 2)
+	}
+	if got.Overlap != 1 {
+		t.Errorf("expected earliest listing of each day to be selected. got overlap: %d, want: %d", got.Overlap, 1)
+	}
+}
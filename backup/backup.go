@@ -0,0 +1,298 @@
+package backup
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/mtlynch/prosperbot/redis"
+)
+
+const (
+	format = "prosperbot-backup"
+	// Version is the archive format version written by Backup. Restore
+	// accepts archives with this version or older. Version 2 added TTLs.
+	Version = 2
+)
+
+var (
+	ErrStoreNotEmpty = errors.New("store already contains data")
+	ErrCorrupt       = errors.New("backup archive is corrupt")
+)
+
+// An archive is a gzipped stream of JSON lines: a Header, one Entry per key
+// with a checksum of its contents, and a Footer with the number of keys and a
+// checksum over all the entry checksums. Keys are written one at a time so
+// that large stores never have to fit in memory.
+type Header struct {
+	Format  string
+	Version int
+	Created time.Time
+}
+
+type Entry struct {
+	Key    string
+	Type   string
+	Values []string
+	// TTL is how many seconds the key had left before it expired, or zero if
+	// it never expires.
+	TTL      int64 `json:",omitempty"`
+	Checksum string
+}
+
+type Footer struct {
+	Keys     int
+	Checksum string
+}
+
+type line struct {
+	Entry  *Entry  `json:",omitempty"`
+	Footer *Footer `json:",omitempty"`
+}
+
+func (e Entry) checksum() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d:%s%d:%s", len(e.Key), e.Key, len(e.Type), e.Type)
+	for _, v := range e.Values {
+		fmt.Fprintf(h, "%d:%s", len(v), v)
+	}
+	// Entries without a TTL hash the same as they did in version 1.
+	if e.TTL > 0 {
+		fmt.Fprintf(h, "ttl:%d", e.TTL)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+var errUnsupportedType = errors.New("unsupported type")
+
+func readValues(r redis.RedisDumper, key, keyType string) ([]string, error) {
+	switch keyType {
+	case "string":
+		v, err := r.Get(key)
+		return []string{v}, err
+	case "list":
+		return r.LRange(key, 0, -1)
+	case "set":
+		return r.SMembers(key)
+	case "hash":
+		return r.HGetAll(key)
+	}
+	return nil, errUnsupportedType
+}
+
+// botKeys returns every key in the store that belongs to the bot, in order.
+// Other applications' keys in the same database are ignored.
+func botKeys(r redis.RedisDumper) ([]string, error) {
+	keys := []string{}
+	for _, pattern := range redis.KeyPatterns {
+		matching, err := r.Keys(pattern)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, matching...)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Backup writes every key the bot owns to w, along with the time each has
+// left before it expires, and returns the number of keys written. Keys of
+// types the archive can't hold are skipped with a warning.
+func Backup(r redis.RedisDumper, w io.Writer, created time.Time) (int, error) {
+	keys, err := botKeys(r)
+	if err != nil {
+		return 0, err
+	}
+
+	zw := gzip.NewWriter(w)
+	enc := json.NewEncoder(zw)
+	if err = enc.Encode(Header{format, Version, created}); err != nil {
+		return 0, err
+	}
+	total := sha256.New()
+	count := 0
+	for _, k := range keys {
+		keyType, err := r.Type(k)
+		if err != nil {
+			return count, err
+		}
+		if keyType == "none" {
+			// The key expired or was deleted since it was listed.
+			continue
+		}
+		values, err := readValues(r, k, keyType)
+		if err == errUnsupportedType {
+			log.Printf("warning: not backing up %s: unsupported type %s", k, keyType)
+			continue
+		} else if err != nil {
+			return count, err
+		}
+		ttl, err := r.TTL(k)
+		if err != nil {
+			return count, err
+		}
+		e := Entry{Key: k, Type: keyType, Values: values}
+		if ttl > 0 {
+			e.TTL = ttl
+		}
+		e.Checksum = e.checksum()
+		if err = enc.Encode(line{Entry: &e}); err != nil {
+			return count, err
+		}
+		io.WriteString(total, e.Checksum)
+		count++
+	}
+	footer := Footer{Keys: count, Checksum: hex.EncodeToString(total.Sum(nil))}
+	if err = enc.Encode(line{Footer: &footer}); err != nil {
+		return count, err
+	}
+	return count, zw.Close()
+}
+
+type reader struct {
+	dec   *json.Decoder
+	total hash.Hash
+	count int
+}
+
+func newReader(r io.Reader) (*reader, Header, error) {
+	zr, err := gzip.NewReader(bufio.NewReader(r))
+	if err != nil {
+		return nil, Header{}, err
+	}
+	dec := json.NewDecoder(zr)
+	var h Header
+	if err = dec.Decode(&h); err != nil {
+		return nil, Header{}, fmt.Errorf("failed to read backup header: %v", err)
+	}
+	if h.Format != format {
+		return nil, Header{}, fmt.Errorf("not a prosperbot backup: %q", h.Format)
+	}
+	if h.Version > Version {
+		return nil, Header{}, fmt.Errorf("backup version %d is newer than supported version %d", h.Version, Version)
+	}
+	return &reader{dec: dec, total: sha256.New()}, h, nil
+}
+
+// next returns the next verified entry, or io.EOF after a valid footer.
+func (rd *reader) next() (Entry, error) {
+	var l line
+	if err := rd.dec.Decode(&l); err != nil {
+		if err == io.EOF {
+			return Entry{}, fmt.Errorf("%v: missing footer", ErrCorrupt)
+		}
+		return Entry{}, fmt.Errorf("%v: %v", ErrCorrupt, err)
+	}
+	if l.Footer != nil {
+		if l.Footer.Keys != rd.count || l.Footer.Checksum != hex.EncodeToString(rd.total.Sum(nil)) {
+			return Entry{}, fmt.Errorf("%v: footer does not match contents", ErrCorrupt)
+		}
+		return Entry{}, io.EOF
+	}
+	if l.Entry == nil {
+		return Entry{}, fmt.Errorf("%v: unrecognized line", ErrCorrupt)
+	}
+	e := *l.Entry
+	if e.Checksum != e.checksum() {
+		return Entry{}, fmt.Errorf("%v: checksum mismatch for %s", ErrCorrupt, e.Key)
+	}
+	io.WriteString(rd.total, e.Checksum)
+	rd.count++
+	return e, nil
+}
+
+// Verify reads a whole archive and checks every checksum without touching the
+// store. It returns the archive header and the number of keys.
+func Verify(r io.Reader) (Header, int, error) {
+	rd, h, err := newReader(r)
+	if err != nil {
+		return Header{}, 0, err
+	}
+	for {
+		if _, err = rd.next(); err == io.EOF {
+			return h, rd.count, nil
+		} else if err != nil {
+			return Header{}, 0, err
+		}
+	}
+}
+
+func writeEntry(w redis.RedisLoader, e Entry) error {
+	if len(e.Values) == 0 {
+		return nil
+	}
+	values := make([]interface{}, len(e.Values))
+	for i, v := range e.Values {
+		values[i] = v
+	}
+	var err error
+	switch e.Type {
+	case "string":
+		_, err = w.Set(e.Key, e.Values[0])
+	case "list":
+		_, err = w.RPush(e.Key, values...)
+	case "set":
+		_, err = w.SAdd(e.Key, values...)
+	case "hash":
+		_, err = w.HMSet(e.Key, values...)
+	default:
+		err = fmt.Errorf("cannot restore %s: unsupported type %s", e.Key, e.Type)
+	}
+	return err
+}
+
+// Restore loads an archive into the store and returns the number of keys
+// restored. Keys that had a TTL get the same time to live again. Unless force
+// is set, it refuses to load into a store that already has keys belonging to
+// the bot. With force, archived keys replace existing keys of the same name
+// and other existing keys are left alone.
+//
+// Entries are checked as they are loaded, but a truncated archive is only
+// detected at the end, so callers should Verify the archive first.
+func Restore(w redis.RedisLoader, r io.Reader, force bool) (int, error) {
+	if !force {
+		for _, pattern := range redis.KeyPatterns {
+			existing, err := w.Keys(pattern)
+			if err != nil {
+				return 0, err
+			}
+			if len(existing) > 0 {
+				return 0, ErrStoreNotEmpty
+			}
+		}
+	}
+	rd, _, err := newReader(r)
+	if err != nil {
+		return 0, err
+	}
+	for {
+		e, err := rd.next()
+		if err == io.EOF {
+			return rd.count, nil
+		} else if err != nil {
+			return rd.count, err
+		}
+		if force {
+			if _, err = w.Del(e.Key); err != nil {
+				return rd.count, err
+			}
+		}
+		if err = writeEntry(w, e); err != nil {
+			return rd.count, err
+		}
+		if e.TTL > 0 {
+			if _, err = w.Expire(e.Key, uint64(e.TTL)); err != nil {
+				return rd.count, err
+			}
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// mockStore is an in-memory store holding lists, sets and hashes as slices.
+type mockStore struct {
+	types  map[string]string
+	values map[string][]string
+	ttls   map[string]int64
+}
+
+func newMockStore() *mockStore {
+	return &mockStore{types: map[string]string{}, values: map[string][]string{}, ttls: map[string]int64{}}
+}
+
+func (s *mockStore) Keys(pattern string) ([]string, error) {
+	keys := []string{}
+	for k := range s.types {
+		if k == pattern || (strings.HasSuffix(pattern, "*") && strings.HasPrefix(k, strings.TrimSuffix(pattern, "*"))) {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+
+func (s *mockStore) TTL(key string) (int64, error) {
+	if _, ok := s.types[key]; !ok {
+		return -2, nil
+	}
+	if ttl, ok := s.ttls[key]; ok {
+		return ttl, nil
+	}
+	return -1, nil
+}
+
+func (s *mockStore) Expire(key string, seconds uint64) (bool, error) {
+	s.ttls[key] = int64(seconds)
+	return true, nil
+}
+
+func (s *mockStore) Type(key string) (string, error) {
+	if t, ok := s.types[key]; ok {
+		return t, nil
+	}
+	return "none", nil
+}
+
+func (s *mockStore) Get(key string) (string, error) {
+	return s.values[key][0], nil
+}
+
+func (s *mockStore) LRange(key string, start int64, stop int64) ([]string, error) {
+	return s.values[key], nil
+}
+
+func (s *mockStore) SMembers(key string) ([]string, error) {
+	return s.values[key], nil
+}
+
+func (s *mockStore) HGetAll(key string) ([]string, error) {
+	return s.values[key], nil
+}
+
+func (s *mockStore) Del(keys ...string) (int64, error) {
+	for _, k := range keys {
+		delete(s.types, k)
+		delete(s.values, k)
+		delete(s.ttls, k)
+	}
+	return int64(len(keys)), nil
+}
+
+func (s *mockStore) add(key, keyType string, values ...interface{}) {
+	s.types[key] = keyType
+	for _, v := range values {
+		s.values[key] = append(s.values[key], v.(string))
+	}
+}
+
+func (s *mockStore) Set(key string, value interface{}) (string, error) {
+	s.types[key] = "string"
+	s.values[key] = []string{value.(string)}
+	return "OK", nil
+}
+
+func (s *mockStore) RPush(key string, values ...interface{}) (int64, error) {
+	s.add(key, "list", values...)
+	return int64(len(s.values[key])), nil
+}
+
+func (s *mockStore) SAdd(key string, members ...interface{}) (int64, error) {
+	s.add(key, "set", members...)
+	return int64(len(members)), nil
+}
+
+func (s *mockStore) HMSet(key string, values ...interface{}) (string, error) {
+	s.add(key, "hash", values...)
+	return "OK", nil
+}
+
+func populatedStore() *mockStore {
+	s := newMockStore()
+	s.add("accountInformation", "list", `{"Timestamp":"b"}`, `{"Timestamp":"a"}`)
+	s.add("listing:1", "string", `{"ListingNumber":1}`)
+	s.ttls["listing:1"] = 3600
+	s.add("note:1619-2", "list", `{"Note":{}}`)
+	s.add("order:abc", "string", `{"Order":{}}`)
+	s.add("seenListings", "set", "x", "y")
+	s.add("lineage:1", "hash", "field", "value")
+	return s
+}
+
+var created = time.Date(2016, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func TestBackupRestore(t *testing.T) {
+	src := populatedStore()
+	var archive bytes.Buffer
+	// Keys that aren't the bot's, or that the archive can't hold, are skipped.
+	store := populatedStore()
+	store.add("otherApp:key", "string", "ignored")
+	store.add("listing:scores", "zset", "ignored")
+	n, err := Backup(store, &archive, created)
+	if err != nil {
+		t.Fatalf("backup failed: %v", err)
+	}
+	if n != 6 {
+		t.Errorf("unexpected number of keys backed up. got: %d, want: %d", n, 6)
+	}
+
+	h, verified, err := Verify(bytes.NewReader(archive.Bytes()))
+	if err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+	if verified != n || h.Version != Version || !h.Created.Equal(created) {
+		t.Errorf("unexpected verify result. got: %+v (%d keys)", h, verified)
+	}
+
+	dst := newMockStore()
+	restored, err := Restore(dst, bytes.NewReader(archive.Bytes()), false)
+	if err != nil {
+		t.Fatalf("restore failed: %v", err)
+	}
+	if restored != n {
+		t.Errorf("unexpected number of keys restored. got: %d, want: %d", restored, n)
+	}
+	if !reflect.DeepEqual(dst, src) {
+		t.Errorf("restored store does not match original. got: %+v, want: %+v", dst, src)
+	}
+}
+
+func TestRestoreNonEmptyStore(t *testing.T) {
+	var archive bytes.Buffer
+	if _, err := Backup(populatedStore(), &archive, created); err != nil {
+		t.Fatalf("backup failed: %v", err)
+	}
+
+	foreign := newMockStore()
+	foreign.add("unrelated", "string", "kept")
+	if _, err := Restore(foreign, bytes.NewReader(archive.Bytes()), false); err != nil {
+		t.Errorf("keys that aren't the bot's should not block a restore, got: %v", err)
+	}
+
+	dst := newMockStore()
+	dst.add("note:1619-2", "list", "existing")
+	dst.add("unrelated", "string", "kept")
+	if _, err := Restore(dst, bytes.NewReader(archive.Bytes()), false); err != ErrStoreNotEmpty {
+		t.Fatalf("expected ErrStoreNotEmpty, got: %v", err)
+	}
+	if len(dst.types) != 2 {
+		t.Errorf("store should be untouched after refused restore. got: %+v", dst.types)
+	}
+
+	if _, err := Restore(dst, bytes.NewReader(archive.Bytes()), true); err != nil {
+		t.Fatalf("forced restore failed: %v", err)
+	}
+	if got := dst.values["note:1619-2"]; !reflect.DeepEqual(got, []string{`{"Note":{}}`}) {
+		t.Errorf("forced restore should replace existing keys. got: %v", got)
+	}
+	if got := dst.values["unrelated"]; !reflect.DeepEqual(got, []string{"kept"}) {
+		t.Errorf("forced restore should leave other keys alone. got: %v", got)
+	}
+}
+
+func TestVerifyCorrupt(t *testing.T) {
+	var archive bytes.Buffer
+	if _, err := Backup(populatedStore(), &archive, created); err != nil {
+		t.Fatalf("backup failed: %v", err)
+	}
+	zr, err := gzip.NewReader(&archive)
+	if err != nil {
+		t.Fatalf("failed to decompress archive: %v", err)
+	}
+	plain, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("failed to decompress archive: %v", err)
+	}
+	lines := strings.SplitAfter(string(plain), "\n")
+
+	compress := func(s string) []byte {
+		var b bytes.Buffer
+		zw := gzip.NewWriter(&b)
+		zw.Write([]byte(s))
+		zw.Close()
+		return b.Bytes()
+	}
+	var tests = []struct {
+		contents string
+		msg      string
+	}{
+		{strings.Replace(string(plain), `ListingNumber\":1`, `ListingNumber\":2`, 1), "modified value should fail checksum"},
+		{strings.Join(append(lines[:2:2], lines[3:]...), ""), "missing entry should fail footer check"},
+		{strings.Join(lines[:len(lines)-2], ""), "truncated archive should be rejected"},
+		{strings.Replace(string(plain), `"Version":2`, `"Version":99`, 1), "newer version should be rejected"},
+	}
+	for _, tt := range tests {
+		if _, _, err := Verify(bytes.NewReader(compress(tt.contents))); err == nil {
+			t.Errorf("%s: expected error", tt.msg)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package buyer
 
 import (
+	"fmt"
+
 	"github.com/mtlynch/gofn-prosper/interval"
 	"github.com/mtlynch/gofn-prosper/prosper"
 )
@@ -13,25 +15,53 @@ type ClientSideFilter struct {
 	EmploymentStatusDescriptionBlacklist      []string
 }
 
+// DefaultClientSideFilter returns the filter the bot applies to listings
+// before bidding on them.
+func DefaultClientSideFilter() ClientSideFilter {
+	return ClientSideFilter{
+		PriorProsperLoansLatePaymentsOneMonthPlus: interval.Int32Range{
+			Max: interval.CreateInt32(0),
+		},
+		PriorProsperLoansBalanceOutstanding: interval.Float64Range{
+			Max: interval.CreateFloat64(0.0),
+		},
+		CurrentDelinquencies: interval.Int32Range{
+			Max: interval.CreateInt32(0),
+		},
+		InquiriesLast6Months: interval.Int32Range{
+			Max: interval.CreateInt32(3),
+		},
+		EmploymentStatusDescriptionBlacklist: []string{
+			"Unemployed", "Not Available",
+		},
+	}
+}
+
 func (csf ClientSideFilter) Filter(l prosper.Listing) bool {
+	return csf.RejectReason(l) == ""
+}
+
+// RejectReason explains why the filter rejects a listing, or returns an empty
+// string if the listing passes.
+func (csf ClientSideFilter) RejectReason(l prosper.Listing) string {
 	if !isInInt32Range(csf.PriorProsperLoansLatePaymentsOneMonthPlus, int32(l.PriorProsperLoansLatePaymentsOneMonthPlus)) {
-		return false
+		return fmt.Sprintf("PriorProsperLoansLatePaymentsOneMonthPlus out of range: %d", l.PriorProsperLoansLatePaymentsOneMonthPlus)
 	}
 	if !isInFloat64Range(csf.PriorProsperLoansBalanceOutstanding, l.PriorProsperLoansBalanceOutstanding) {
-		return false
+		return fmt.Sprintf("PriorProsperLoansBalanceOutstanding out of range: %v", l.PriorProsperLoansBalanceOutstanding)
 	}
 	if !isInInt32Range(csf.CurrentDelinquencies, int32(l.CurrentDelinquencies)) {
-		return false
+		return fmt.Sprintf("CurrentDelinquencies out of range: %d", l.CurrentDelinquencies)
 	}
 	if !isInInt32Range(csf.InquiriesLast6Months, int32(l.InquiriesLast6Months)) {
-		return false
+		return fmt.Sprintf("InquiriesLast6Months out of range: %d", l.InquiriesLast6Months)
 	}
 	for _, blacklisted := range csf.EmploymentStatusDescriptionBlacklist {
 		if l.EmploymentStatusDescription == blacklisted {
-			return false
+			return fmt.Sprintf("EmploymentStatusDescription is blacklisted: %s", l.EmploymentStatusDescription)
 		}
 	}
-	return true
+	return ""
 }
 
 func isInInt32Range(r interval.Int32Range, v int32) bool {
@@ -107,3 +107,23 @@ func TestClientSideFilter(t *testing.T) {
 		}
 	}
 }
+
+func TestClientSideFilterRejectReason(t *testing.T) {
+	filter := ClientSideFilter{
+		CurrentDelinquencies:                 interval.NewInt32Range(0, 2),
+		EmploymentStatusDescriptionBlacklist: []string{"Unemployed"},
+	}
+	var tests = []struct {
+		listing prosper.Listing
+		want    string
+	}{
+		{prosper.Listing{}, ""},
+		{prosper.Listing{CurrentDelinquencies: 3}, "CurrentDelinquencies out of range: 3"},
+		{prosper.Listing{EmploymentStatusDescription: "Unemployed"}, "EmploymentStatusDescription is blacklisted: Unemployed"},
+	}
+	for _, tt := range tests {
+		if got := filter.RejectReason(tt.listing); got != tt.want {
+			t.Errorf("unexpected reject reason for listing: %+v. got = %q, want = %q", tt.listing, got, tt.want)
+		}
+	}
+}
@@ -1,57 +1,306 @@
 package buyer
 
 import (
+	"encoding/json"
+	"fmt"
 	"log"
+	"sort"
+	"time"
 
-	"github.com/mtlynch/gofn-prosper/interval"
 	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/clock"
+	"github.com/mtlynch/prosperbot/redis"
+	"github.com/mtlynch/prosperbot/scoring"
 )
 
+const defaultBatchWindow = 5 * time.Second
+
+// Ranking controls how the bot prioritizes listings that pass its filters.
+// The zero value bids on listings in the order they arrive.
+type Ranking struct {
+	Scorer scoring.Scorer
+	// BatchWindow is how long to collect listings before bidding on them in
+	// order of score. Defaults to 5s.
+	BatchWindow time.Duration
+}
+
+// Enabled reports whether listings are ranked before bidding.
+func (r Ranking) Enabled() bool {
+	return r.Scorer != nil
+}
+
 type listingBuyer struct {
 	listings  <-chan prosper.Listing
 	orders    chan<- prosper.OrderID
 	bidPlacer prosper.BidPlacer
 	bidAmount float64
+	// lineage, if set, records each decision and order in the listing's
+	// lineage.
+	lineage redis.RedisHashSetter
+	clock   clock.Timer
+	policy  Policy
+	// accounts, if set, is read for the available cash before bidding.
+	accounts redis.RedisReader
+	// borrowers, if set, tracks the bot's exposure to each borrower.
+	borrowers redis.RedisBorrowerIndexer
+	// reevaluation, if set, queues declined listings to be reconsidered.
+	reevaluation redis.RedisReevaluationQueuer
+	// claims, if set, records each listing before it is bid on, so that no
+	// listing is bid on twice.
+	claims redis.RedisBidClaimer
+}
+
+type scoredListing struct {
+	listing prosper.Listing
+	score   float64
+}
+
+type byScore []scoredListing
+
+func (s byScore) Len() int           { return len(s) }
+func (s byScore) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byScore) Less(i, j int) bool { return s[i].score > s[j].score }
+
+// placedBid is a bid that may not yet be reflected in the saved account
+// information.
+type placedBid struct {
+	amount float64
+	placed time.Time
 }
 
 func (lb listingBuyer) Run() {
-	csf := ClientSideFilter{
-		PriorProsperLoansLatePaymentsOneMonthPlus: interval.Int32Range{
-			Max: interval.CreateInt32(0),
-		},
-		PriorProsperLoansBalanceOutstanding: interval.Float64Range{
-			Max: interval.CreateFloat64(0.0),
-		},
-		CurrentDelinquencies: interval.Int32Range{
-			Max: interval.CreateInt32(0),
-		},
-		InquiriesLast6Months: interval.Int32Range{
-			Max: interval.CreateInt32(3),
-		},
-		EmploymentStatusDescriptionBlacklist: []string{
-			"Unemployed", "Not Available",
-		},
-	}
+	csf := DefaultClientSideFilter()
+	batch := []scoredListing{}
+	var flush <-chan time.Time
+	var placed []placedBid
 	for {
-		listing, more := <-lb.listings
-		if !more {
-			return
+		select {
+		case listing, more := <-lb.listings:
+			if !more {
+				lb.bidInOrder(batch, placed)
+				return
+			}
+			if lb.alreadyOrdered(listing.ListingNumber) || inBatch(batch, listing.ListingNumber) {
+				continue
+			}
+			// TODO: Do purchase filtering in a cleaner place
+			reason := csf.RejectReason(listing)
+			if reason == "" {
+				reason = lb.policy.Risk.RejectReason(listing)
+			}
+			if !lb.policy.Ranking.Enabled() {
+				if reason == "" {
+					reason = lb.exposureRejectReason(listing)
+				}
+				if reason == "" {
+					var cash float64
+					var known bool
+					cash, known, placed = lb.availableCash(placed)
+					if known && cash < lb.bidAmount {
+						reason = fmt.Sprintf("insufficient cash: $%.2f available", cash)
+					}
+				}
+				lb.decide(listing, reason, nil)
+				if reason == "" && lb.bid(listing) && lb.accounts != nil {
+					placed = append(placed, placedBid{amount: lb.bidAmount, placed: lb.clock.Now()})
+				}
+				continue
+			}
+			score := lb.policy.Ranking.Scorer.Score(listing)
+			if reason != "" {
+				lb.decide(listing, reason, &score)
+				continue
+			}
+			batch = append(batch, scoredListing{listing, score})
+			if flush == nil {
+				window := lb.policy.Ranking.BatchWindow
+				if window == 0 {
+					window = defaultBatchWindow
+				}
+				flush = lb.clock.After(window)
+			}
+		case <-flush:
+			placed = lb.bidInOrder(batch, placed)
+			batch = []scoredListing{}
+			flush = nil
 		}
-		// TODO: Do purchase filtering in a cleaner place
-		if !csf.Filter(listing) {
+	}
+}
+
+// bidInOrder bids on the batch of listings from highest score to lowest, for
+// as long as there is cash to cover the bids and within the limits on
+// exposure to each borrower. It returns the bids placed so
+// far that the saved account information may not include yet.
+func (lb listingBuyer) bidInOrder(batch []scoredListing, placed []placedBid) []placedBid {
+	if len(batch) == 0 {
+		return placed
+	}
+	sort.Stable(byScore(batch))
+	cash, known, placed := lb.availableCash(placed)
+	for i, s := range batch {
+		score := s.score
+		if reason := lb.exposureRejectReason(s.listing); reason != "" {
+			lb.decide(s.listing, reason, &score)
 			continue
 		}
-
-		// TODO: Add in retries.
-		orderResponse, err := lb.bidPlacer.PlaceBid(prosper.BidRequest{
-			ListingID: listing.ListingNumber,
-			BidAmount: lb.bidAmount,
-		})
-		if err != nil {
-			log.Printf("failed to place bid on listing %v: %v", listing.ListingNumber, err)
+		if known && cash < lb.bidAmount {
+			lb.decide(s.listing, fmt.Sprintf("insufficient cash: $%.2f available for listing ranked %d of %d", cash, i+1, len(batch)), &score)
 			continue
 		}
-		log.Printf("placed bid, order ID: %v, listing: %v", orderResponse.OrderID, listing.ListingNumber)
-		go func() { lb.orders <- orderResponse.OrderID }()
+		lb.decide(s.listing, "", &score)
+		if lb.bid(s.listing) {
+			cash -= lb.bidAmount
+			placed = append(placed, placedBid{amount: lb.bidAmount, placed: lb.clock.Now()})
+		}
+	}
+	return placed
+}
+
+// availableCash estimates the cash available to bid with from the latest
+// saved account information, less the bids placed since it was saved. It
+// reports false if the cash is unknown. It also returns the bids that are
+// still newer than the account information.
+func (lb listingBuyer) availableCash(placed []placedBid) (float64, bool, []placedBid) {
+	if lb.accounts == nil {
+		return 0, false, placed
+	}
+	a, err := redis.LatestAccount(lb.accounts)
+	if err != nil {
+		if err != redis.ErrAccountNotFound {
+			log.Printf("failed to read available cash: %v", err)
+		}
+		return 0, false, placed
+	}
+	cash := a.Value.AvailableCashBalance
+	pending := []placedBid{}
+	for _, b := range placed {
+		if b.placed.After(a.Timestamp) {
+			cash -= b.amount
+			pending = append(pending, b)
+		}
+	}
+	return cash, true, pending
+}
+
+// bid places a bid on the listing and reports whether it succeeded. A bid
+// that fails is not retried right away. Its claim is released and the listing
+// is queued to be reconsidered, which checks the listing again first.
+func (lb listingBuyer) bid(listing prosper.Listing) bool {
+	if !lb.claim(listing.ListingNumber) {
+		return false
+	}
+	// TODO: Add in retries.
+	orderResponse, err := lb.bidPlacer.PlaceBid(prosper.BidRequest{
+		ListingID: listing.ListingNumber,
+		BidAmount: lb.bidAmount,
+	})
+	if err != nil {
+		log.Printf("failed to place bid on listing %v: %v", listing.ListingNumber, err)
+		lb.release(listing.ListingNumber)
+		lb.queueReevaluation(listing)
+		return false
+	}
+	log.Printf("placed bid, order ID: %v, listing: %v", orderResponse.OrderID, listing.ListingNumber)
+	lb.recordLineage(listing.ListingNumber, redis.LineageFieldOrder, string(orderResponse.OrderID))
+	lb.recordBorrowerBid(listing)
+	go func() { lb.orders <- orderResponse.OrderID }()
+	return true
+}
+
+// decide records the decision on a listing. Declined listings are queued to be
+// reconsidered.
+func (lb listingBuyer) decide(l prosper.Listing, reason string, score *float64) {
+	lb.recordDecision(l.ListingNumber, reason, score)
+	if reason != "" {
+		lb.queueReevaluation(l)
+	}
+}
+
+func (lb listingBuyer) queueReevaluation(l prosper.Listing) {
+	if lb.reevaluation == nil {
+		return
+	}
+	if err := redis.QueueReevaluation(lb.reevaluation, l); err != nil {
+		log.Printf("failed to queue listing %v for re-evaluation: %v", l.ListingNumber, err)
+	}
+}
+
+// claim records that the bot is about to bid on the listing, and reports
+// whether the bid may go ahead.
+func (lb listingBuyer) claim(n prosper.ListingNumber) bool {
+	if lb.claims == nil {
+		return true
+	}
+	claimed, err := redis.ClaimBid(lb.claims, n, lb.clock.Now())
+	if err != nil {
+		// Without a claim, the only safe choice is not to bid.
+		log.Printf("failed to claim listing %v before bidding: %v", n, err)
+		return false
+	}
+	if !claimed {
+		log.Printf("not bidding on listing %v, it was already bid on", n)
+	}
+	return claimed
+}
+
+// release removes the claim on a listing whose bid failed, so that it may be
+// bid on again.
+func (lb listingBuyer) release(n prosper.ListingNumber) {
+	if lb.claims == nil {
+		return
+	}
+	if err := redis.ReleaseBid(lb.claims, n); err != nil {
+		log.Printf("failed to release claim on listing %v: %v", n, err)
+	}
+}
+
+// alreadyOrdered reports whether the bot has bid on the listing before, so
+// that a listing being reconsidered is never bid on twice.
+func (lb listingBuyer) alreadyOrdered(n prosper.ListingNumber) bool {
+	if lb.claims == nil {
+		return false
+	}
+	ordered, err := redis.HasBid(lb.claims, n)
+	if err != nil {
+		// Without knowing, the only safe choice is not to bid.
+		log.Printf("failed to check for bids on listing %v: %v", n, err)
+		return true
+	}
+	return ordered
+}
+
+func inBatch(batch []scoredListing, n prosper.ListingNumber) bool {
+	for _, s := range batch {
+		if s.listing.ListingNumber == n {
+			return true
+		}
+	}
+	return false
+}
+
+func (lb listingBuyer) recordDecision(n prosper.ListingNumber, reason string, score *float64) {
+	if lb.lineage == nil {
+		return
+	}
+	serialized, err := json.Marshal(redis.Decision{
+		Passed:    reason == "",
+		Reason:    reason,
+		Score:     score,
+		Timestamp: lb.clock.Now(),
+	})
+	if err != nil {
+		log.Printf("failed to serialize decision on listing %v: %v", n, err)
+		return
+	}
+	lb.recordLineage(n, redis.LineageFieldDecision, string(serialized))
+}
+
+func (lb listingBuyer) recordLineage(n prosper.ListingNumber, field, value string) {
+	if lb.lineage == nil {
+		return
+	}
+	if _, err := lb.lineage.HSet(redis.LineageKey(n), field, value); err != nil {
+		log.Printf("failed to record lineage of listing %v: %v", n, err)
 	}
 }
@@ -5,8 +5,13 @@ import (
 	"reflect"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/clock"
+	"github.com/mtlynch/prosperbot/redis"
+	"github.com/mtlynch/prosperbot/scoring"
 )
 
 type mockBidPlacer struct {
@@ -98,3 +103,303 @@ func TestListingBuyer(t *testing.T) {
 		}
 	}
 }
+
+type mockRedisHashSetter struct {
+	hashes map[string]map[string]string
+}
+
+func (r *mockRedisHashSetter) HSet(key string, field string, value interface{}) (bool, error) {
+	if r.hashes[key] == nil {
+		r.hashes[key] = map[string]string{}
+	}
+	r.hashes[key][field] = value.(string)
+	return true, nil
+}
+
+func TestListingBuyerRecordsLineage(t *testing.T) {
+	listings := make(chan prosper.Listing)
+	orderIDs := make(chan prosper.OrderID)
+	lineage := mockRedisHashSetter{hashes: map[string]map[string]string{}}
+	buyer := listingBuyer{
+		listings:  listings,
+		orders:    orderIDs,
+		bidPlacer: &mockBidPlacer{orderIDs: prosper.OrderIDs{orderIDA}, errs: []error{nil}},
+		lineage:   &lineage,
+		clock:     clock.NewVirtual(time.Date(2016, 3, 5, 11, 40, 15, 0, time.UTC)),
+	}
+	go func() {
+		listings <- prosper.Listing{ListingNumber: listingIDA}
+		listings <- prosper.Listing{ListingNumber: listingIDB, CurrentDelinquencies: 5}
+		close(listings)
+	}()
+	buyer.Run()
+	<-orderIDs
+	want := map[string]map[string]string{
+		"lineage:123": {
+			"decision": `{"Passed":true,"Timestamp":"2016-03-05T11:40:15Z"}`,
+			"order":    "order-a",
+		},
+		"lineage:456": {
+			"decision": `{"Passed":false,"Reason":"CurrentDelinquencies out of range: 5","Timestamp":"2016-03-05T11:40:15Z"}`,
+		},
+	}
+	if !reflect.DeepEqual(lineage.hashes, want) {
+		t.Errorf("unexpected lineage. got: %+v, want: %+v", lineage.hashes, want)
+	}
+}
+
+func TestListingBuyerRanksListings(t *testing.T) {
+	now := time.Date(2016, 3, 5, 11, 40, 15, 0, time.UTC)
+	v := clock.NewVirtual(now)
+	store := redis.NewMemoryStore(v)
+	r := store.Client()
+	account, err := redis.EncodeAccountRecord(redis.AccountRecord{
+		Value:     prosper.AccountInformation{AvailableCashBalance: 60.0},
+		Timestamp: now.Add(-1 * time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("failed to encode account record: %v", err)
+	}
+	r.LPush(redis.KeyAccountInformation, account)
+
+	listings := make(chan prosper.Listing)
+	orderIDs := make(chan prosper.OrderID)
+	buyer := listingBuyer{
+		listings:  listings,
+		orders:    orderIDs,
+		bidPlacer: &mockBidPlacer{orderIDs: prosper.OrderIDs{orderIDA, orderIDB}, errs: []error{nil, nil}},
+		bidAmount: 25.0,
+		lineage:   r,
+		clock:     v,
+		policy: Policy{
+			Ranking: Ranking{
+				Scorer:      scoring.LinearModel{Weights: map[string]float64{"EstimatedReturn": 100.0}},
+				BatchWindow: 5 * time.Second,
+			},
+		},
+		accounts: r,
+	}
+	done := make(chan bool)
+	go func() {
+		buyer.Run()
+		done <- true
+	}()
+	listings <- prosper.Listing{ListingNumber: 111, EstimatedReturn: 0.05}
+	listings <- prosper.Listing{ListingNumber: 222, EstimatedReturn: 0.09}
+	listings <- prosper.Listing{ListingNumber: 333, CurrentDelinquencies: 5, EstimatedReturn: 0.10}
+	listings <- prosper.Listing{ListingNumber: 444, EstimatedReturn: 0.07}
+	v.BlockUntil(1)
+	v.Advance(5 * time.Second)
+	<-orderIDs
+	<-orderIDs
+	close(listings)
+	<-done
+
+	decisions := map[prosper.ListingNumber]string{}
+	orders := map[prosper.ListingNumber]string{}
+	for _, n := range []prosper.ListingNumber{111, 222, 333, 444} {
+		fields, err := r.HGetAll(redis.LineageKey(n))
+		if err != nil {
+			t.Fatalf("failed to read lineage of %d: %v", n, err)
+		}
+		for i := 0; i+1 < len(fields); i += 2 {
+			switch fields[i] {
+			case redis.LineageFieldDecision:
+				decisions[n] = fields[i+1]
+			case redis.LineageFieldOrder:
+				orders[n] = fields[i+1]
+			}
+		}
+	}
+	wantDecisions := map[prosper.ListingNumber]string{
+		111: `{"Passed":false,"Reason":"insufficient cash: $10.00 available for listing ranked 3 of 3","Score":5,"Timestamp":"2016-03-05T11:40:20Z"}`,
+		222: `{"Passed":true,"Score":9,"Timestamp":"2016-03-05T11:40:20Z"}`,
+		333: `{"Passed":false,"Reason":"CurrentDelinquencies out of range: 5","Score":10,"Timestamp":"2016-03-05T11:40:15Z"}`,
+		444: `{"Passed":true,"Score":7.000000000000001,"Timestamp":"2016-03-05T11:40:20Z"}`,
+	}
+	if !reflect.DeepEqual(decisions, wantDecisions) {
+		t.Errorf("unexpected decisions. got: %+v, want: %+v", decisions, wantDecisions)
+	}
+	wantOrders := map[prosper.ListingNumber]string{222: "order-a", 444: "order-b"}
+	if !reflect.DeepEqual(orders, wantOrders) {
+		t.Errorf("unexpected orders. got: %+v, want: %+v", orders, wantOrders)
+	}
+}
+
+func TestListingBuyerLimitsBorrowerExposure(t *testing.T) {
+	r := redis.NewMemoryStore(clock.DefaultClock{}).Client()
+	if err := redis.RecordBorrowerBid(r, "member:ABC123", 111, 25.0); err != nil {
+		t.Fatalf("failed to record bid: %v", err)
+	}
+	listings := make(chan prosper.Listing)
+	orderIDs := make(chan prosper.OrderID)
+	buyer := listingBuyer{
+		listings:  listings,
+		orders:    orderIDs,
+		bidPlacer: &mockBidPlacer{orderIDs: prosper.OrderIDs{orderIDA}, errs: []error{nil}},
+		bidAmount: 25.0,
+		lineage:   r,
+		clock:     clock.NewVirtual(time.Date(2016, 3, 5, 11, 40, 15, 0, time.UTC)),
+		policy:    Policy{MaxExposurePerBorrower: 60.0},
+		borrowers: r,
+	}
+	go func() {
+		listings <- prosper.Listing{ListingNumber: 222, MemberKey: "ABC123"}
+		listings <- prosper.Listing{ListingNumber: 333, MemberKey: "ABC123"}
+		close(listings)
+	}()
+	buyer.Run()
+	if got := <-orderIDs; got != orderIDA {
+		t.Errorf("unexpected order. got = %v, want = %v", got, orderIDA)
+	}
+	fields, err := r.HGetAll(redis.LineageKey(333))
+	if err != nil {
+		t.Fatalf("failed to read lineage: %v", err)
+	}
+	want := []string{"decision", `{"Passed":false,"Reason":"borrower exposure $50.00 plus bid of $25.00 would exceed $60.00","Timestamp":"2016-03-05T11:40:15Z"}`}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("unexpected lineage. got = %+v, want = %+v", fields, want)
+	}
+}
+
+func TestListingBuyerQueuesDeclinedListings(t *testing.T) {
+	r := redis.NewMemoryStore(clock.DefaultClock{}).Client()
+	if _, err := r.HSet(redis.LineageKey(listingIDB), redis.LineageFieldOrder, string(orderIDB)); err != nil {
+		t.Fatalf("failed to record order: %v", err)
+	}
+	start := time.Date(2016, 3, 5, 9, 0, 0, 0, time.UTC)
+	listings := make(chan prosper.Listing)
+	orderIDs := make(chan prosper.OrderID)
+	bidPlacer := &mockBidPlacer{orderIDs: prosper.OrderIDs{orderIDA}, errs: []error{nil}}
+	buyer := listingBuyer{
+		listings:     listings,
+		orders:       orderIDs,
+		bidPlacer:    bidPlacer,
+		bidAmount:    25.0,
+		lineage:      r,
+		clock:        clock.NewVirtual(time.Date(2016, 3, 5, 11, 40, 15, 0, time.UTC)),
+		reevaluation: r,
+		claims:       r,
+	}
+	go func() {
+		listings <- prosper.Listing{ListingNumber: 789, ListingStartDate: start, CurrentDelinquencies: 2}
+		// The bot already bid on this listing, so it must not bid again.
+		listings <- prosper.Listing{ListingNumber: listingIDB, ListingStartDate: start}
+		listings <- prosper.Listing{ListingNumber: listingIDA, ListingStartDate: start}
+		close(listings)
+	}()
+	buyer.Run()
+	if got := <-orderIDs; got != orderIDA {
+		t.Errorf("unexpected order. got = %v, want = %v", got, orderIDA)
+	}
+	if len(bidPlacer.orderIDs) != 0 {
+		t.Errorf("expected exactly one bid, %d bids unplaced", len(bidPlacer.orderIDs))
+	}
+	queued, err := r.SMembers(redis.ReevaluationKey(start))
+	if err != nil {
+		t.Fatalf("failed to read re-evaluation queue: %v", err)
+	}
+	if want := []string{"789"}; !reflect.DeepEqual(queued, want) {
+		t.Errorf("unexpected re-evaluation queue. got = %v, want = %v", queued, want)
+	}
+}
+
+func TestListingBuyerClaimsListingsBeforeBidding(t *testing.T) {
+	r := redis.NewMemoryStore(clock.DefaultClock{}).Client()
+	start := time.Date(2016, 3, 5, 9, 0, 0, 0, time.UTC)
+	listings := make(chan prosper.Listing)
+	orderIDs := make(chan prosper.OrderID)
+	// The bid on the second listing fails.
+	bidPlacer := &mockBidPlacer{orderIDs: prosper.OrderIDs{orderIDA, orderIDB}, errs: []error{nil, genericErr}}
+	buyer := listingBuyer{
+		listings:     listings,
+		orders:       orderIDs,
+		bidPlacer:    bidPlacer,
+		bidAmount:    25.0,
+		clock:        clock.NewVirtual(time.Date(2016, 3, 5, 11, 40, 15, 0, time.UTC)),
+		reevaluation: r,
+		claims:       r,
+	}
+	go func() {
+		listings <- prosper.Listing{ListingNumber: listingIDA, ListingStartDate: start}
+		listings <- prosper.Listing{ListingNumber: listingIDA, ListingStartDate: start}
+		listings <- prosper.Listing{ListingNumber: listingIDB, ListingStartDate: start}
+		close(listings)
+	}()
+	buyer.Run()
+	if got := <-orderIDs; got != orderIDA {
+		t.Errorf("unexpected order. got = %v, want = %v", got, orderIDA)
+	}
+	if len(bidPlacer.orderIDs) != 0 {
+		t.Errorf("expected exactly two bids, %d bids unplaced", len(bidPlacer.orderIDs))
+	}
+	if claimed, err := r.Exists(redis.BidClaimKey(listingIDA)); err != nil || !claimed {
+		t.Errorf("listing should stay claimed after a bid. got = %v, %v", claimed, err)
+	}
+	if claimed, err := r.Exists(redis.BidClaimKey(listingIDB)); err != nil || claimed {
+		t.Errorf("listing should be released after a failed bid. got = %v, %v", claimed, err)
+	}
+	queued, err := r.SMembers(redis.ReevaluationKey(start))
+	if err != nil {
+		t.Fatalf("failed to read re-evaluation queue: %v", err)
+	}
+	if want := []string{"456"}; !reflect.DeepEqual(queued, want) {
+		t.Errorf("listing with a failed bid should be queued for re-evaluation. got = %v, want = %v", queued, want)
+	}
+}
+
+func TestListingBuyerChecksCash(t *testing.T) {
+	now := time.Date(2016, 3, 5, 11, 40, 15, 0, time.UTC)
+	r := redis.NewMemoryStore(clock.DefaultClock{}).Client()
+	account, err := redis.EncodeAccountRecord(redis.AccountRecord{
+		Value:     prosper.AccountInformation{AvailableCashBalance: 40.0},
+		Timestamp: now.Add(-1 * time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("failed to encode account record: %v", err)
+	}
+	r.LPush(redis.KeyAccountInformation, account)
+	start := time.Date(2016, 3, 5, 9, 0, 0, 0, time.UTC)
+	listings := make(chan prosper.Listing)
+	orderIDs := make(chan prosper.OrderID)
+	bidPlacer := &mockBidPlacer{orderIDs: prosper.OrderIDs{orderIDA}, errs: []error{nil}}
+	buyer := listingBuyer{
+		listings:     listings,
+		orders:       orderIDs,
+		bidPlacer:    bidPlacer,
+		bidAmount:    25.0,
+		lineage:      r,
+		clock:        clock.NewVirtual(now),
+		accounts:     r,
+		reevaluation: r,
+		claims:       r,
+	}
+	go func() {
+		listings <- prosper.Listing{ListingNumber: listingIDA, ListingStartDate: start}
+		// The first bid leaves too little cash for a second.
+		listings <- prosper.Listing{ListingNumber: listingIDB, ListingStartDate: start}
+		close(listings)
+	}()
+	buyer.Run()
+	if got := <-orderIDs; got != orderIDA {
+		t.Errorf("unexpected order. got = %v, want = %v", got, orderIDA)
+	}
+	fields, err := r.HGetAll(redis.LineageKey(listingIDB))
+	if err != nil {
+		t.Fatalf("failed to read lineage: %v", err)
+	}
+	want := []string{"decision", `{"Passed":false,"Reason":"insufficient cash: $15.00 available","Timestamp":"2016-03-05T11:40:15Z"}`}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("unexpected lineage. got = %+v, want = %+v", fields, want)
+	}
+	if claimed, err := r.Exists(redis.BidClaimKey(listingIDB)); err != nil || claimed {
+		t.Errorf("listing without cash to bid should not be claimed. got = %v, %v", claimed, err)
+	}
+	queued, err := r.SMembers(redis.ReevaluationKey(start))
+	if err != nil {
+		t.Fatalf("failed to read re-evaluation queue: %v", err)
+	}
+	if want := []string{"456"}; !reflect.DeepEqual(queued, want) {
+		t.Errorf("listing without cash to bid should be queued for re-evaluation. got = %v, want = %v", queued, want)
+	}
+}
@@ -0,0 +1,134 @@
+package buyer
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/clock"
+	"github.com/mtlynch/prosperbot/redis"
+)
+
+// listingObserver follows the listings that the bot has seen after they drop
+// out of the search for new listings, which only looks back a minute. On a
+// fixed interval, it searches once for every listing that started between the
+// oldest and newest watched listing and adds any change to the history of each
+// watched listing, until the listing is no longer active or its snapshot
+// expires.
+type listingObserver struct {
+	s            prosper.ListingSearcher
+	redis        redis.RedisListingObserver
+	pollInterval time.Duration
+	clock        clock.Timer
+}
+
+// ObserveListings starts following the funding of active listings that the
+// bot has seen, searching for all of them once per interval.
+func ObserveListings(interval time.Duration, s prosper.ListingSearcher, clk clock.Timer) error {
+	r, err := redis.New()
+	if err != nil {
+		return err
+	}
+	o := listingObserver{
+		s:            s,
+		redis:        r,
+		pollInterval: interval,
+		clock:        clk,
+	}
+	go o.Run()
+	return nil
+}
+
+func (o listingObserver) Run() {
+	log.Printf("observing active listings every %v", o.pollInterval)
+	for {
+		o.observe()
+		o.clock.Sleep(o.pollInterval)
+	}
+}
+
+// observe saves an observation of every watched listing.
+func (o listingObserver) observe() {
+	snapshots, err := o.watched()
+	if err != nil {
+		log.Printf("failed to read watched listings: %v", err)
+		return
+	}
+	if len(snapshots) == 0 {
+		return
+	}
+	min, max := snapshots[0].ListingStartDate, snapshots[0].ListingStartDate
+	for _, l := range snapshots {
+		if l.ListingStartDate.Before(min) {
+			min = l.ListingStartDate
+		}
+		if l.ListingStartDate.After(max) {
+			max = l.ListingStartDate
+		}
+	}
+	listings, err := searchStartedBetween(o.s, min, max)
+	if err != nil {
+		log.Printf("failed to search for watched listings: %v", err)
+		return
+	}
+	found := map[prosper.ListingNumber]prosper.Listing{}
+	for _, l := range listings {
+		found[l.ListingNumber] = l
+	}
+	for _, l := range snapshots {
+		if err = o.observeListing(l.ListingNumber, found); err != nil {
+			log.Printf("failed to observe listing %v: %v", l.ListingNumber, err)
+		}
+	}
+}
+
+// watched returns the saved snapshot of every watched listing, and stops
+// watching listings whose snapshot has expired.
+func (o listingObserver) watched() ([]prosper.Listing, error) {
+	members, err := o.redis.SMembers(redis.KeyWatchedListings)
+	if err != nil {
+		return []prosper.Listing{}, err
+	}
+	snapshots := []prosper.Listing{}
+	for _, m := range members {
+		n, err := strconv.ParseInt(m, 10, 64)
+		if err != nil {
+			log.Printf("invalid watched listing %q: %v", m, err)
+			o.unwatch(m)
+			continue
+		}
+		snapshot, err := redis.Listing(o.redis, prosper.ListingNumber(n))
+		if err == redis.ErrListingNotFound {
+			o.unwatch(m)
+			continue
+		} else if err != nil {
+			return []prosper.Listing{}, err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, nil
+}
+
+// observeListing saves the current state of a watched listing from the
+// listings that the search found and stops watching it once it is no longer
+// active.
+func (o listingObserver) observeListing(n prosper.ListingNumber, found map[prosper.ListingNumber]prosper.Listing) error {
+	current, ok := found[n]
+	if !ok {
+		return o.unwatch(int64(n))
+	}
+	if err := saveObservation(o.redis, o.clock, current); err != nil {
+		return err
+	}
+	if current.ListingStatus != prosper.ListingActive {
+		return o.unwatch(int64(n))
+	}
+	return nil
+}
+
+func (o listingObserver) unwatch(member interface{}) error {
+	_, err := o.redis.SRem(redis.KeyWatchedListings, member)
+	return err
+}
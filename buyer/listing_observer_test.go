@@ -0,0 +1,158 @@
+package buyer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/clock"
+	"github.com/mtlynch/prosperbot/redis"
+)
+
+func TestListingObserver(t *testing.T) {
+	start := time.Date(2016, 1, 1, 9, 1, 0, 0, time.UTC)
+	listing := prosper.Listing{ListingNumber: 123, ListingStatus: prosper.ListingActive, ListingStartDate: start, PercentFunded: 0.1}
+	funding := listing
+	funding.PercentFunded = 0.6
+	funded := listing
+	// Any status other than active.
+	funded.ListingStatus = 6
+	funded.PercentFunded = 1.0
+	var tests = []struct {
+		snapshot    bool
+		found       []prosper.Listing
+		wantHistory []prosper.Listing
+		wantWatched []string
+		msg         string
+	}{
+		{
+			snapshot:    true,
+			found:       []prosper.Listing{{ListingNumber: 456, ListingStartDate: start}, funding},
+			wantHistory: []prosper.Listing{listing, funding},
+			wantWatched: []string{"123"},
+			msg:         "an active listing should be observed and still watched",
+		},
+		{
+			snapshot:    true,
+			found:       []prosper.Listing{listing},
+			wantHistory: []prosper.Listing{listing},
+			wantWatched: []string{"123"},
+			msg:         "an unchanged listing should not add to its history",
+		},
+		{
+			snapshot:    true,
+			found:       []prosper.Listing{funded},
+			wantHistory: []prosper.Listing{listing, funded},
+			wantWatched: []string{},
+			msg:         "a listing that is no longer active should be observed and no longer watched",
+		},
+		{
+			snapshot:    true,
+			found:       []prosper.Listing{},
+			wantHistory: []prosper.Listing{listing},
+			wantWatched: []string{},
+			msg:         "a listing that search no longer finds should no longer be watched",
+		},
+		{
+			snapshot:    false,
+			found:       []prosper.Listing{funding},
+			wantHistory: []prosper.Listing{},
+			wantWatched: []string{},
+			msg:         "a listing whose snapshot expired should no longer be watched",
+		},
+	}
+	for _, tt := range tests {
+		r := redis.NewMemoryStore(clock.DefaultClock{}).Client()
+		v := clock.NewVirtual(mockCurrentTime)
+		if tt.snapshot {
+			filter := seenListingFilter{redis: r, clock: v}
+			if _, err := filter.saveListing(listing); err != nil {
+				t.Fatalf("%s: failed to save listing: %v", tt.msg, err)
+			}
+		} else {
+			r.SAdd(redis.KeyWatchedListings, 123)
+		}
+		o := listingObserver{
+			s:     &mockListingSearcher{listings: tt.found},
+			redis: r,
+			clock: v,
+		}
+		o.observe()
+		history, err := redis.ListingHistory(r, 123)
+		if err != nil {
+			t.Fatalf("%s: failed to read history: %v", tt.msg, err)
+		}
+		got := []prosper.Listing{}
+		for _, h := range history {
+			got = append(got, h.Listing)
+		}
+		if !reflect.DeepEqual(got, tt.wantHistory) {
+			t.Errorf("%s: unexpected history. got: %+v, want: %+v", tt.msg, got, tt.wantHistory)
+		}
+		watched, err := r.SMembers(redis.KeyWatchedListings)
+		if err != nil {
+			t.Fatalf("%s: failed to read watched listings: %v", tt.msg, err)
+		}
+		if !reflect.DeepEqual(watched, tt.wantWatched) {
+			t.Errorf("%s: unexpected watched listings. got: %v, want: %v", tt.msg, watched, tt.wantWatched)
+		}
+	}
+}
+
+func TestSearchListing(t *testing.T) {
+	start := time.Date(2016, 1, 1, 9, 1, 0, 0, time.UTC)
+	s := &mockListingSearcher{listings: makeListings(120)}
+	got, err := searchListing(s, prosper.Listing{ListingNumber: 110, ListingStartDate: start})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.ListingNumber != 110 {
+		t.Errorf("unexpected listing. got: %v, want: %v", got.ListingNumber, 110)
+	}
+	if s.gotExcludeListingsInvested {
+		t.Errorf("search for a known listing should include listings already invested in")
+	}
+	wantRange := [2]time.Time{start, start}
+	if r := s.gotSearchFilter.ListingStartDate; r.Min == nil || r.Max == nil || [2]time.Time{*r.Min, *r.Max} != wantRange {
+		t.Errorf("search should be limited to the listing's start date. got: %+v", r)
+	}
+	if _, err = searchListing(s, prosper.Listing{ListingNumber: 500, ListingStartDate: start}); err != errListingNotListed {
+		t.Errorf("unexpected error for a listing search can't find. got: %v, want: %v", err, errListingNotListed)
+	}
+}
+
+func TestListingObserverSearchesOnce(t *testing.T) {
+	early := time.Date(2016, 1, 1, 9, 1, 0, 0, time.UTC)
+	late := early.Add(2 * time.Hour)
+	listings := []prosper.Listing{
+		{ListingNumber: 123, ListingStatus: prosper.ListingActive, ListingStartDate: early},
+		{ListingNumber: 456, ListingStatus: prosper.ListingActive, ListingStartDate: late},
+	}
+	r := redis.NewMemoryStore(clock.DefaultClock{}).Client()
+	v := clock.NewVirtual(mockCurrentTime)
+	filter := seenListingFilter{redis: r, clock: v}
+	for _, l := range listings {
+		if _, err := filter.saveListing(l); err != nil {
+			t.Fatalf("failed to save listing: %v", err)
+		}
+	}
+	s := &mockListingSearcher{listings: listings}
+	o := listingObserver{s: s, redis: r, clock: v}
+	o.observe()
+	if s.calls != 1 {
+		t.Errorf("watched listings should be found with one search. got: %d searches", s.calls)
+	}
+	wantRange := [2]time.Time{early, late}
+	if got := s.gotSearchFilter.ListingStartDate; got.Min == nil || got.Max == nil || [2]time.Time{*got.Min, *got.Max} != wantRange {
+		t.Errorf("search should cover the start dates of every watched listing. got: %+v", got)
+	}
+	watched, err := r.SMembers(redis.KeyWatchedListings)
+	if err != nil {
+		t.Fatalf("failed to read watched listings: %v", err)
+	}
+	if len(watched) != 2 {
+		t.Errorf("active listings should still be watched. got: %v", watched)
+	}
+}
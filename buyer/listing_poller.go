@@ -15,7 +15,7 @@ type listingPoller struct {
 	searchFilter prosper.SearchFilter
 	listings     chan<- prosper.Listing
 	pollInterval time.Duration
-	clock        clock.Clock
+	clock        clock.Timer
 }
 
 // Maximum number of attempts before we give up on the listing poll attempt.
@@ -62,6 +62,6 @@ func (lp listingPoller) Run() {
 	}
 	for {
 		go getListings()
-		time.Sleep(lp.pollInterval)
+		lp.clock.Sleep(lp.pollInterval)
 	}
 }
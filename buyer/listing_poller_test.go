@@ -7,6 +7,8 @@ import (
 
 	"github.com/mtlynch/gofn-prosper/interval"
 	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/clock"
 )
 
 type mockListingSearcher struct {
@@ -116,7 +118,7 @@ func TestListingPoller(t *testing.T) {
 			searchFilter: tt.searchFilter,
 			listings:     listings,
 			pollInterval: 10 * time.Second,
-			clock:        mockClock{mockCurrentTime},
+			clock:        clock.NewVirtual(mockCurrentTime),
 		}
 		go listingPoller.Run()
 		var gotListings []prosper.Listing
@@ -0,0 +1,55 @@
+package buyer
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/interval"
+	"github.com/mtlynch/gofn-prosper/prosper"
+)
+
+// errListingNotListed is returned when a search no longer finds a listing,
+// which means it is no longer active.
+var errListingNotListed = errors.New("listing no longer listed")
+
+// searchListing fetches the current state of a listing from Prosper. The
+// search filter cannot select a listing by number, so it searches every
+// listing that started at the same time as l and picks l out of the results.
+func searchListing(s prosper.ListingSearcher, l prosper.Listing) (prosper.Listing, error) {
+	listings, err := searchStartedBetween(s, l.ListingStartDate, l.ListingStartDate)
+	if err != nil {
+		return prosper.Listing{}, err
+	}
+	for _, found := range listings {
+		if found.ListingNumber == l.ListingNumber {
+			return found, nil
+		}
+	}
+	return prosper.Listing{}, errListingNotListed
+}
+
+// searchStartedBetween returns every listing that started between min and max
+// inclusive, including listings the bot already invested in.
+func searchStartedBetween(s prosper.ListingSearcher, min, max time.Time) ([]prosper.Listing, error) {
+	filter := prosper.SearchFilter{
+		ListingStartDate: interval.TimeRange{Min: &min, Max: &max},
+	}
+	listings := []prosper.Listing{}
+	offset := 0
+	limit := 50
+	for {
+		response, err := s.Search(prosper.SearchParams{
+			Offset: offset,
+			Limit:  limit,
+			Filter: filter,
+		})
+		if err != nil {
+			return []prosper.Listing{}, err
+		}
+		listings = append(listings, response.Results...)
+		offset += response.ResultCount
+		if response.ResultCount < limit || offset >= response.TotalCount {
+			return listings, nil
+		}
+	}
+}
@@ -2,13 +2,21 @@ package buyer
 
 import (
 	"log"
+	"time"
 
 	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/clock"
 )
 
+// orderQueryInterval is how long to wait between queries of an order's status.
+const orderQueryInterval = 1 * time.Second
+
 type orderStatusQueryWorker struct {
-	querier      prosper.OrderStatusQuerier
-	orderUpdates chan<- prosper.OrderResponse
+	querier       prosper.OrderStatusQuerier
+	orderUpdates  chan<- prosper.OrderResponse
+	clock         clock.Timer
+	queryInterval time.Duration
 }
 
 func (qw orderStatusQueryWorker) QueryUntilComplete(orderID prosper.OrderID) {
@@ -21,6 +29,7 @@ func (qw orderStatusQueryWorker) QueryUntilComplete(orderID prosper.OrderID) {
 		if err != nil {
 			log.Printf("Failed to query orderStatus for %v, err: %v", orderID, err)
 			retries -= 1
+			qw.clock.Sleep(qw.queryInterval)
 			continue
 		}
 		go func() { qw.orderUpdates <- response }()
@@ -29,6 +38,7 @@ func (qw orderStatusQueryWorker) QueryUntilComplete(orderID prosper.OrderID) {
 			log.Printf("order %v is complete: %v", orderID, response)
 			return
 		}
+		qw.clock.Sleep(qw.queryInterval)
 	}
 }
 
@@ -36,13 +46,19 @@ type orderTracker struct {
 	querier      prosper.OrderStatusQuerier
 	orders       <-chan prosper.OrderID
 	orderUpdates chan<- prosper.OrderResponse
+	clock        clock.Timer
 }
 
 func (ot orderTracker) Run() {
 	for {
 		orderID := <-ot.orders
 		log.Printf("new order: %v", orderID)
-		worker := orderStatusQueryWorker{ot.querier, ot.orderUpdates}
+		worker := orderStatusQueryWorker{
+			querier:       ot.querier,
+			orderUpdates:  ot.orderUpdates,
+			clock:         ot.clock,
+			queryInterval: orderQueryInterval,
+		}
 		go worker.QueryUntilComplete(orderID)
 	}
 }
@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/clock"
 )
 
 type mockOrderStatusQuerier struct {
@@ -102,6 +104,7 @@ func TestOrderStatusQueryWorker(t *testing.T) {
 		queryWorker := orderStatusQueryWorker{
 			querier:      &orderQuerier,
 			orderUpdates: orderStatuses,
+			clock:        clock.NewVirtual(mockCurrentTime),
 		}
 		queryWorker.QueryUntilComplete(tt.orderID)
 		gotOrderStatuses := []prosper.OrderResponse{}
@@ -0,0 +1,65 @@
+package buyer
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/redis"
+)
+
+// Policy holds the optional rules for which listings the bot bids on and in
+// what order. The zero value bids on every listing that passes the default
+// filters, as it arrives.
+type Policy struct {
+	Ranking Ranking
+	Risk    RiskFilter
+	// MaxExposurePerBorrower is the most the bot may have at stake with any
+	// one borrower, counting outstanding notes and pending bids. Zero means no
+	// limit.
+	MaxExposurePerBorrower float64
+	// ReevaluationInterval is how often to check whether listings that were
+	// declined should be reconsidered. Zero disables re-evaluation.
+	ReevaluationInterval time.Duration
+}
+
+// exposureRejectReason explains why a bid on the listing would exceed the
+// limit on exposure to its borrower, or returns an empty string if it would
+// not. Listings whose borrower cannot be identified are not limited.
+func (lb listingBuyer) exposureRejectReason(l prosper.Listing) string {
+	max := lb.policy.MaxExposurePerBorrower
+	if max <= 0 || lb.borrowers == nil {
+		return ""
+	}
+	id, ok := redis.BorrowerID(l)
+	if !ok {
+		return ""
+	}
+	exposure, err := redis.BorrowerExposure(lb.borrowers, id)
+	if err != nil {
+		// Without the exposure, the only safe choice is not to bid.
+		log.Printf("failed to read exposure to borrower of listing %v: %v", l.ListingNumber, err)
+		return fmt.Sprintf("borrower exposure unknown: %v", err)
+	}
+	if exposure+lb.bidAmount > max {
+		return fmt.Sprintf("borrower exposure $%.2f plus bid of $%.2f would exceed $%.2f", exposure, lb.bidAmount, max)
+	}
+	return ""
+}
+
+// recordBorrowerBid adds a placed bid to the exposure of the listing's
+// borrower.
+func (lb listingBuyer) recordBorrowerBid(l prosper.Listing) {
+	if lb.borrowers == nil {
+		return
+	}
+	id, ok := redis.BorrowerID(l)
+	if !ok {
+		return
+	}
+	if err := redis.RecordBorrowerBid(lb.borrowers, id, l.ListingNumber, lb.bidAmount); err != nil {
+		log.Printf("failed to record bid on listing %v against its borrower: %v", l.ListingNumber, err)
+	}
+}
@@ -1,19 +1,21 @@
 package buyer
 
 import (
+	"fmt"
 	"log"
 	"time"
 
 	"github.com/mtlynch/gofn-prosper/prosper"
 
 	"github.com/mtlynch/prosperbot/clock"
+	"github.com/mtlynch/prosperbot/redis"
 )
 
 // TODO: Add support in Polling for excluding based on a blacklist of
 // employment status. Do I actually need to do this? Maybe I can just
 // whitelist employment statuses.
 
-func Poll(checkInterval time.Duration, f prosper.SearchFilter, isBuyingEnabled bool, c prosper.Client) error {
+func Poll(checkInterval time.Duration, f prosper.SearchFilter, isBuyingEnabled bool, c prosper.Client, clk clock.Timer, p Policy) error {
 	allListings := make(chan prosper.Listing)
 	newListings := make(chan prosper.Listing)
 	orders := make(chan prosper.OrderID)
@@ -23,33 +25,65 @@ func Poll(checkInterval time.Duration, f prosper.SearchFilter, isBuyingEnabled b
 		searchFilter: f,
 		listings:     allListings,
 		pollInterval: checkInterval,
-		clock:        clock.DefaultClock{},
+		clock:        clk,
 	}
 	seenFilter, err := NewSeenListingFilter(allListings, newListings)
 	if err != nil {
 		return err
 	}
+	seenFilter.clock = clk
 
 	var buyer listingBuyer
 	var tracker orderTracker
 	var logger orderStatusLogger
+	var re reevaluator
+	reevaluate := isBuyingEnabled && p.ReevaluationInterval > 0
 	if isBuyingEnabled {
+		r, err := redis.New()
+		if err != nil {
+			return err
+		}
 		buyer = listingBuyer{
 			listings:  newListings,
 			orders:    orders,
 			bidPlacer: c,
 			bidAmount: 25.0,
+			lineage:   r,
+			clock:     clk,
+			policy:    p,
+			accounts:  r,
+			borrowers: r,
+			claims:    r,
+		}
+		if p.MaxExposurePerBorrower > 0 {
+			indexed, skipped, err := redis.IndexBorrowers(r)
+			if err != nil {
+				return fmt.Errorf("failed to index borrowers: %v", err)
+			}
+			log.Printf("tracking exposure to borrowers of %d listings", indexed)
+			if len(skipped) > 0 {
+				log.Printf("warning: cannot identify the borrowers of %d listings the bot owns notes or orders for, so they do not count toward the exposure limit: %v", len(skipped), skipped)
+			}
+		}
+		if reevaluate {
+			buyer.reevaluation = r
+			re, err = newReevaluator(newListings, c, f, p, buyer.bidAmount, clk)
+			if err != nil {
+				return err
+			}
 		}
 		tracker = orderTracker{
 			querier:      c,
 			orders:       orders,
 			orderUpdates: orderUpdates,
+			clock:        clk,
 		}
 		logger, err = NewOrderStatusLogger(orderUpdates)
 		if err != nil {
 			log.Printf("failed to create order status logger: %v", err)
 			return err
 		}
+		logger.clock = clk
 	}
 	go func() {
 		log.Printf("starting buyer polling")
@@ -60,6 +94,9 @@ func Poll(checkInterval time.Duration, f prosper.SearchFilter, isBuyingEnabled b
 			go buyer.Run()
 			go tracker.Run()
 			go logger.Run()
+			if reevaluate {
+				go re.Run()
+			}
 		} else {
 			l := <-newListings
 			log.Printf("new purchase candidate: %v", l.ListingNumber)
@@ -1,7 +1,6 @@
 package buyer
 
 import (
-	"encoding/json"
 	"log"
 
 	"github.com/mtlynch/gofn-prosper/prosper"
@@ -51,12 +50,12 @@ func (r orderStatusLogger) Run() {
 }
 
 func (r orderStatusLogger) saveOrderStatus(record redis.OrderRecord) error {
-	serialized, err := json.Marshal(record)
+	serialized, err := redis.EncodeOrderRecord(record)
 	if err != nil {
 		return err
 	}
 	key := redis.KeyPrefixOrders + string(record.Order.OrderID)
-	_, err = r.redis.Set(key, string(serialized))
+	_, err = r.redis.Set(key, serialized)
 	if err != nil {
 		return err
 	}
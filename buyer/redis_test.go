@@ -7,6 +7,9 @@ import (
 	"time"
 
 	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/clock"
+	"github.com/mtlynch/prosperbot/redis"
 )
 
 type mockRedisSetter struct {
@@ -24,9 +27,9 @@ func (m *mockRedisSetter) Set(key string, value interface{}) (string, error) {
 }
 
 const (
-	orderAUpdate1Serialized = `{"Order":{"OrderID":"id-a","BidStatus":[{"ListingID":54321,"BidAmount":25,"Status":0,"Result":0,"BidAmountPlaced":25}],"OrderStatus":0,"OrderDate":"2016-04-23T11:54:29Z"},"Timestamp":"2016-02-14T12:28:15.000000022Z"}`
-	orderAUpdate2Serialized = `{"Order":{"OrderID":"id-a","BidStatus":[{"ListingID":54321,"BidAmount":25,"Status":0,"Result":4,"BidAmountPlaced":25}],"OrderStatus":0,"OrderDate":"2016-04-23T11:54:29Z"},"Timestamp":"2016-02-14T12:28:15.000000022Z"}`
-	orderBSerialized        = `{"Order":{"OrderID":"id-b","BidStatus":[{"ListingID":987654,"BidAmount":37.5,"Status":0,"Result":3,"BidAmountPlaced":37.5}],"OrderStatus":0,"OrderDate":"2016-03-25T20:18:04.000000036Z"},"Timestamp":"2016-02-14T12:28:15.000000022Z"}`
+	orderAUpdate1Serialized = `{"Order":{"OrderID":"id-a","BidStatus":[{"ListingID":54321,"BidAmount":25,"Status":0,"Result":0,"BidAmountPlaced":25}],"OrderStatus":0,"OrderDate":"2016-04-23T11:54:29Z"},"Timestamp":"2016-02-14T12:28:15.000000022Z","Version":1}`
+	orderAUpdate2Serialized = `{"Order":{"OrderID":"id-a","BidStatus":[{"ListingID":54321,"BidAmount":25,"Status":0,"Result":4,"BidAmountPlaced":25}],"OrderStatus":0,"OrderDate":"2016-04-23T11:54:29Z"},"Timestamp":"2016-02-14T12:28:15.000000022Z","Version":1}`
+	orderBSerialized        = `{"Order":{"OrderID":"id-b","BidStatus":[{"ListingID":987654,"BidAmount":37.5,"Status":0,"Result":3,"BidAmountPlaced":37.5}],"OrderStatus":0,"OrderDate":"2016-03-25T20:18:04.000000036Z"},"Timestamp":"2016-02-14T12:28:15.000000022Z","Version":1}`
 )
 
 var (
@@ -146,3 +149,29 @@ func TestRedisLogger(t *testing.T) {
 		}
 	}
 }
+
+func TestNewOrderStatusLogger(t *testing.T) {
+	store := redis.NewMemoryStore(clock.DefaultClock{})
+	defer redis.UseMemoryStore(store)()
+
+	orderUpdates := make(chan prosper.OrderResponse)
+	logger, err := NewOrderStatusLogger(orderUpdates)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	go logger.Run()
+	orderUpdates <- orderAUpdate1
+	// The logger saves each update before it accepts the next one.
+	orderUpdates <- orderB
+	serialized, err := store.Client().Get(redis.KeyPrefixOrders + string(orderAUpdate1.OrderID))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	record, err := redis.DecodeOrderRecord(serialized)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(record.Order, orderAUpdate1) {
+		t.Errorf("unexpected saved order. got: %+v, want: %+v", record.Order, orderAUpdate1)
+	}
+}
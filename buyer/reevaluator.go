@@ -0,0 +1,221 @@
+package buyer
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/clock"
+	"github.com/mtlynch/prosperbot/redis"
+)
+
+// maxListingAge is how long after it starts that a listing may still be open
+// for bids.
+const maxListingAge = 14 * 24 * time.Hour
+
+// reevaluator sends declined listings back to the listing buyer when the
+// reason they were declined may no longer hold: when more cash becomes
+// available, or when the buying rules have changed since they were declined.
+type reevaluator struct {
+	listings chan<- prosper.Listing
+	// s is searched for the current state of each listing to re-evaluate.
+	s prosper.ListingSearcher
+	// f is the search filter that new listings must match, which the search
+	// for a listing's current state doesn't apply.
+	f         prosper.SearchFilter
+	redis     redis.RedisReevaluator
+	clock     clock.Timer
+	interval  time.Duration
+	bidAmount float64
+	// fingerprint identifies the buying rules in effect.
+	fingerprint string
+}
+
+func newReevaluator(listings chan<- prosper.Listing, s prosper.ListingSearcher, f prosper.SearchFilter, p Policy, bidAmount float64, clk clock.Timer) (reevaluator, error) {
+	r, err := redis.New()
+	if err != nil {
+		return reevaluator{}, err
+	}
+	fingerprint, err := policyFingerprint(f, DefaultClientSideFilter(), p, bidAmount)
+	if err != nil {
+		return reevaluator{}, err
+	}
+	return reevaluator{
+		listings:    listings,
+		s:           s,
+		f:           f,
+		redis:       r,
+		clock:       clk,
+		interval:    p.ReevaluationInterval,
+		bidAmount:   bidAmount,
+		fingerprint: fingerprint,
+	}, nil
+}
+
+// policyFingerprint identifies the rules that decide which listings the bot
+// bids on, so that a change to them can be detected across restarts.
+func policyFingerprint(f prosper.SearchFilter, csf ClientSideFilter, p Policy, bidAmount float64) (string, error) {
+	// How often to re-evaluate doesn't change which listings are bought.
+	p.ReevaluationInterval = 0
+	serialized, err := json.Marshal(struct {
+		SearchFilter     prosper.SearchFilter
+		ClientSideFilter ClientSideFilter
+		Policy           Policy
+		BidAmount        float64
+	}{f, csf, p, bidAmount})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha1.Sum(serialized)), nil
+}
+
+func (re reevaluator) Run() {
+	lastCash := 0.0
+	for {
+		rulesChanged, err := re.rulesChanged()
+		if err != nil {
+			log.Printf("failed to check for changes to buying rules: %v", err)
+		}
+		cash, known := re.availableCash()
+		cashIncreased := known && cash > lastCash && cash >= re.bidAmount
+		if known {
+			lastCash = cash
+		}
+		if err := re.prune(); err != nil {
+			log.Printf("failed to prune re-evaluation queue: %v", err)
+		}
+		if rulesChanged || cashIncreased {
+			sent, err := re.requeue()
+			if err != nil {
+				log.Printf("failed to re-evaluate declined listings: %v", err)
+			}
+			log.Printf("re-evaluating %d declined listings", sent)
+			if rulesChanged && err == nil {
+				if _, err = re.redis.Set(redis.KeyReevaluationPolicy, re.fingerprint); err != nil {
+					log.Printf("failed to save buying rules fingerprint: %v", err)
+				}
+			}
+		}
+		re.clock.Sleep(re.interval)
+	}
+}
+
+// rulesChanged reports whether the buying rules differ from those that the
+// queued listings were declined under.
+func (re reevaluator) rulesChanged() (bool, error) {
+	saved, err := re.redis.Get(redis.KeyReevaluationPolicy)
+	if err != nil {
+		return false, err
+	}
+	if saved == "" {
+		// Nothing has been declined under earlier rules.
+		_, err = re.redis.Set(redis.KeyReevaluationPolicy, re.fingerprint)
+		return false, err
+	}
+	return saved != re.fingerprint, nil
+}
+
+func (re reevaluator) availableCash() (float64, bool) {
+	a, err := redis.LatestAccount(re.redis)
+	if err != nil {
+		if err != redis.ErrAccountNotFound {
+			log.Printf("failed to read available cash: %v", err)
+		}
+		return 0, false
+	}
+	return a.Value.AvailableCashBalance, true
+}
+
+// prune drops the days of listings that are too old to still be active.
+func (re reevaluator) prune() error {
+	keys, err := re.redis.Keys(redis.KeyPrefixReevaluation + "*")
+	if err != nil {
+		return err
+	}
+	cutoff := re.clock.Now().Add(-maxListingAge)
+	for _, k := range keys {
+		day, err := redis.ReevaluationDay(k)
+		if err != nil {
+			log.Printf("ignoring unexpected re-evaluation key %s: %v", k, err)
+			continue
+		}
+		// Listings from the last day in the window may have started at any
+		// time during it.
+		if day.Add(24 * time.Hour).Before(cutoff) {
+			if _, err = re.redis.Del(k); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// requeue sends every queued listing that is still active to the listing
+// buyer, as Prosper shows it now, and returns how many were sent. Each listing
+// is removed from the queue before it is sent, so it is only queued again if
+// it is declined again.
+func (re reevaluator) requeue() (int, error) {
+	keys, err := re.redis.Keys(redis.KeyPrefixReevaluation + "*")
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, k := range keys {
+		members, err := re.redis.SMembers(k)
+		if err != nil {
+			return sent, err
+		}
+		for _, m := range members {
+			l, active, err := re.current(m)
+			if err != nil {
+				return sent, err
+			}
+			if _, err = re.redis.SRem(k, m); err != nil {
+				return sent, err
+			}
+			if !active {
+				continue
+			}
+			re.listings <- l
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// current fetches the current state of a queued listing from Prosper. It
+// reports false, without searching, for listings that are too old to still
+// be active or that the bot already bid on, and for listings that Prosper
+// shows are no longer active or that no longer match the search filter.
+func (re reevaluator) current(member string) (prosper.Listing, bool, error) {
+	number, err := strconv.ParseInt(member, 10, 64)
+	if err != nil {
+		log.Printf("ignoring invalid listing number %q in re-evaluation queue", member)
+		return prosper.Listing{}, false, nil
+	}
+	snapshot, err := redis.Listing(re.redis, prosper.ListingNumber(number))
+	if err == redis.ErrListingNotFound {
+		return prosper.Listing{}, false, nil
+	} else if err != nil {
+		return prosper.Listing{}, false, err
+	}
+	if snapshot.ListingStartDate.Before(re.clock.Now().Add(-maxListingAge)) {
+		return prosper.Listing{}, false, nil
+	}
+	bid, err := redis.HasBid(re.redis, snapshot.ListingNumber)
+	if err != nil || bid {
+		return prosper.Listing{}, false, err
+	}
+	l, err := searchListing(re.s, snapshot)
+	if err == errListingNotListed {
+		return prosper.Listing{}, false, nil
+	} else if err != nil {
+		return prosper.Listing{}, false, fmt.Errorf("failed to search for listing %v: %v", snapshot.ListingNumber, err)
+	}
+	return l, l.ListingStatus == prosper.ListingActive && MatchesSearchFilter(re.f, l), nil
+}
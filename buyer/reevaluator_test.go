@@ -0,0 +1,170 @@
+package buyer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/clock"
+	"github.com/mtlynch/prosperbot/redis"
+)
+
+func TestReevaluatorRequeue(t *testing.T) {
+	now := time.Date(2016, 5, 20, 12, 0, 0, 0, time.UTC)
+	recent := now.Add(-48 * time.Hour)
+	old := now.Add(-15 * 24 * time.Hour)
+	r := redis.NewMemoryStore(clock.DefaultClock{}).Client()
+	for _, l := range []prosper.Listing{
+		{ListingNumber: 1, ListingStartDate: recent, ListingStatus: prosper.ListingActive},
+		{ListingNumber: 2, ListingStartDate: recent, ListingStatus: prosper.ListingActive},
+		{ListingNumber: 3, ListingStartDate: recent, ListingStatus: prosper.ListingActive},
+		{ListingNumber: 4, ListingStartDate: old, ListingStatus: prosper.ListingActive},
+		{ListingNumber: 6, ListingStartDate: recent, ListingStatus: prosper.ListingActive},
+		{ListingNumber: 7, ListingStartDate: recent, ListingStatus: prosper.ListingActive},
+		{ListingNumber: 8, ListingStartDate: recent, ListingStatus: prosper.ListingActive, ProsperRating: prosper.RatingA},
+	} {
+		serialized, err := json.Marshal(l)
+		if err != nil {
+			t.Fatalf("failed to encode listing: %v", err)
+		}
+		r.Set(fmt.Sprintf("%s%d", redis.KeyPrefixListing, l.ListingNumber), string(serialized))
+		if err = redis.QueueReevaluation(r, l); err != nil {
+			t.Fatalf("failed to queue listing: %v", err)
+		}
+	}
+	// Listing 5 was declined but its snapshot has since expired.
+	redis.QueueReevaluation(r, prosper.Listing{ListingNumber: 5, ListingStartDate: recent})
+	// Listing 2 was ordered before claims were saved, and listing 6 was
+	// claimed to be bid on.
+	r.HSet(redis.LineageKey(2), redis.LineageFieldOrder, "order-b")
+	redis.ClaimBid(r, 6, recent)
+
+	// Listing 3 was funded since it was saved, listing 7 is no longer listed
+	// and listing 8 is now rated too low for the search filter.
+	fresh := prosper.Listing{ListingNumber: 1, ListingStartDate: recent, ListingStatus: prosper.ListingActive, ProsperRating: prosper.RatingA, PercentFunded: 0.5}
+	searcher := &mockListingSearcher{listings: []prosper.Listing{
+		fresh,
+		{ListingNumber: 2, ListingStartDate: recent, ListingStatus: prosper.ListingActive},
+		{ListingNumber: 3, ListingStartDate: recent, ListingStatus: 6},
+		{ListingNumber: 6, ListingStartDate: recent, ListingStatus: prosper.ListingActive},
+		{ListingNumber: 8, ListingStartDate: recent, ListingStatus: prosper.ListingActive, ProsperRating: prosper.RatingHR},
+	}}
+	listings := make(chan prosper.Listing, 8)
+	re := reevaluator{
+		listings:  listings,
+		s:         searcher,
+		f:         prosper.SearchFilter{Rating: []prosper.Rating{prosper.RatingAA, prosper.RatingA}},
+		redis:     r,
+		clock:     clock.NewVirtual(now),
+		bidAmount: 25.0,
+	}
+	sent, err := re.requeue()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sent != 1 {
+		t.Errorf("unexpected count of listings sent. got = %d, want = 1", sent)
+	}
+	if got := <-listings; !reflect.DeepEqual(got, fresh) {
+		t.Errorf("listing should be sent as Prosper shows it now. got = %+v, want = %+v", got, fresh)
+	}
+	for _, start := range []time.Time{recent, old} {
+		if queued, _ := r.SMembers(redis.ReevaluationKey(start)); len(queued) != 0 {
+			t.Errorf("re-evaluated listings should leave the queue. got = %v", queued)
+		}
+	}
+	// Listings 1, 3, 7 and 8 are searched for. The others are skipped first.
+	if searcher.calls != 4 {
+		t.Errorf("unexpected number of searches. got = %d, want = 4", searcher.calls)
+	}
+}
+
+func TestReevaluatorRequeueSearchFails(t *testing.T) {
+	now := time.Date(2016, 5, 20, 12, 0, 0, 0, time.UTC)
+	l := prosper.Listing{ListingNumber: 1, ListingStartDate: now.Add(-time.Hour), ListingStatus: prosper.ListingActive}
+	r := redis.NewMemoryStore(clock.DefaultClock{}).Client()
+	serialized, _ := json.Marshal(l)
+	r.Set(redis.KeyPrefixListing+"1", string(serialized))
+	redis.QueueReevaluation(r, l)
+	re := reevaluator{
+		s:     &mockListingSearcher{listings: []prosper.Listing{}, err: errors.New("mock search error")},
+		redis: r,
+		clock: clock.NewVirtual(now),
+	}
+	if _, err := re.requeue(); err == nil {
+		t.Errorf("a failed search should be reported")
+	}
+	if queued, _ := r.SMembers(redis.ReevaluationKey(l.ListingStartDate)); !reflect.DeepEqual(queued, []string{"1"}) {
+		t.Errorf("a listing that could not be searched for should stay queued. got = %v", queued)
+	}
+}
+
+func TestReevaluatorPrune(t *testing.T) {
+	now := time.Date(2016, 5, 20, 12, 0, 0, 0, time.UTC)
+	r := redis.NewMemoryStore(clock.DefaultClock{}).Client()
+	for _, days := range []int{1, 14, 15, 16} {
+		start := now.Add(-time.Duration(days) * 24 * time.Hour)
+		redis.QueueReevaluation(r, prosper.Listing{ListingNumber: prosper.ListingNumber(days), ListingStartDate: start})
+	}
+	re := reevaluator{redis: r, clock: clock.NewVirtual(now)}
+	if err := re.prune(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	keys, err := r.Keys(redis.KeyPrefixReevaluation + "*")
+	if err != nil {
+		t.Fatalf("failed to read keys: %v", err)
+	}
+	sort.Strings(keys)
+	want := []string{"reevaluate:2016-05-06", "reevaluate:2016-05-19"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("unexpected days after pruning. got = %v, want = %v", keys, want)
+	}
+}
+
+func TestReevaluatorRulesChanged(t *testing.T) {
+	r := redis.NewMemoryStore(clock.DefaultClock{}).Client()
+	fingerprint := func(p Policy) string {
+		f, err := policyFingerprint(DefaultSearchFilter(), DefaultClientSideFilter(), p, 25.0)
+		if err != nil {
+			t.Fatalf("failed to fingerprint policy: %v", err)
+		}
+		return f
+	}
+	var tests = []struct {
+		policy Policy
+		want   bool
+		msg    string
+	}{
+		{
+			policy: Policy{},
+			want:   false,
+			msg:    "first run has nothing to compare against",
+		},
+		{
+			policy: Policy{ReevaluationInterval: time.Hour},
+			want:   false,
+			msg:    "re-evaluation interval is not a buying rule",
+		},
+		{
+			policy: Policy{MaxExposurePerBorrower: 100.0},
+			want:   true,
+			msg:    "changed exposure limit is a change in rules",
+		},
+	}
+	for _, tt := range tests {
+		re := reevaluator{redis: r, fingerprint: fingerprint(tt.policy)}
+		got, err := re.rulesChanged()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.msg, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: unexpected result. got = %v, want = %v", tt.msg, got, tt.want)
+		}
+	}
+}
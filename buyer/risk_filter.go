@@ -0,0 +1,28 @@
+package buyer
+
+import (
+	"fmt"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/scoring"
+)
+
+// RiskFilter rejects listings whose loans are predicted to be too likely to
+// charge off or default. The zero value accepts every listing.
+type RiskFilter struct {
+	Predictor      scoring.Predictor
+	MaxProbability float64
+}
+
+// RejectReason explains why the filter rejects a listing, or returns an empty
+// string if the listing passes.
+func (f RiskFilter) RejectReason(l prosper.Listing) string {
+	if f.Predictor == nil {
+		return ""
+	}
+	if p := f.Predictor.Probability(l); p > f.MaxProbability {
+		return fmt.Sprintf("predicted default probability %.3f exceeds %.3f", p, f.MaxProbability)
+	}
+	return ""
+}
@@ -0,0 +1,43 @@
+package buyer
+
+import (
+	"testing"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/scoring"
+)
+
+func TestRiskFilter(t *testing.T) {
+	model := scoring.LogisticModel{Intercept: -3.0, Weights: map[string]float64{"DtiWprosperLoan": 4.0}}
+	var tests = []struct {
+		filter RiskFilter
+		l      prosper.Listing
+		want   string
+		msg    string
+	}{
+		{
+			filter: RiskFilter{},
+			l:      prosper.Listing{DtiWprosperLoan: 0.9},
+			want:   "",
+			msg:    "filter without a model should accept every listing",
+		},
+		{
+			filter: RiskFilter{Predictor: model, MaxProbability: 0.2},
+			l:      prosper.Listing{DtiWprosperLoan: 0.1},
+			want:   "",
+			msg:    "listing below the threshold should pass",
+		},
+		{
+			filter: RiskFilter{Predictor: model, MaxProbability: 0.2},
+			l:      prosper.Listing{DtiWprosperLoan: 0.5},
+			want:   "predicted default probability 0.269 exceeds 0.200",
+			msg:    "listing above the threshold should be rejected",
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.RejectReason(tt.l); got != tt.want {
+			t.Errorf("%s: unexpected reason. got = %q, want = %q", tt.msg, got, tt.want)
+		}
+	}
+}
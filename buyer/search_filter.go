@@ -0,0 +1,82 @@
+package buyer
+
+import (
+	"github.com/mtlynch/gofn-prosper/interval"
+	"github.com/mtlynch/gofn-prosper/prosper"
+)
+
+// DefaultSearchFilter returns the filter the bot uses when searching Prosper
+// for new listings.
+func DefaultSearchFilter() prosper.SearchFilter {
+	return prosper.SearchFilter{
+		EstimatedReturn: interval.Float64Range{Min: interval.CreateFloat64(0.0849)},
+		ListingStatus:   []prosper.ListingStatus{prosper.ListingActive},
+		IncomeRange:     []prosper.IncomeRange{prosper.Between25kAnd50k, prosper.Between50kAnd75k, prosper.Between75kAnd100k, prosper.Over100k},
+		// Re-enable when Prosper fixes their bug here.
+		/*PriorProsperLoansLatePaymentsOneMonthPlus: interval.Int32Range{
+			Max: interval.CreateInt32(0),
+		},
+		PriorProsperLoansBalanceOutstanding: interval.Float64Range{
+			Max: interval.CreateFloat64(0.0),
+		},*/
+		InquiriesLast6Months: interval.Int32Range{Max: interval.CreateInt32(3)},
+		DtiWprosperLoan:      interval.Float64Range{Max: interval.CreateFloat64(0.4)},
+		Rating:               []prosper.Rating{prosper.RatingAA, prosper.RatingA, prosper.RatingB, prosper.RatingC, prosper.RatingD, prosper.RatingE},
+	}
+}
+
+// MatchesSearchFilter reports whether Prosper would have returned l for a
+// search with filter f. It evaluates the criteria that the bot uses, so that
+// saved listings can be replayed against a different filter. The listing start
+// date is ignored because the bot sets it on every poll.
+func MatchesSearchFilter(f prosper.SearchFilter, l prosper.Listing) bool {
+	if !isInFloat64Range(f.EstimatedReturn, l.EstimatedReturn) {
+		return false
+	}
+	if !isInFloat64Range(f.DtiWprosperLoan, l.DtiWprosperLoan) {
+		return false
+	}
+	if !isInInt32Range(f.InquiriesLast6Months, int32(l.InquiriesLast6Months)) {
+		return false
+	}
+	if !isInInt32Range(f.PriorProsperLoansLatePaymentsOneMonthPlus, int32(l.PriorProsperLoansLatePaymentsOneMonthPlus)) {
+		return false
+	}
+	if !isInFloat64Range(f.PriorProsperLoansBalanceOutstanding, l.PriorProsperLoansBalanceOutstanding) {
+		return false
+	}
+	if len(f.ListingStatus) > 0 {
+		found := false
+		for _, s := range f.ListingStatus {
+			if l.ListingStatus == s {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(f.IncomeRange) > 0 {
+		found := false
+		for _, i := range f.IncomeRange {
+			if l.IncomeRange == i {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(f.Rating) > 0 {
+		found := false
+		for _, r := range f.Rating {
+			if l.ProsperRating == r {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,68 @@
+package buyer
+
+import (
+	"testing"
+
+	"github.com/mtlynch/gofn-prosper/interval"
+	"github.com/mtlynch/gofn-prosper/prosper"
+)
+
+func TestMatchesSearchFilter(t *testing.T) {
+	var tests = []struct {
+		listing prosper.Listing
+		filter  prosper.SearchFilter
+		want    bool
+	}{
+		{
+			listing: prosper.Listing{},
+			filter:  prosper.SearchFilter{},
+			want:    true,
+		},
+		{
+			listing: prosper.Listing{EstimatedReturn: 0.08},
+			filter:  prosper.SearchFilter{EstimatedReturn: interval.Float64Range{Min: interval.CreateFloat64(0.0849)}},
+			want:    false,
+		},
+		{
+			listing: prosper.Listing{EstimatedReturn: 0.09},
+			filter:  prosper.SearchFilter{EstimatedReturn: interval.Float64Range{Min: interval.CreateFloat64(0.0849)}},
+			want:    true,
+		},
+		{
+			listing: prosper.Listing{DtiWprosperLoan: 0.45},
+			filter:  prosper.SearchFilter{DtiWprosperLoan: interval.Float64Range{Max: interval.CreateFloat64(0.4)}},
+			want:    false,
+		},
+		{
+			listing: prosper.Listing{InquiriesLast6Months: 4},
+			filter:  prosper.SearchFilter{InquiriesLast6Months: interval.Int32Range{Max: interval.CreateInt32(3)}},
+			want:    false,
+		},
+		{
+			listing: prosper.Listing{ProsperRating: prosper.RatingB},
+			filter:  prosper.SearchFilter{Rating: []prosper.Rating{prosper.RatingAA, prosper.RatingA}},
+			want:    false,
+		},
+		{
+			listing: prosper.Listing{ProsperRating: prosper.RatingA},
+			filter:  prosper.SearchFilter{Rating: []prosper.Rating{prosper.RatingAA, prosper.RatingA}},
+			want:    true,
+		},
+		{
+			listing: prosper.Listing{IncomeRange: prosper.Between25kAnd50k},
+			filter:  prosper.SearchFilter{IncomeRange: []prosper.IncomeRange{prosper.Over100k}},
+			want:    false,
+		},
+		{
+			listing: prosper.Listing{ListingStatus: prosper.ListingActive},
+			filter:  prosper.SearchFilter{ListingStatus: []prosper.ListingStatus{prosper.ListingActive}},
+			want:    true,
+		},
+	}
+	for _, tt := range tests {
+		got := MatchesSearchFilter(tt.filter, tt.listing)
+		if got != tt.want {
+			t.Errorf("unexpected search filter result for listing: %+v and filter: %+v. got = %v, want = %v", tt.listing, tt.filter, got, tt.want)
+		}
+	}
+}
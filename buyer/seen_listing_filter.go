@@ -1,19 +1,22 @@
 package buyer
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"log"
 
 	"github.com/mtlynch/gofn-prosper/prosper"
 
+	"github.com/mtlynch/prosperbot/clock"
 	"github.com/mtlynch/prosperbot/redis"
 )
 
 type seenListingFilter struct {
 	listings    <-chan prosper.Listing
 	newListings chan<- prosper.Listing
-	redis       redis.RedisSetNXer
+	redis       redis.RedisListingSaver
+	clock       clock.Clock
 }
 
 func NewSeenListingFilter(listings <-chan prosper.Listing, newListings chan<- prosper.Listing) (seenListingFilter, error) {
@@ -25,6 +28,7 @@ func NewSeenListingFilter(listings <-chan prosper.Listing, newListings chan<- pr
 		listings:    listings,
 		newListings: newListings,
 		redis:       r,
+		clock:       clock.DefaultClock{},
 	}, nil
 }
 
@@ -47,11 +51,73 @@ func (r seenListingFilter) Run() {
 	}
 }
 
+// saveListing keeps the first observation of a listing as its snapshot and
+// adds every observation that differs from the last one to its history. It
+// reports whether the listing had not been seen before.
 func (r seenListingFilter) saveListing(listing prosper.Listing) (isNew bool, err error) {
+	// Listings whose snapshots have expired are only remembered by number.
+	expired, err := r.redis.SIsMember(redis.KeySeenListings, int64(listing.ListingNumber))
+	if err != nil {
+		return false, err
+	}
+	if expired {
+		return false, nil
+	}
 	serialized, err := json.Marshal(listing)
 	if err != nil {
 		return false, err
 	}
 	key := fmt.Sprintf("%s%d", redis.KeyPrefixListing, listing.ListingNumber)
-	return r.redis.SetNX(key, string(serialized))
+	isNew, err = r.redis.SetNX(key, string(serialized))
+	if err != nil {
+		return false, err
+	}
+	if isNew && listing.ListingStatus == prosper.ListingActive {
+		if _, err = r.redis.SAdd(redis.KeyWatchedListings, int64(listing.ListingNumber)); err != nil {
+			log.Printf("failed to watch listing %v: %v", listing.ListingNumber, err)
+		}
+	}
+	if err = saveObservation(r.redis, r.clock, listing); err != nil {
+		log.Printf("failed to save observation of listing %v: %v", listing.ListingNumber, err)
+	}
+	return isNew, nil
+}
+
+// saveObservation adds an observation of a listing to its history, unless
+// nothing changed since the last one.
+func saveObservation(r redis.RedisListPrepender, clk clock.Clock, listing prosper.Listing) error {
+	key := redis.ListingHistoryKey(listing.ListingNumber)
+	latest, err := r.LRange(key, 0, 0)
+	if err != nil {
+		return err
+	}
+	if len(latest) > 0 {
+		last, err := redis.DecodeListingRecord(latest[0])
+		if err != nil {
+			return err
+		}
+		// Compare the listings as they are saved, since listings that save
+		// the same can still differ in memory, such as in the locations of
+		// their dates.
+		lastSerialized, err := json.Marshal(last.Listing)
+		if err != nil {
+			return err
+		}
+		serialized, err := json.Marshal(listing)
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(lastSerialized, serialized) {
+			return nil
+		}
+	}
+	serialized, err := redis.EncodeListingRecord(redis.ListingRecord{
+		Listing:   listing,
+		Timestamp: clk.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = r.LPush(key, serialized)
+	return err
 }
@@ -1,16 +1,46 @@
 package buyer
 
 import (
+	"fmt"
 	"reflect"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/clock"
+	"github.com/mtlynch/prosperbot/redis"
 )
 
 type mockRedisSetNXer struct {
-	values map[string]string
-	err    error
+	values  map[string]string
+	members map[string]bool
+	lists   map[string][]string
+	err     error
+}
+
+func (r *mockRedisSetNXer) LRange(key string, start int64, stop int64) ([]string, error) {
+	if len(r.lists[key]) == 0 {
+		return []string{}, nil
+	}
+	return r.lists[key][:1], nil
+}
+
+func (r *mockRedisSetNXer) LPush(key string, values ...interface{}) (int64, error) {
+	if r.lists == nil {
+		r.lists = map[string][]string{}
+	}
+	r.lists[key] = append([]string{values[0].(string)}, r.lists[key]...)
+	return int64(len(r.lists[key])), nil
+}
+
+func (r *mockRedisSetNXer) SAdd(key string, members ...interface{}) (int64, error) {
+	return int64(len(members)), nil
+}
+
+func (r *mockRedisSetNXer) SIsMember(key string, member interface{}) (bool, error) {
+	return r.members[fmt.Sprintf("%s:%v", key, member)], nil
 }
 
 func (r *mockRedisSetNXer) SetNX(key string, value interface{}) (bool, error) {
@@ -42,6 +72,7 @@ func (b byListingNumber) Less(i, j int) bool { return b[i].ListingNumber < b[j].
 func TestSeenListingFilter(t *testing.T) {
 	var tests = []struct {
 		redisStartingValues map[string]string
+		redisMembers        map[string]bool
 		redisErr            error
 		listings            []prosper.Listing
 		wantNewListings     []prosper.Listing
@@ -74,18 +105,27 @@ func TestSeenListingFilter(t *testing.T) {
 			wantNewListings: []prosper.Listing{},
 			msg:             "previously seen listings should not pass filter",
 		},
+		{
+			redisStartingValues: make(map[string]string),
+			redisMembers:        map[string]bool{"seenListings:123": true},
+			listings:            []prosper.Listing{listingA, listingB},
+			wantNewListings:     []prosper.Listing{listingB},
+			msg:                 "listings with expired snapshots should not pass filter",
+		},
 	}
 	for _, tt := range tests {
 		listings := make(chan prosper.Listing)
 		newListings := make(chan prosper.Listing)
 		setNXer := mockRedisSetNXer{
-			values: tt.redisStartingValues,
-			err:    tt.redisErr,
+			values:  tt.redisStartingValues,
+			members: tt.redisMembers,
+			err:     tt.redisErr,
 		}
 		filter := seenListingFilter{
 			listings:    listings,
 			newListings: newListings,
 			redis:       &setNXer,
+			clock:       clock.NewVirtual(mockCurrentTime),
 		}
 		go func() {
 			for _, u := range tt.listings {
@@ -104,3 +144,76 @@ func TestSeenListingFilter(t *testing.T) {
 		}
 	}
 }
+
+func TestNewSeenListingFilter(t *testing.T) {
+	store := redis.NewMemoryStore(clock.DefaultClock{})
+	defer redis.UseMemoryStore(store)()
+	store.Client().SAdd(redis.KeySeenListings, 789)
+
+	listings := make(chan prosper.Listing)
+	newListings := make(chan prosper.Listing)
+	filter, err := NewSeenListingFilter(listings, newListings)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	go filter.Run()
+	for _, l := range []prosper.Listing{listingA, listingA, {ListingNumber: 789}, listingB} {
+		listings <- l
+	}
+	close(listings)
+	got := []prosper.Listing{<-newListings, <-newListings}
+	sort.Sort(byListingNumber(got))
+	want := []prosper.Listing{listingA, listingB}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected new listings. got: %+v, want: %+v", got, want)
+	}
+	if keys, _ := store.Client().Keys(redis.KeyPrefixListing + "*"); !reflect.DeepEqual(keys, []string{"listing:123", "listing:456"}) {
+		t.Errorf("unexpected saved listings: %v", keys)
+	}
+}
+
+func TestSeenListingFilterSavesObservations(t *testing.T) {
+	r := redis.NewMemoryStore(clock.DefaultClock{}).Client()
+	v := clock.NewVirtual(mockCurrentTime)
+	filter := seenListingFilter{redis: r, clock: v}
+	start := time.Date(2016, 1, 1, 9, 1, 0, 0, time.UTC)
+	observations := []prosper.Listing{
+		{ListingNumber: 123, ListingStatus: prosper.ListingActive, ListingStartDate: start, PercentFunded: 0.1, AmountRemaining: 9000},
+		// The same observation, with its start date in a different location.
+		{ListingNumber: 123, ListingStatus: prosper.ListingActive, ListingStartDate: start.In(time.FixedZone("UTC", 0)), PercentFunded: 0.1, AmountRemaining: 9000},
+		{ListingNumber: 123, ListingStatus: prosper.ListingActive, ListingStartDate: start, PercentFunded: 0.4, AmountRemaining: 6000},
+	}
+	gotNew := []bool{}
+	for _, l := range observations {
+		isNew, err := filter.saveListing(l)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		gotNew = append(gotNew, isNew)
+		v.Advance(time.Second)
+	}
+	if want := []bool{true, false, false}; !reflect.DeepEqual(gotNew, want) {
+		t.Errorf("listing should only be new once. got: %v, want: %v", gotNew, want)
+	}
+	first, err := redis.Listing(r, 123)
+	if err != nil {
+		t.Fatalf("failed to read listing: %v", err)
+	}
+	if !reflect.DeepEqual(first, observations[0]) {
+		t.Errorf("snapshot should be the first observation. got: %+v, want: %+v", first, observations[0])
+	}
+	history, err := redis.ListingHistory(r, 123)
+	if err != nil {
+		t.Fatalf("failed to read history: %v", err)
+	}
+	want := []redis.ListingRecord{
+		{Listing: observations[0], Timestamp: mockCurrentTime, Version: redis.SchemaVersion},
+		{Listing: observations[2], Timestamp: mockCurrentTime.Add(2 * time.Second), Version: redis.SchemaVersion},
+	}
+	if !reflect.DeepEqual(history, want) {
+		t.Errorf("unexpected history. got: %+v, want: %+v", history, want)
+	}
+	if watched, _ := r.SMembers(redis.KeyWatchedListings); !reflect.DeepEqual(watched, []string{"123"}) {
+		t.Errorf("a new active listing should be watched. got: %v", watched)
+	}
+}
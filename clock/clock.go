@@ -9,6 +9,23 @@ type Clock interface {
 	Now() time.Time
 }
 
+// Timer is a Clock that can also wait for time to pass.
+type Timer interface {
+	Clock
+	// After sends the time on the returned channel once d has passed.
+	After(d time.Duration) <-chan time.Time
+	// Sleep blocks until d has passed.
+	Sleep(d time.Duration)
+	// NewTicker sends the time on its channel every d.
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker delivers ticks at intervals, like time.Ticker.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
 // DefaultClock is a default clock implementation that returns the system time.
 type DefaultClock struct {
 }
@@ -17,3 +34,27 @@ type DefaultClock struct {
 func (c DefaultClock) Now() time.Time {
 	return time.Now()
 }
+
+func (c DefaultClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (c DefaultClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+func (c DefaultClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+type realTicker struct {
+	t *time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time {
+	return t.t.C
+}
+
+func (t realTicker) Stop() {
+	t.t.Stop()
+}
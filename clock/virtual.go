@@ -0,0 +1,145 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Virtual is a clock whose time only moves when Advance is called, so that
+// tests can run through hours of timers and tickers instantly.
+type Virtual struct {
+	mu      sync.Mutex
+	waiting *sync.Cond
+	now     time.Time
+	timers  []*virtualTimer
+}
+
+type virtualTimer struct {
+	deadline time.Time
+	// period is the interval of a ticker, or zero for a one-shot timer.
+	period time.Duration
+	c      chan time.Time
+}
+
+// NewVirtual creates a virtual clock that starts at the given time.
+func NewVirtual(start time.Time) *Virtual {
+	v := &Virtual{now: start}
+	v.waiting = sync.NewCond(&v.mu)
+	return v
+}
+
+func (v *Virtual) Now() time.Time {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.now
+}
+
+// After sends the virtual time on the returned channel once Advance moves the
+// clock d past the current time. A d of zero or less fires immediately.
+func (v *Virtual) After(d time.Duration) <-chan time.Time {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	c := make(chan time.Time, 1)
+	if d <= 0 {
+		c <- v.now
+		return c
+	}
+	v.add(&virtualTimer{deadline: v.now.Add(d), c: c})
+	return c
+}
+
+func (v *Virtual) Sleep(d time.Duration) {
+	<-v.After(d)
+}
+
+// NewTicker creates a ticker that ticks every d of virtual time. Like
+// time.Ticker, it drops ticks that the receiver isn't ready for.
+func (v *Virtual) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	t := &virtualTimer{deadline: v.now.Add(d), period: d, c: make(chan time.Time, 1)}
+	v.add(t)
+	return virtualTicker{v, t}
+}
+
+// add registers a timer. The caller must hold v.mu.
+func (v *Virtual) add(t *virtualTimer) {
+	v.timers = append(v.timers, t)
+	v.waiting.Broadcast()
+}
+
+// remove unregisters a timer. The caller must hold v.mu.
+func (v *Virtual) remove(t *virtualTimer) {
+	for i, other := range v.timers {
+		if other == t {
+			v.timers = append(v.timers[:i], v.timers[i+1:]...)
+			return
+		}
+	}
+}
+
+// Advance moves the clock forward by d, firing every timer and tick that falls
+// due along the way in deadline order. Goroutines woken by Advance see the new
+// time; timers they start are only fired by a later Advance.
+func (v *Virtual) Advance(d time.Duration) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	end := v.now.Add(d)
+	for {
+		var next *virtualTimer
+		for _, t := range v.timers {
+			if !t.deadline.After(end) && (next == nil || t.deadline.Before(next.deadline)) {
+				next = t
+			}
+		}
+		if next == nil {
+			break
+		}
+		v.now = next.deadline
+		select {
+		case next.c <- v.now:
+		default:
+		}
+		if next.period > 0 {
+			next.deadline = next.deadline.Add(next.period)
+		} else {
+			v.remove(next)
+		}
+	}
+	v.now = end
+}
+
+// Waiters returns how many timers and tickers are pending.
+func (v *Virtual) Waiters() int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return len(v.timers)
+}
+
+// BlockUntil waits until at least n timers and tickers are pending, which
+// tells a test that the goroutines it expects to sleep have done so.
+func (v *Virtual) BlockUntil(n int) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for len(v.timers) < n {
+		v.waiting.Wait()
+	}
+}
+
+type virtualTicker struct {
+	v *Virtual
+	t *virtualTimer
+}
+
+func (t virtualTicker) C() <-chan time.Time {
+	return t.t.c
+}
+
+func (t virtualTicker) Stop() {
+	t.v.mu.Lock()
+	defer t.v.mu.Unlock()
+	t.v.remove(t.t)
+}
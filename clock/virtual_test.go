@@ -0,0 +1,83 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+var start = time.Date(2016, 6, 1, 9, 0, 0, 0, time.UTC)
+
+func TestVirtualAfter(t *testing.T) {
+	v := NewVirtual(start)
+	c := v.After(time.Minute)
+	v.Advance(59 * time.Second)
+	select {
+	case <-c:
+		t.Fatalf("timer fired before its deadline")
+	default:
+	}
+	v.Advance(time.Second)
+	select {
+	case got := <-c:
+		if want := start.Add(time.Minute); !got.Equal(want) {
+			t.Errorf("unexpected fire time. got: %v, want: %v", got, want)
+		}
+	default:
+		t.Fatalf("timer did not fire at its deadline")
+	}
+	if v.Waiters() != 0 {
+		t.Errorf("fired timer should no longer be pending, got %d waiters", v.Waiters())
+	}
+	select {
+	case <-v.After(0):
+	default:
+		t.Errorf("zero duration timer should fire immediately")
+	}
+}
+
+func TestVirtualTicker(t *testing.T) {
+	v := NewVirtual(start)
+	ticker := v.NewTicker(10 * time.Second)
+	ticks := []time.Time{}
+	for i := 0; i < 3; i++ {
+		v.Advance(10 * time.Second)
+		ticks = append(ticks, <-ticker.C())
+	}
+	for i, tick := range ticks {
+		if want := start.Add(time.Duration(i+1) * 10 * time.Second); !tick.Equal(want) {
+			t.Errorf("unexpected tick %d. got: %v, want: %v", i, tick, want)
+		}
+	}
+	// Ticks that nobody receives are dropped rather than queued.
+	v.Advance(time.Minute)
+	<-ticker.C()
+	select {
+	case <-ticker.C():
+		t.Errorf("missed ticks should be dropped")
+	default:
+	}
+	ticker.Stop()
+	v.Advance(time.Minute)
+	select {
+	case <-ticker.C():
+		t.Errorf("stopped ticker should not tick")
+	default:
+	}
+	if got, want := v.Now(), start.Add(150*time.Second); !got.Equal(want) {
+		t.Errorf("unexpected time. got: %v, want: %v", got, want)
+	}
+}
+
+func TestVirtualSleep(t *testing.T) {
+	v := NewVirtual(start)
+	woke := make(chan time.Time)
+	go func() {
+		v.Sleep(time.Hour)
+		woke <- v.Now()
+	}()
+	v.BlockUntil(1)
+	v.Advance(2 * time.Hour)
+	if got, want := <-woke, start.Add(2*time.Hour); !got.Equal(want) {
+		t.Errorf("sleeper should see the time after the advance. got: %v, want: %v", got, want)
+	}
+}
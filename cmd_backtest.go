@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mtlynch/prosperbot/backtest"
+)
+
+func backtestCommand(args []string) error {
+	fs := newFlagSet("backtest")
+	strategyPath := fs.String("strategy", "", "JSON file with the SearchFilter, ClientSideFilter and Sizing to test (default: the bot's current rules)")
+	fs.Parse(args)
+	strategy := backtest.DefaultStrategy()
+	if *strategyPath != "" {
+		var err error
+		strategy, err = backtest.LoadStrategy(*strategyPath)
+		if err != nil {
+			return err
+		}
+	}
+	r, err := openStore()
+	if err != nil {
+		return err
+	}
+	result, err := backtest.Run(r, strategy, time.Now())
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Listings evaluated:\t%d\n", result.ListingsEvaluated)
+	fmt.Fprintf(w, "Would buy:\t%d\t($%.2f)\n", result.WouldBuy, result.AmountInvested)
+	fmt.Fprintf(w, "Actually bought:\t%d\n", result.ActualPurchases)
+	fmt.Fprintf(w, "Bought by both:\t%d\n", result.Overlap)
+	fmt.Fprintf(w, "Only by strategy:\t%d\n", result.OnlyStrategy)
+	fmt.Fprintf(w, "Only by the bot:\t%d\n", result.OnlyActual)
+	p := result.Performance
+	fmt.Fprintf(w, "\nOutcomes for %d selected notes the bot owns\n", p.Notes)
+	fmt.Fprintf(w, "  Invested:\t$%.2f\n", p.Invested)
+	fmt.Fprintf(w, "  Received:\t$%.2f\n", p.Received)
+	fmt.Fprintf(w, "  Principal lost:\t$%.2f\n", p.PrincipalLost)
+	if p.HasXIRR {
+		fmt.Fprintf(w, "  XIRR:\t%.2f%%\n", p.XIRR*100)
+	}
+	fmt.Fprintf(w, "  Net annualized return:\t%.2f%%\n", p.NetAnnualizedReturn*100)
+	return w.Flush()
+}
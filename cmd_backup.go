@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mtlynch/prosperbot/backup"
+	"github.com/mtlynch/prosperbot/redis"
+)
+
+func backupCommand(args []string) error {
+	fs := newFlagSet("backup")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("usage: backup <archive file>")
+	}
+	r, err := openStore()
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(fs.Arg(0), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	n, err := backup.Backup(r, f, time.Now())
+	if err != nil {
+		f.Close()
+		os.Remove(fs.Arg(0))
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("backed up %d keys to %s\n", n, fs.Arg(0))
+	return nil
+}
+
+func restoreCommand(args []string) error {
+	fs := newFlagSet("restore")
+	force := fs.Bool("force", false, "restore even if the store already has data, replacing keys in the archive")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("usage: restore [-force] <archive file>")
+	}
+	path := fs.Arg(0)
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	h, n, err := backup.Verify(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	r, err := redis.New()
+	if err != nil {
+		return err
+	}
+	f, err = os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	restored, err := backup.Restore(r, f, *force)
+	if err == backup.ErrStoreNotEmpty {
+		return errors.New("store already contains data, use -force to overwrite it")
+	} else if err != nil {
+		return err
+	}
+	fmt.Printf("restored %d of %d keys from backup created %s\n", restored, n, h.Created.Format(time.RFC3339))
+	return nil
+}
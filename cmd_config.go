@@ -0,0 +1,20 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+func configCommand(args []string) error {
+	if len(args) < 1 || args[0] != "validate" {
+		return errors.New("usage: config validate [-config path]")
+	}
+	fs := newFlagSet("config validate")
+	configPath := configFlag(fs)
+	fs.Parse(args[1:])
+	if _, err := loadConfig(*configPath); err != nil {
+		return err
+	}
+	fmt.Println("config is valid")
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/mtlynch/prosperbot/export"
+	"github.com/mtlynch/prosperbot/redis"
+)
+
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", s, time.Local)
+}
+
+func exportCommand(args []string) error {
+	fs := newFlagSet("export")
+	format := fs.String("format", "csv", "output format (csv or ndjson)")
+	since := fs.String("since", "", "only export records from this time on (2006-01-02 or RFC 3339)")
+	until := fs.String("until", "", "only export records before this time (2006-01-02 or RFC 3339)")
+	full := fs.Bool("full", false, "export every note snapshot instead of only the latest")
+	outputPath := fs.String("output", "", "file to write to (default stdout)")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("usage: export [flags] account|notes|orders|listings")
+	}
+	var tr export.TimeRange
+	var err error
+	if tr.Since, err = parseTime(*since); err != nil {
+		return fmt.Errorf("invalid -since: %v", err)
+	}
+	if tr.Until, err = parseTime(*until); err != nil {
+		return fmt.Errorf("invalid -until: %v", err)
+	}
+
+	var columns []string
+	var write func(redis.RedisHistoryScanner, export.RowWriter) error
+	switch fs.Arg(0) {
+	case "account":
+		columns = export.AccountColumns
+		write = func(r redis.RedisHistoryScanner, w export.RowWriter) error { return export.Accounts(r, w, tr) }
+	case "notes":
+		columns = export.NoteColumns
+		write = func(r redis.RedisHistoryScanner, w export.RowWriter) error { return export.Notes(r, w, tr, *full) }
+	case "orders":
+		columns = export.OrderColumns
+		write = func(r redis.RedisHistoryScanner, w export.RowWriter) error { return export.Orders(r, w, tr) }
+	case "listings":
+		columns = export.ListingColumns
+		write = func(r redis.RedisHistoryScanner, w export.RowWriter) error { return export.Listings(r, w, tr) }
+	default:
+		return fmt.Errorf("unrecognized dataset: %s", fs.Arg(0))
+	}
+
+	r, err := openStore()
+	if err != nil {
+		return err
+	}
+	var out io.Writer = os.Stdout
+	if *outputPath != "" {
+		f, err := os.Create(*outputPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	w, err := export.NewRowWriter(out, *format, columns)
+	if err != nil {
+		return err
+	}
+	return write(r, w)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/redis"
+)
+
+func lineageCommand(args []string) error {
+	fs := newFlagSet("lineage")
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		return errors.New("usage: lineage listing|order|note <id>")
+	}
+	r, err := openStore()
+	if err != nil {
+		return err
+	}
+	var lineage interface{}
+	switch kind, id := fs.Arg(0), fs.Arg(1); kind {
+	case "listing":
+		n, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid listing number: %s", id)
+		}
+		lineage, err = redis.LineageForListing(r, prosper.ListingNumber(n))
+		if err != nil {
+			return err
+		}
+	case "order":
+		lineage, err = redis.LineageForOrder(r, prosper.OrderID(id))
+		if err != nil {
+			return err
+		}
+	case "note":
+		lineage, err = redis.LineageForNote(r, id)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown lineage lookup: %s", kind)
+	}
+	serialized, err := json.MarshalIndent(lineage, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s\n", serialized)
+	return nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/buyer"
+	"github.com/mtlynch/prosperbot/redis"
+)
+
+func listingCommand(args []string) error {
+	fs := newFlagSet("listing")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("usage: listing <listing number>")
+	}
+	id, err := strconv.ParseInt(fs.Arg(0), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid listing number: %s", fs.Arg(0))
+	}
+	listingNumber := prosper.ListingNumber(id)
+	r, err := openStore()
+	if err != nil {
+		return err
+	}
+	listing, err := redis.Listing(r, listingNumber)
+	if err != nil {
+		return err
+	}
+	serialized, err := json.MarshalIndent(listing, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s\n\n", serialized)
+
+	if err = printDecision(r, listing); err != nil {
+		return err
+	}
+
+	history, err := redis.ListingHistory(r, listingNumber)
+	if err != nil {
+		return err
+	}
+	if len(history) > 0 {
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "OBSERVED\tSINCE START\tSTATUS\tFUNDED\tREMAINING")
+		for _, h := range history {
+			l := h.Listing
+			fmt.Fprintf(w, "%s\t%v\t%d\t%.1f%%\t$%.2f\n", h.Timestamp.Format("2006-01-02 15:04:05"), h.Timestamp.Sub(l.ListingStartDate), l.ListingStatus, l.PercentFunded*100, l.AmountRemaining)
+		}
+		fmt.Fprintln(w)
+		if err = w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	orders, err := redis.Orders(r)
+	if err != nil {
+		return err
+	}
+	matching := []redis.OrderRecord{}
+	for _, o := range orders {
+		for _, b := range o.Order.BidStatus {
+			if b.ListingID == listingNumber {
+				matching = append(matching, o)
+				break
+			}
+		}
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	if len(matching) == 0 {
+		fmt.Fprintln(w, "No orders placed for this listing.")
+	} else {
+		writeOrders(w, matching)
+	}
+	owned, err := redis.FindNotes(r, redis.NoteListingIndex(listingNumber))
+	if err != nil {
+		return err
+	}
+	if len(owned) > 0 {
+		fmt.Fprintln(w)
+		writeNotes(w, owned)
+	}
+	return w.Flush()
+}
+
+// printDecision shows the decision the bot saved for a listing. Listings seen
+// before decisions were saved only have the client-side filter recomputed,
+// which ignores the bot's other buying rules.
+func printDecision(r redis.RedisHashGetter, l prosper.Listing) error {
+	d, err := redis.DecisionForListing(r, l.ListingNumber)
+	if err == redis.ErrDecisionNotFound {
+		reason := buyer.DefaultClientSideFilter().RejectReason(l)
+		if reason == "" {
+			fmt.Printf("Decision (recomputed, client-side filter only): passed\n\n")
+		} else {
+			fmt.Printf("Decision (recomputed, client-side filter only): rejected, %s\n\n", reason)
+		}
+		return nil
+	} else if err != nil {
+		return err
+	}
+	verdict := "passed"
+	if !d.Passed {
+		verdict = "rejected, " + d.Reason
+	}
+	if d.Score != nil {
+		verdict += fmt.Sprintf(" (score %.4f)", *d.Score)
+	}
+	fmt.Printf("Decision at %s: %s\n\n", d.Timestamp.Format("2006-01-02 15:04:05"), verdict)
+	return nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/portfolio"
+	"github.com/mtlynch/prosperbot/redis"
+)
+
+func writeNotes(w io.Writer, notes []prosper.Note) {
+	fmt.Fprintln(w, "NOTE\tLISTING\tRATING\tTERM\tSTATUS\tDAYS PAST DUE\tOWNED\tBALANCE")
+	for _, n := range notes {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%d\t%s\t%d\t$%.2f\t$%.2f\n",
+			n.LoanNoteID,
+			n.ListingNumber,
+			portfolio.RatingName(n.Rating),
+			n.Term,
+			n.NoteStatusDescription,
+			n.DaysPastDue,
+			n.NoteOwnershipAmount,
+			n.PrincipalBalanceProRataShare)
+	}
+}
+
+// latestNotes returns the latest saved state of every note, ordered by note ID.
+func latestNotes(r redis.RedisInspector) ([]prosper.Note, error) {
+	ids, err := redis.NoteIDs(r)
+	if err != nil {
+		return []prosper.Note{}, err
+	}
+	notes := []prosper.Note{}
+	for _, id := range ids {
+		latest, _, err := redis.LatestNoteRecord(r, id)
+		if err == redis.ErrNoteNotFound {
+			continue
+		} else if err != nil {
+			return []prosper.Note{}, err
+		}
+		notes = append(notes, latest.Note)
+	}
+	return notes, nil
+}
+
+// notesCommand looks up filtered notes through the note indexes. The bot adds
+// every note to its indexes the first time it sees the note in each run, so
+// indexes added since the bot last ran are empty until it runs again.
+func notesCommand(args []string) error {
+	fs := newFlagSet("notes")
+	status := fs.String("status", "", "only show notes with this status, such as CURRENT or CHARGEOFF")
+	lateOnly := fs.Bool("late", false, "only show notes that are past due")
+	fs.Parse(args)
+	r, err := openStore()
+	if err != nil {
+		return err
+	}
+	indexes := []string{}
+	if *status != "" {
+		indexes = append(indexes, redis.NoteStatusDescriptionIndex(*status))
+	}
+	if *lateOnly {
+		indexes = append(indexes, redis.NoteLateIndex())
+	}
+	var notes []prosper.Note
+	if len(indexes) == 0 {
+		notes, err = latestNotes(r)
+	} else {
+		notes, err = redis.FindNotes(r, indexes...)
+	}
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	writeNotes(w, notes)
+	return w.Flush()
+}
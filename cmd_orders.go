@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/redis"
+)
+
+func orderStatusName(s prosper.OrderStatus) string {
+	switch s {
+	case prosper.OrderInProgress:
+		return "IN_PROGRESS"
+	case prosper.OrderCompleted:
+		return "COMPLETED"
+	}
+	return fmt.Sprintf("%v", s)
+}
+
+func bidResultName(r prosper.BidResult) string {
+	switch r {
+	case prosper.NoBidResult:
+		return "PENDING"
+	case prosper.BidSucceeded:
+		return "SUCCEEDED"
+	case prosper.BidFailed:
+		return "FAILED"
+	}
+	return fmt.Sprintf("%v", r)
+}
+
+func writeOrders(w io.Writer, orders []redis.OrderRecord) {
+	fmt.Fprintln(w, "ORDER\tDATE\tSTATUS\tLISTING\tBID\tPLACED\tRESULT")
+	for _, o := range orders {
+		for _, b := range o.Order.BidStatus {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t$%.2f\t$%.2f\t%s\n",
+				o.Order.OrderID,
+				o.Order.OrderDate.Format("2006-01-02 15:04"),
+				orderStatusName(o.Order.OrderStatus),
+				b.ListingID,
+				b.BidAmount,
+				b.BidAmountPlaced,
+				bidResultName(b.Result))
+		}
+	}
+}
+
+func ordersCommand(args []string) error {
+	fs := newFlagSet("orders")
+	pendingOnly := fs.Bool("pending", false, "only show orders that are still in progress")
+	fs.Parse(args)
+	r, err := openStore()
+	if err != nil {
+		return err
+	}
+	orders, err := redis.Orders(r)
+	if err != nil {
+		return err
+	}
+	if *pendingOnly {
+		pending := []redis.OrderRecord{}
+		for _, o := range orders {
+			if isPending(o.Order) {
+				pending = append(pending, o)
+			}
+		}
+		orders = pending
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	writeOrders(w, orders)
+	return w.Flush()
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mtlynch/prosperbot/clock"
+	"github.com/mtlynch/prosperbot/reconcile"
+)
+
+func reconcileCommand(args []string) error {
+	fs := newFlagSet("reconcile")
+	configPath := configFlag(fs)
+	run := fs.Bool("run", false, "reconcile now instead of showing the last saved report")
+	fs.Parse(args)
+	var report reconcile.Report
+	if *run {
+		cfg, err := loadConfig(*configPath)
+		if err != nil {
+			return err
+		}
+		job, err := reconcile.NewJob(cfg.Reconciliation, clock.DefaultClock{})
+		if err != nil {
+			return err
+		}
+		if report, err = job.RunOnce(); err != nil {
+			return err
+		}
+	} else {
+		r, err := openStore()
+		if err != nil {
+			return err
+		}
+		var ok bool
+		report, ok, err = reconcile.LatestReport(r)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("No reconciliation report saved yet. Use -run to reconcile now.")
+			return nil
+		}
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Reconciliation as of %s: %d bids, %d notes\n", report.Timestamp.Format("2006-01-02 15:04:05"), report.BidsChecked, report.NotesChecked)
+	if len(report.Discrepancies) == 0 {
+		fmt.Fprintln(w, "No discrepancies.")
+		return w.Flush()
+	}
+	fmt.Fprintln(w, "\nListing\tProblem\tBid\tNotes\tOrders\tLoan notes")
+	for _, d := range report.Discrepancies {
+		orderIDs := make([]string, len(d.OrderIDs))
+		for i, id := range d.OrderIDs {
+			orderIDs[i] = string(id)
+		}
+		fmt.Fprintf(w, "%d\t%s\t$%.2f\t$%.2f\t%s\t%s\n", d.ListingNumber, d.Kind, d.BidAmount, d.NoteAmount, strings.Join(orderIDs, ","), strings.Join(d.LoanNoteIDs, ","))
+	}
+	return w.Flush()
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/mtlynch/prosperbot/record"
+)
+
+func replayCommand(args []string) error {
+	fs := newFlagSet("replay")
+	configPath := configFlag(fs)
+	verbose := fs.Bool("v", false, "show the bot's log output")
+	divergedOnly := fs.Bool("diverged", false, "only show listings where the replayed bot bid differently than the recorded one")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("usage: replay [-v] [-diverged] <API log>")
+	}
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %v", err)
+	}
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	entries, err := record.ReadEntries(f)
+	if err != nil {
+		return err
+	}
+	if !*verbose {
+		log.SetOutput(ioutil.Discard)
+		defer log.SetOutput(os.Stderr)
+	}
+	policy, err := newPolicy(cfg)
+	if err != nil {
+		return err
+	}
+	outcomes, err := record.Rerun(entries, policy)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "LISTING\tDECISION\tORDER\tRECORDED BID\tREASON")
+	diverged := 0
+	for _, o := range outcomes {
+		if o.Diverged() {
+			diverged++
+		} else if *divergedOnly {
+			continue
+		}
+		decision := "reject"
+		if o.Passed {
+			decision = "bid"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%v\t%s\n", o.ListingNumber, decision, o.OrderID, o.BidRecorded, o.Reason)
+	}
+	fmt.Fprintf(w, "\n%d listings replayed, %d decided differently than recorded\n", len(outcomes), diverged)
+	return w.Flush()
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+	"github.com/mtlynch/gofn-prosper/prosper/auth"
+
+	"github.com/mtlynch/prosperbot/account"
+	"github.com/mtlynch/prosperbot/buyer"
+	"github.com/mtlynch/prosperbot/clock"
+	"github.com/mtlynch/prosperbot/config"
+	"github.com/mtlynch/prosperbot/digest"
+	"github.com/mtlynch/prosperbot/maintenance"
+	"github.com/mtlynch/prosperbot/notes"
+	"github.com/mtlynch/prosperbot/notify"
+	"github.com/mtlynch/prosperbot/reconcile"
+	"github.com/mtlynch/prosperbot/record"
+	"github.com/mtlynch/prosperbot/scoring"
+)
+
+func parseCredentials(path string) (creds auth.ClientCredentials, err error) {
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return auth.ClientCredentials{}, err
+	}
+	err = json.Unmarshal(file, &creds)
+	if err != nil {
+		return auth.ClientCredentials{}, err
+	}
+	return creds, nil
+}
+
+func runCommand(args []string) error {
+	fs := newFlagSet("run")
+	configPath := configFlag(fs)
+	credsPath := fs.String("creds", "prosper-creds.json", "Prosper client credentials file")
+	isBuyingEnabled := fs.Bool("enable-buying", false, "is listing buying enabled?")
+	recordPath := fs.String("record", "", "append every Prosper API call to this file, with credentials redacted")
+	fs.Parse(args)
+	log.Println("Starting up!")
+	creds, err := parseCredentials(*credsPath)
+	if err != nil {
+		return fmt.Errorf("failed to parse credentials: %v", err)
+	}
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %v", err)
+	}
+	c := prosper.NewClient(creds)
+	if *recordPath != "" {
+		f, err := os.OpenFile(*recordPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to open API log: %v", err)
+		}
+		defer f.Close()
+		c = record.NewRecorder(c, f, clock.DefaultClock{}, creds.ClientID, creds.ClientSecret, creds.Username, creds.Password)
+	}
+	if err = startBot(c, cfg, *isBuyingEnabled, clock.DefaultClock{}); err != nil {
+		return err
+	}
+	for {
+		time.Sleep(10 * time.Minute)
+	}
+}
+
+// startBot starts every component of the bot in the background, talking to
+// Prosper through c and polling on clk.
+func startBot(c prosper.Client, cfg config.Config, isBuyingEnabled bool, clk clock.Timer) error {
+	policy, err := newPolicy(cfg)
+	if err != nil {
+		return err
+	}
+	f := buyer.DefaultSearchFilter()
+	buyer.Poll(1*time.Second, f, isBuyingEnabled, c, clk, policy)
+	if err = buyer.ObserveListings(1*time.Minute, c, clk); err != nil {
+		return fmt.Errorf("failed to start observing listings: %v", err)
+	}
+	account.Poll(1*time.Minute, c, clk)
+	notifiers := notify.NewNotifiers(cfg.Notifications)
+	var transitions chan notes.Transition
+	if len(notifiers) > 0 {
+		transitions = make(chan notes.Transition)
+		dispatcher, err := notify.NewDispatcher(transitions, cfg.Notifications, notifiers, clk)
+		if err != nil {
+			return fmt.Errorf("failed to create notification dispatcher: %v", err)
+		}
+		go dispatcher.Run()
+	}
+	notes.Poll(10*time.Minute, c, transitions, clk)
+	if cfg.Digest.Time != "" {
+		scheduler, err := digest.NewScheduler(cfg.Digest, notifiers, clk)
+		if err != nil {
+			return fmt.Errorf("failed to create digest scheduler: %v", err)
+		}
+		go scheduler.Run()
+	}
+	if cfg.Retention.Enabled() {
+		job, err := maintenance.NewJob(cfg.Retention, clk)
+		if err != nil {
+			return fmt.Errorf("failed to create maintenance job: %v", err)
+		}
+		go job.Run()
+	}
+	if cfg.Reconciliation.Enabled() {
+		job, err := reconcile.NewJob(cfg.Reconciliation, clk)
+		if err != nil {
+			return fmt.Errorf("failed to create reconciliation job: %v", err)
+		}
+		go job.Run()
+	}
+	return nil
+}
+
+// newPolicy creates the buying policy described by the config.
+func newPolicy(cfg config.Config) (buyer.Policy, error) {
+	risk, err := newRiskFilter(cfg.Risk)
+	if err != nil {
+		return buyer.Policy{}, err
+	}
+	return buyer.Policy{
+		Ranking:                newRanking(cfg.Scoring),
+		Risk:                   risk,
+		MaxExposurePerBorrower: cfg.Exposure.MaxPerBorrower,
+		ReevaluationInterval:   cfg.Reevaluation.Interval.Duration,
+	}, nil
+}
+
+// newRanking creates the listing ranking described by the scoring config.
+func newRanking(s config.Scoring) buyer.Ranking {
+	if !s.Enabled() {
+		return buyer.Ranking{}
+	}
+	return buyer.Ranking{
+		Scorer:      scoring.LinearModel{Intercept: s.Intercept, Weights: s.Weights},
+		BatchWindow: s.BatchWindow.Duration,
+	}
+}
+
+// newRiskFilter loads the default model named in the risk config.
+func newRiskFilter(r config.Risk) (buyer.RiskFilter, error) {
+	if !r.Enabled() {
+		return buyer.RiskFilter{}, nil
+	}
+	m, err := scoring.LoadModel(r.ModelPath)
+	if err != nil {
+		return buyer.RiskFilter{}, fmt.Errorf("failed to load default model: %v", err)
+	}
+	return buyer.RiskFilter{Predictor: m, MaxProbability: r.MaxDefaultProbability}, nil
+}
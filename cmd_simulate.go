@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mtlynch/prosperbot/sim"
+)
+
+func simulateCommand(args []string) error {
+	fs := newFlagSet("simulate")
+	days := fs.Int("days", 1, "days of virtual time to simulate")
+	listings := fs.Int("listings", 500, "synthetic listings per day")
+	seed := fs.Int64("seed", 1, "seed for the synthetic listings")
+	verbose := fs.Bool("v", false, "show the bot's log output")
+	fs.Parse(args)
+	if !*verbose {
+		log.SetOutput(ioutil.Discard)
+		defer log.SetOutput(os.Stderr)
+	}
+	started := time.Now()
+	result, err := sim.Run(sim.Config{
+		Duration:       time.Duration(*days) * 24 * time.Hour,
+		ListingsPerDay: *listings,
+		Seed:           *seed,
+	})
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Simulated %s to %s in %v\n", result.Start.Format("2006-01-02 15:04"), result.End.Format("2006-01-02 15:04"), time.Since(started))
+	fmt.Fprintf(w, "  Listings offered:\t%d\n", result.ListingsOffered)
+	fmt.Fprintf(w, "  Listings found by search:\t%d\n", result.ListingsSeen)
+	fmt.Fprintf(w, "  Listings passing filters:\t%d\n", result.ListingsPassed)
+	fmt.Fprintf(w, "  Bids placed:\t%d\n", result.BidsPlaced)
+	fmt.Fprintf(w, "  Amount invested:\t$%.2f\n", result.AmountInvested)
+	return w.Flush()
+}
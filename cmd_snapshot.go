@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/mtlynch/prosperbot/portfolio"
+)
+
+func snapshotCommand(args []string) error {
+	fs := newFlagSet("snapshot")
+	showNotes := fs.Bool("notes", false, "list the state of every note")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("usage: snapshot [-notes] <time (2006-01-02 or RFC 3339)>")
+	}
+	t, err := parseTime(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("invalid time: %v", err)
+	}
+	r, err := openStore()
+	if err != nil {
+		return err
+	}
+	s, err := portfolio.LoadSnapshot(r, t)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Portfolio as of %s\n", s.AsOf.Format("2006-01-02 15:04:05"))
+	if s.HasAccount {
+		a := s.Account
+		fmt.Fprintf(w, "  Total account value:\t$%.2f\n", a.TotalAccountValue)
+		fmt.Fprintf(w, "  Available cash:\t$%.2f\n", a.AvailableCashBalance)
+		fmt.Fprintf(w, "  Outstanding principal:\t$%.2f\n", a.OutstandingPrincipalOnActiveNotes)
+	} else {
+		fmt.Fprintln(w, "  No account information saved yet.")
+	}
+	fmt.Fprintf(w, "  Notes:\t%d (%d open)\n", len(s.Notes), len(s.OpenNotes()))
+	fmt.Fprintf(w, "  Late:\t%.1f%% of open notes\n", s.LateFraction()*100)
+	counts := s.StatusCounts()
+	statuses := []string{}
+	for status := range counts {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+	for _, status := range statuses {
+		fmt.Fprintf(w, "  %s:\t%d\n", status, counts[status])
+	}
+	if *showNotes {
+		fmt.Fprintln(w)
+		writeNotes(w, s.Notes)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/redis"
+)
+
+func isPending(o prosper.OrderResponse) bool {
+	if o.OrderStatus != prosper.OrderCompleted {
+		return true
+	}
+	for _, b := range o.BidStatus {
+		if b.Result == prosper.NoBidResult {
+			return true
+		}
+	}
+	return false
+}
+
+func statusCommand(args []string) error {
+	fs := newFlagSet("status")
+	fs.Parse(args)
+	r, err := openStore()
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	latest, err := redis.LatestAccount(r)
+	if err == redis.ErrAccountNotFound {
+		fmt.Fprintln(w, "No account information saved yet.")
+	} else if err != nil {
+		return err
+	} else {
+		a := latest.Value
+		fmt.Fprintf(w, "Account as of %s\n", latest.Timestamp.Format("2006-01-02 15:04:05"))
+		fmt.Fprintf(w, "  Total account value:\t$%.2f\n", a.TotalAccountValue)
+		fmt.Fprintf(w, "  Available cash:\t$%.2f\n", a.AvailableCashBalance)
+		fmt.Fprintf(w, "  Outstanding principal:\t$%.2f\n", a.OutstandingPrincipalOnActiveNotes)
+		fmt.Fprintf(w, "  Pending investments:\t$%.2f\n", a.PendingInvestmentsPrimaryMarket)
+		fmt.Fprintf(w, "  Last deposit:\t$%.2f on %s\n", a.LastDepositAmount, a.LastDepositDate.Format("2006-01-02"))
+		fmt.Fprintf(w, "  Last withdrawal:\t$%.2f on %s\n", a.LastWithdrawAmount, a.LastWithdrawDate.Format("2006-01-02"))
+	}
+	orders, err := redis.Orders(r)
+	if err != nil {
+		return err
+	}
+	pending := []redis.OrderRecord{}
+	for _, o := range orders {
+		if isPending(o.Order) {
+			pending = append(pending, o)
+		}
+	}
+	fmt.Fprintf(w, "\nPending orders: %d\n", len(pending))
+	writeOrders(w, pending)
+	return w.Flush()
+}
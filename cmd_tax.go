@@ -0,0 +1,38 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+
+	"github.com/mtlynch/prosperbot/tax"
+)
+
+func taxReportCommand(args []string) error {
+	fs := newFlagSet("tax-report")
+	year := fs.Int("year", 0, "tax year to report on")
+	format := fs.String("format", "csv", "format of the report (csv or json)")
+	outputPath := fs.String("output", "", "file to write the report to (default stdout)")
+	fs.Parse(args)
+	if *year == 0 {
+		return errors.New("-year is required")
+	}
+	r, err := openStore()
+	if err != nil {
+		return err
+	}
+	report, err := tax.Load(r, *year)
+	if err != nil {
+		return err
+	}
+	var w io.Writer = os.Stdout
+	if *outputPath != "" {
+		f, err := os.Create(*outputPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	return tax.Write(w, report, *format)
+}
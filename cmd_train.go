@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mtlynch/prosperbot/scoring"
+)
+
+func trainCommand(args []string) error {
+	fs := newFlagSet("train")
+	output := fs.String("output", "default-model.json", "file to save the model coefficients to")
+	minAge := fs.Int("min-age", 12, "months a note that is still open must have aged to count as repaid")
+	features := fs.String("features", "", "comma-separated listing features to fit (default: all of "+strings.Join(scoring.FeatureNames(), ", ")+")")
+	fs.Parse(args)
+	opts := scoring.TrainOptions{}
+	if *features != "" {
+		opts.Features = strings.Split(*features, ",")
+	}
+	r, err := openStore()
+	if err != nil {
+		return err
+	}
+	examples, err := scoring.LoadExamples(r, *minAge)
+	if err != nil {
+		return err
+	}
+	m, err := scoring.Train(examples, opts)
+	if err != nil {
+		return err
+	}
+	if err = scoring.SaveModel(m, *output); err != nil {
+		return err
+	}
+	defaults := 0
+	for _, e := range examples {
+		if e.Defaulted {
+			defaults++
+		}
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Trained on %d listings, %d of which defaulted\n", len(examples), defaults)
+	fmt.Fprintf(w, "Log loss:\t%.4f\n", scoring.LogLoss(m, examples))
+	fmt.Fprintf(w, "Intercept:\t%.6g\n", m.Intercept)
+	names := []string{}
+	for name := range m.Weights {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s:\t%.6g\n", name, m.Weights[name])
+	}
+	fmt.Fprintf(w, "Saved model to %s\n", *output)
+	return w.Flush()
+}
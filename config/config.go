@@ -0,0 +1,263 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mtlynch/prosperbot/scoring"
+)
+
+// Config holds the bot's settings, read from a JSON file.
+type Config struct {
+	Notifications  Notifications
+	Digest         Digest
+	Retention      Retention
+	Reconciliation Reconciliation
+	Scoring        Scoring
+	Risk           Risk
+	Exposure       Exposure
+	Reevaluation   Reevaluation
+}
+
+// Notifications controls alerts about changes in note status.
+type Notifications struct {
+	NotifyLate      bool
+	NotifyChargeOff bool
+	NotifyDefault   bool
+	// BatchWindow is how long to collect alerts before sending them together.
+	BatchWindow Duration
+	SMTP        *SMTP
+	Webhook     *Webhook
+}
+
+// SMTP describes an email server to send notifications through.
+type SMTP struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+// Webhook describes an HTTP endpoint that receives notifications as JSON.
+type Webhook struct {
+	URL string
+}
+
+// Digest controls the daily portfolio report.
+type Digest struct {
+	// Time is the local time of day to generate the digest, as "15:04". The
+	// digest is disabled if Time is empty.
+	Time string
+	// Directory, if set, is where digests are written as text and HTML files.
+	Directory string
+	// Notify sends digests through the configured notification backends.
+	Notify bool
+}
+
+// Retention controls how long history is kept in full. A zero value for any
+// setting keeps that history forever.
+type Retention struct {
+	// ListingDays is how many days after their start that full listing
+	// snapshots are kept. Expired listings are still remembered so that the
+	// bot never bids on them twice.
+	ListingDays int
+	// AccountDailyAfterDays is the age after which account history is thinned
+	// to one record per day.
+	AccountDailyAfterDays int
+	// NoteDiffAfterDays is the age after which note history is stored as
+	// changed fields only.
+	NoteDiffAfterDays int
+	// Interval is how often the maintenance job runs. Defaults to 24h.
+	Interval Duration
+}
+
+// Enabled reports whether any retention policy is set.
+func (r Retention) Enabled() bool {
+	return r.ListingDays > 0 || r.AccountDailyAfterDays > 0 || r.NoteDiffAfterDays > 0
+}
+
+// Reconciliation controls the job that checks completed orders against the
+// notes the bot owns.
+type Reconciliation struct {
+	// Interval is how often to reconcile. Reconciliation is disabled if
+	// Interval is zero.
+	Interval Duration
+	// GracePeriod is how long after a bid a missing note is tolerated.
+	// Defaults to 72h.
+	GracePeriod Duration
+}
+
+// Enabled reports whether the reconciliation job should run.
+func (r Reconciliation) Enabled() bool {
+	return r.Interval.Duration > 0
+}
+
+// Scoring controls how listings that pass the filters are ranked, so that the
+// bot bids on the highest-scoring ones first when cash is limited.
+type Scoring struct {
+	// Weights maps listing features, such as "EstimatedReturn" or
+	// "CreditScore", to their weight in a linear model. Ranking is disabled if
+	// there are no weights.
+	Weights   map[string]float64
+	Intercept float64
+	// BatchWindow is how long to collect listings before bidding on them in
+	// order of score. Defaults to 5s.
+	BatchWindow Duration
+}
+
+// Enabled reports whether listings are ranked.
+func (s Scoring) Enabled() bool {
+	return len(s.Weights) > 0
+}
+
+// Risk controls the filter that rejects listings that a model trained on the
+// bot's own notes predicts are likely to charge off or default.
+type Risk struct {
+	// ModelPath is a model file written by the train command. The filter is
+	// disabled if ModelPath is empty.
+	ModelPath string
+	// MaxDefaultProbability is the highest predicted probability of default
+	// that a listing may have.
+	MaxDefaultProbability float64
+}
+
+// Enabled reports whether listings are filtered by predicted default.
+func (r Risk) Enabled() bool {
+	return r.ModelPath != ""
+}
+
+// Exposure limits how much the bot lends to any one borrower.
+type Exposure struct {
+	// MaxPerBorrower is the most that may be at stake with one borrower,
+	// counting outstanding principal and pending bids. Zero means no limit.
+	// Borrowers are identified by the member key of their listings, so
+	// listings without one are not limited.
+	MaxPerBorrower float64
+}
+
+// Reevaluation controls how listings that the bot declined are reconsidered
+// when more cash becomes available or the buying rules change.
+type Reevaluation struct {
+	// Interval is how often to check for more cash or changed rules.
+	// Re-evaluation is disabled if Interval is zero.
+	Interval Duration
+}
+
+// Duration is a time.Duration that is represented in JSON as a string like
+// "90s" or "5m".
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// Load reads the config file at path. An empty path gives the default config.
+func Load(path string) (Config, error) {
+	if path == "" {
+		return Config{}, nil
+	}
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	var c Config
+	if err = json.Unmarshal(file, &c); err != nil {
+		return Config{}, err
+	}
+	return c, nil
+}
+
+// Validate checks that the settings in c are complete and consistent.
+func (c Config) Validate() error {
+	problems := []string{}
+	n := c.Notifications
+	if n.SMTP != nil {
+		if n.SMTP.Host == "" {
+			problems = append(problems, "Notifications.SMTP.Host is required")
+		}
+		if n.SMTP.Port <= 0 || n.SMTP.Port > 65535 {
+			problems = append(problems, fmt.Sprintf("Notifications.SMTP.Port is invalid: %d", n.SMTP.Port))
+		}
+		if n.SMTP.From == "" {
+			problems = append(problems, "Notifications.SMTP.From is required")
+		}
+		if len(n.SMTP.To) == 0 {
+			problems = append(problems, "Notifications.SMTP.To must have at least one recipient")
+		}
+	}
+	if n.Webhook != nil {
+		u, err := url.Parse(n.Webhook.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("Notifications.Webhook.URL is not a valid HTTP URL: %q", n.Webhook.URL))
+		}
+	}
+	hasBackend := n.SMTP != nil || n.Webhook != nil
+	if (n.NotifyLate || n.NotifyChargeOff || n.NotifyDefault) && !hasBackend {
+		problems = append(problems, "Notifications are enabled but no SMTP or Webhook backend is configured")
+	}
+	if n.BatchWindow.Duration < 0 {
+		problems = append(problems, "Notifications.BatchWindow must not be negative")
+	}
+	if c.Digest.Time != "" {
+		if _, err := time.Parse("15:04", c.Digest.Time); err != nil {
+			problems = append(problems, fmt.Sprintf("Digest.Time must be in 24-hour HH:MM format: %q", c.Digest.Time))
+		}
+		if c.Digest.Notify && !hasBackend {
+			problems = append(problems, "Digest.Notify is set but no SMTP or Webhook backend is configured")
+		}
+		if !c.Digest.Notify && c.Digest.Directory == "" {
+			problems = append(problems, "Digest.Time is set but digests have no Directory and Notify is not set")
+		}
+	}
+	r := c.Retention
+	if r.ListingDays < 0 || r.AccountDailyAfterDays < 0 || r.NoteDiffAfterDays < 0 {
+		problems = append(problems, "Retention periods must not be negative")
+	}
+	if r.Interval.Duration < 0 {
+		problems = append(problems, "Retention.Interval must not be negative")
+	}
+	if c.Reconciliation.Interval.Duration < 0 || c.Reconciliation.GracePeriod.Duration < 0 {
+		problems = append(problems, "Reconciliation durations must not be negative")
+	}
+	if err := (scoring.LinearModel{Weights: c.Scoring.Weights}).Validate(); err != nil {
+		problems = append(problems, "Scoring.Weights: "+err.Error())
+	}
+	if c.Scoring.BatchWindow.Duration < 0 {
+		problems = append(problems, "Scoring.BatchWindow must not be negative")
+	}
+	if c.Risk.Enabled() && (c.Risk.MaxDefaultProbability <= 0 || c.Risk.MaxDefaultProbability > 1) {
+		problems = append(problems, fmt.Sprintf("Risk.MaxDefaultProbability must be greater than 0 and at most 1: %v", c.Risk.MaxDefaultProbability))
+	}
+	if c.Reevaluation.Interval.Duration < 0 {
+		problems = append(problems, "Reevaluation.Interval must not be negative")
+	}
+	if c.Exposure.MaxPerBorrower < 0 {
+		problems = append(problems, "Exposure.MaxPerBorrower must not be negative")
+	}
+	if len(problems) > 0 {
+		return errors.New("invalid config:\n  " + strings.Join(problems, "\n  "))
+	}
+	return nil
+}
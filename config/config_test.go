@@ -0,0 +1,215 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	var tests = []struct {
+		contents string
+		want     Config
+		wantErr  bool
+		msg      string
+	}{
+		{
+			contents: `{}`,
+			want:     Config{},
+			msg:      "empty config should give default values",
+		},
+		{
+			contents: `{"Notifications":{"NotifyLate":true,"BatchWindow":"5m","Webhook":{"URL":"http://localhost/hook"}}}`,
+			want: Config{
+				Notifications: Notifications{
+					NotifyLate:  true,
+					BatchWindow: Duration{5 * time.Minute},
+					Webhook:     &Webhook{URL: "http://localhost/hook"},
+				},
+			},
+			msg: "notification settings should be parsed",
+		},
+		{
+			contents: `{"Notifications":{"BatchWindow":"5 minutes"}}`,
+			wantErr:  true,
+			msg:      "invalid durations should be rejected",
+		},
+		{
+			contents: `{{mock bad JSON`,
+			wantErr:  true,
+			msg:      "invalid JSON should be rejected",
+		},
+	}
+	for _, tt := range tests {
+		f, err := ioutil.TempFile("", "prosperbot-config")
+		if err != nil {
+			t.Fatalf("failed to create temp file: %v", err)
+		}
+		defer os.Remove(f.Name())
+		if _, err = f.WriteString(tt.contents); err != nil {
+			t.Fatalf("failed to write temp file: %v", err)
+		}
+		f.Close()
+		got, err := Load(f.Name())
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected error, got nil", tt.msg)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.msg, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: unexpected config. got: %+v, want: %+v", tt.msg, got, tt.want)
+		}
+	}
+}
+
+func TestLoadEmptyPath(t *testing.T) {
+	got, err := Load("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, Config{}) {
+		t.Errorf("unexpected config for empty path. got: %+v, want: %+v", got, Config{})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	var tests = []struct {
+		config  Config
+		wantErr bool
+		msg     string
+	}{
+		{
+			config: Config{},
+			msg:    "default config should be valid",
+		},
+		{
+			config: Config{
+				Notifications: Notifications{
+					NotifyLate: true,
+					SMTP: &SMTP{
+						Host: "mail.example.com",
+						Port: 587,
+						From: "bot@example.com",
+						To:   []string{"me@example.com"},
+					},
+				},
+				Digest: Digest{Time: "07:30", Notify: true},
+			},
+			msg: "complete SMTP config should be valid",
+		},
+		{
+			config: Config{
+				Notifications: Notifications{
+					SMTP: &SMTP{Host: "mail.example.com", Port: 587},
+				},
+			},
+			wantErr: true,
+			msg:     "SMTP config without sender or recipients should be invalid",
+		},
+		{
+			config: Config{
+				Notifications: Notifications{
+					Webhook: &Webhook{URL: "localhost/hook"},
+				},
+			},
+			wantErr: true,
+			msg:     "webhook URL without a scheme should be invalid",
+		},
+		{
+			config: Config{
+				Notifications: Notifications{NotifyChargeOff: true},
+			},
+			wantErr: true,
+			msg:     "enabled notifications without a backend should be invalid",
+		},
+		{
+			config: Config{
+				Digest: Digest{Time: "7:30pm", Directory: "/tmp"},
+			},
+			wantErr: true,
+			msg:     "digest time in the wrong format should be invalid",
+		},
+		{
+			config: Config{
+				Digest: Digest{Time: "19:30"},
+			},
+			wantErr: true,
+			msg:     "digest with nowhere to deliver it should be invalid",
+		},
+		{
+			config: Config{
+				Retention: Retention{ListingDays: 90, AccountDailyAfterDays: 30, NoteDiffAfterDays: 30},
+			},
+			msg: "retention policies should be valid",
+		},
+		{
+			config: Config{
+				Retention: Retention{ListingDays: -1},
+			},
+			wantErr: true,
+			msg:     "negative retention period should be invalid",
+		},
+		{
+			config: Config{
+				Reconciliation: Reconciliation{Interval: Duration{-time.Hour}},
+			},
+			wantErr: true,
+			msg:     "negative reconciliation interval should be invalid",
+		},
+		{
+			config: Config{
+				Scoring: Scoring{Weights: map[string]float64{"EstimatedReturn": 10.0, "CreditScore": 0.01}},
+			},
+			msg: "scoring on known features should be valid",
+		},
+		{
+			config: Config{
+				Scoring: Scoring{Weights: map[string]float64{"Horoscope": 1.0}},
+			},
+			wantErr: true,
+			msg:     "scoring on an unknown feature should be invalid",
+		},
+		{
+			config: Config{
+				Risk: Risk{ModelPath: "model.json", MaxDefaultProbability: 0.15},
+			},
+			msg: "risk filter with a threshold should be valid",
+		},
+		{
+			config: Config{
+				Risk: Risk{ModelPath: "model.json"},
+			},
+			wantErr: true,
+			msg:     "risk filter without a threshold should be invalid",
+		},
+		{
+			config: Config{
+				Exposure: Exposure{MaxPerBorrower: -25.0},
+			},
+			wantErr: true,
+			msg:     "negative exposure limit should be invalid",
+		},
+		{
+			config: Config{
+				Reevaluation: Reevaluation{Interval: Duration{-time.Minute}},
+			},
+			wantErr: true,
+			msg:     "negative re-evaluation interval should be invalid",
+		},
+	}
+	for _, tt := range tests {
+		err := tt.config.Validate()
+		if tt.wantErr && err == nil {
+			t.Errorf("%s: expected error, got nil", tt.msg)
+		} else if !tt.wantErr && err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.msg, err)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package digest
+
+import (
+	"sort"
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/redis"
+)
+
+// StatusChange is a note whose status changed during the digest period.
+type StatusChange struct {
+	LoanNoteID string
+	From       string
+	To         string
+	Timestamp  time.Time
+}
+
+// Digest summarizes portfolio activity over a period, usually one day.
+type Digest struct {
+	Start                time.Time
+	End                  time.Time
+	BidsPlaced           int
+	AmountBid            float64
+	BidsFilled           int
+	AmountFilled         float64
+	Deposits             float64
+	Withdrawals          float64
+	PrincipalReceived    float64
+	InterestReceived     float64
+	StatusChanges        []StatusChange
+	AccountValue         float64
+	PreviousAccountValue float64
+}
+
+// AccountValueChange is the change in total account value over the period.
+func (d Digest) AccountValueChange() float64 {
+	return d.AccountValue - d.PreviousAccountValue
+}
+
+// Build creates a digest of activity in [start, end) from the bot's history.
+func Build(r redis.RedisReader, start, end time.Time) (Digest, error) {
+	d := Digest{
+		Start:         start,
+		End:           end,
+		StatusChanges: []StatusChange{},
+	}
+	orders, err := redis.Orders(r)
+	if err != nil {
+		return Digest{}, err
+	}
+	addOrders(&d, orders)
+	account, err := redis.AccountHistory(r)
+	if err != nil {
+		return Digest{}, err
+	}
+	addAccount(&d, account)
+	notes, err := redis.AllNoteHistories(r)
+	if err != nil {
+		return Digest{}, err
+	}
+	for _, history := range notes {
+		addNote(&d, history)
+	}
+	sort.Sort(byTimestamp(d.StatusChanges))
+	return d, nil
+}
+
+type byTimestamp []StatusChange
+
+func (b byTimestamp) Len() int      { return len(b) }
+func (b byTimestamp) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b byTimestamp) Less(i, j int) bool {
+	if b[i].Timestamp.Equal(b[j].Timestamp) {
+		return b[i].LoanNoteID < b[j].LoanNoteID
+	}
+	return b[i].Timestamp.Before(b[j].Timestamp)
+}
+
+func (d Digest) contains(t time.Time) bool {
+	return !t.Before(d.Start) && t.Before(d.End)
+}
+
+func addOrders(d *Digest, orders []redis.OrderRecord) {
+	for _, o := range orders {
+		if !d.contains(o.Order.OrderDate) {
+			continue
+		}
+		for _, b := range o.Order.BidStatus {
+			d.BidsPlaced++
+			d.AmountBid += b.BidAmount
+			if b.Result == prosper.BidSucceeded {
+				d.BidsFilled++
+				d.AmountFilled += b.BidAmountPlaced
+			}
+		}
+	}
+}
+
+// addAccount adds cash movements and account value from the account history.
+// Prosper only reports the most recent deposit and withdrawal, so each one is
+// counted once per distinct date on which it was reported.
+func addAccount(d *Digest, history []redis.AccountRecord) {
+	deposits := map[int64]float64{}
+	withdrawals := map[int64]float64{}
+	for _, r := range history {
+		if d.contains(r.Value.LastDepositDate) {
+			deposits[r.Value.LastDepositDate.UnixNano()] = r.Value.LastDepositAmount
+		}
+		if d.contains(r.Value.LastWithdrawDate) {
+			withdrawals[r.Value.LastWithdrawDate.UnixNano()] = r.Value.LastWithdrawAmount
+		}
+	}
+	for _, amount := range deposits {
+		d.Deposits += amount
+	}
+	for _, amount := range withdrawals {
+		d.Withdrawals += amount
+	}
+	if current, ok := redis.AccountAt(history, d.End); ok {
+		d.AccountValue = current.Value.TotalAccountValue
+	}
+	if previous, ok := redis.AccountAt(history, d.Start); ok {
+		d.PreviousAccountValue = previous.Value.TotalAccountValue
+	}
+}
+
+// addNote adds payments received and status changes from a single note's
+// history.
+func addNote(d *Digest, history []redis.NoteRecord) {
+	if len(history) == 0 {
+		return
+	}
+	current, ok := redis.NoteAt(history, d.End)
+	if !ok {
+		return
+	}
+	// Payments received before we first saw the note are not attributed to
+	// the period.
+	baseline, ok := redis.NoteAt(history, d.Start)
+	if !ok {
+		baseline = history[0]
+	}
+	d.PrincipalReceived += current.Note.PrincipalPaidProRataShare - baseline.Note.PrincipalPaidProRataShare
+	d.InterestReceived += current.Note.InterestPaidProRataShare - baseline.Note.InterestPaidProRataShare
+	for i := 1; i < len(history); i++ {
+		prev, cur := history[i-1], history[i]
+		if !d.contains(cur.Timestamp) {
+			continue
+		}
+		if prev.Note.NoteStatusDescription == cur.Note.NoteStatusDescription {
+			continue
+		}
+		d.StatusChanges = append(d.StatusChanges, StatusChange{
+			LoanNoteID: cur.Note.LoanNoteID,
+			From:       prev.Note.NoteStatusDescription,
+			To:         cur.Note.NoteStatusDescription,
+			Timestamp:  cur.Timestamp,
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package digest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type mockRedisReader struct {
+	values map[string]string
+	lists  map[string][]string
+}
+
+func (r mockRedisReader) Get(key string) (string, error) {
+	return r.values[key], nil
+}
+
+func (r mockRedisReader) Keys(pattern string) ([]string, error) {
+	prefix := strings.TrimSuffix(pattern, "*")
+	keys := []string{}
+	for k := range r.values {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	for k := range r.lists {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+
+func (r mockRedisReader) LRange(key string, start int64, stop int64) ([]string, error) {
+	return r.lists[key], nil
+}
+
+var (
+	periodStart = time.Date(2016, 5, 1, 8, 0, 0, 0, time.UTC)
+	periodEnd   = time.Date(2016, 5, 2, 8, 0, 0, 0, time.UTC)
+	mockHistory = mockRedisReader{
+		values: map[string]string{
+			// Placed and filled during the period.
+			"order:id-a": `{"Order":{"OrderID":"id-a","BidStatus":[{"ListingID":123,"BidAmount":25,"Result":4,"BidAmountPlaced":25}],"OrderDate":"2016-05-01T09:00:00Z"},"Timestamp":"2016-05-01T09:01:00Z"}`,
+			// Placed during the period but failed.
+			"order:id-b": `{"Order":{"OrderID":"id-b","BidStatus":[{"ListingID":456,"BidAmount":25,"Result":3,"BidAmountPlaced":0}],"OrderDate":"2016-05-01T10:00:00Z"},"Timestamp":"2016-05-01T10:01:00Z"}`,
+			// Placed before the period.
+			"order:id-c": `{"Order":{"OrderID":"id-c","BidStatus":[{"ListingID":789,"BidAmount":25,"Result":4,"BidAmountPlaced":25}],"OrderDate":"2016-04-30T10:00:00Z"},"Timestamp":"2016-04-30T10:01:00Z"}`,
+		},
+		lists: map[string][]string{
+			"accountInformation": {
+				`{"Value":{"TotalAccountValue":1100,"LastDepositAmount":100,"LastDepositDate":"2016-05-01T15:00:00Z","LastWithdrawAmount":50,"LastWithdrawDate":"2016-04-20T15:00:00Z"},"Timestamp":"2016-05-01T15:05:00Z"}`,
+				`{"Value":{"TotalAccountValue":1050,"LastDepositAmount":100,"LastDepositDate":"2016-05-01T15:00:00Z","LastWithdrawAmount":50,"LastWithdrawDate":"2016-04-20T15:00:00Z"},"Timestamp":"2016-05-01T15:01:00Z"}`,
+				`{"Value":{"TotalAccountValue":1000,"LastDepositAmount":200,"LastDepositDate":"2016-04-01T15:00:00Z","LastWithdrawAmount":50,"LastWithdrawDate":"2016-04-20T15:00:00Z"},"Timestamp":"2016-04-25T15:00:00Z"}`,
+			},
+			"note:1619-2": {
+				`{"Note":{"LoanNoteID":"1619-2","PrincipalPaidProRataShare":3,"InterestPaidProRataShare":1.5,"NoteStatusDescription":"CURRENT"},"Timestamp":"2016-05-01T20:00:00Z"}`,
+				`{"Note":{"LoanNoteID":"1619-2","PrincipalPaidProRataShare":1,"InterestPaidProRataShare":0.5,"NoteStatusDescription":"CURRENT"},"Timestamp":"2016-04-01T20:00:00Z"}`,
+			},
+			"note:2000-1": {
+				`{"Note":{"LoanNoteID":"2000-1","PrincipalPaidProRataShare":5,"InterestPaidProRataShare":2,"NoteStatusDescription":"CHARGEOFF"},"Timestamp":"2016-05-01T21:00:00Z"}`,
+				`{"Note":{"LoanNoteID":"2000-1","PrincipalPaidProRataShare":5,"InterestPaidProRataShare":2,"NoteStatusDescription":"CURRENT"},"Timestamp":"2016-04-01T20:00:00Z"}`,
+			},
+			// First seen during the period, so earlier payments don't count.
+			"note:3000-1": {
+				`{"Note":{"LoanNoteID":"3000-1","PrincipalPaidProRataShare":10,"InterestPaidProRataShare":4,"NoteStatusDescription":"CURRENT"},"Timestamp":"2016-05-01T21:00:00Z"}`,
+			},
+		},
+	}
+)
+
+func TestBuild(t *testing.T) {
+	got, err := Build(mockHistory, periodStart, periodEnd)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := Digest{
+		Start:                periodStart,
+		End:                  periodEnd,
+		BidsPlaced:           2,
+		AmountBid:            50,
+		BidsFilled:           1,
+		AmountFilled:         25,
+		Deposits:             100,
+		Withdrawals:          0,
+		PrincipalReceived:    2,
+		InterestReceived:     1,
+		AccountValue:         1100,
+		PreviousAccountValue: 1000,
+		StatusChanges: []StatusChange{
+			{
+				LoanNoteID: "2000-1",
+				From:       "CURRENT",
+				To:         "CHARGEOFF",
+				Timestamp:  time.Date(2016, 5, 1, 21, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected digest. got: %+v, want: %+v", got, want)
+	}
+	if got.AccountValueChange() != 100 {
+		t.Errorf("unexpected account value change. got: %v, want: %v", got.AccountValueChange(), 100)
+	}
+}
+
+func TestBuildEmptyHistory(t *testing.T) {
+	got, err := Build(mockRedisReader{}, periodStart, periodEnd)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := Digest{
+		Start:         periodStart,
+		End:           periodEnd,
+		StatusChanges: []StatusChange{},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected digest. got: %+v, want: %+v", got, want)
+	}
+}
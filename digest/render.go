@@ -0,0 +1,65 @@
+package digest
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"text/template"
+
+	"github.com/mtlynch/prosperbot/notify"
+)
+
+var funcs = map[string]interface{}{
+	"date": func(d Digest) string { return d.End.Format("Monday, January 2, 2006") },
+}
+
+var textTemplate = template.Must(template.New("text").Funcs(funcs).Parse(`ProsperBot digest for {{date .}}
+
+Account value: ${{printf "%.2f" .AccountValue}} ({{printf "%+.2f" .AccountValueChange}} from ${{printf "%.2f" .PreviousAccountValue}})
+
+Bids placed: {{.BidsPlaced}} (${{printf "%.2f" .AmountBid}})
+Bids filled: {{.BidsFilled}} (${{printf "%.2f" .AmountFilled}})
+
+Cash deposited: ${{printf "%.2f" .Deposits}}
+Cash withdrawn: ${{printf "%.2f" .Withdrawals}}
+Principal received: ${{printf "%.2f" .PrincipalReceived}}
+Interest received: ${{printf "%.2f" .InterestReceived}}
+
+Note status changes:{{if not .StatusChanges}} none{{end}}
+{{range .StatusChanges}}  {{.LoanNoteID}}: {{.From}} -> {{.To}}
+{{end}}`))
+
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("html").Funcs(funcs).Parse(`<html>
+<body>
+<h1>ProsperBot digest for {{date .}}</h1>
+<p>Account value: ${{printf "%.2f" .AccountValue}} ({{printf "%+.2f" .AccountValueChange}} from ${{printf "%.2f" .PreviousAccountValue}})</p>
+<table>
+<tr><td>Bids placed</td><td>{{.BidsPlaced}}</td><td>${{printf "%.2f" .AmountBid}}</td></tr>
+<tr><td>Bids filled</td><td>{{.BidsFilled}}</td><td>${{printf "%.2f" .AmountFilled}}</td></tr>
+<tr><td>Cash deposited</td><td></td><td>${{printf "%.2f" .Deposits}}</td></tr>
+<tr><td>Cash withdrawn</td><td></td><td>${{printf "%.2f" .Withdrawals}}</td></tr>
+<tr><td>Principal received</td><td></td><td>${{printf "%.2f" .PrincipalReceived}}</td></tr>
+<tr><td>Interest received</td><td></td><td>${{printf "%.2f" .InterestReceived}}</td></tr>
+</table>
+<h2>Note status changes</h2>
+{{if .StatusChanges}}<ul>
+{{range .StatusChanges}}<li>{{.LoanNoteID}}: {{.From}} &rarr; {{.To}}</li>
+{{end}}</ul>{{else}}<p>None</p>{{end}}
+</body>
+</html>
+`))
+
+// Render formats the digest as a message with plain text and HTML bodies.
+func Render(d Digest) (notify.Message, error) {
+	var text, html bytes.Buffer
+	if err := textTemplate.Execute(&text, d); err != nil {
+		return notify.Message{}, err
+	}
+	if err := htmlTemplate.Execute(&html, d); err != nil {
+		return notify.Message{}, err
+	}
+	return notify.Message{
+		Subject: "ProsperBot digest for " + d.End.Format("2006-01-02"),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
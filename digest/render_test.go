@@ -0,0 +1,61 @@
+package digest
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRender(t *testing.T) {
+	d := Digest{
+		Start:                periodStart,
+		End:                  periodEnd,
+		BidsPlaced:           2,
+		AmountBid:            50,
+		BidsFilled:           1,
+		AmountFilled:         25,
+		AccountValue:         1100,
+		PreviousAccountValue: 1000,
+		StatusChanges: []StatusChange{
+			{LoanNoteID: "2000-1", From: "CURRENT", To: "CHARGEOFF", Timestamp: time.Date(2016, 5, 1, 21, 0, 0, 0, time.UTC)},
+		},
+	}
+	m, err := Render(d)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.Subject != "ProsperBot digest for 2016-05-02" {
+		t.Errorf("unexpected subject: %s", m.Subject)
+	}
+	for _, want := range []string{
+		"Account value: $1100.00 (+100.00 from $1000.00)",
+		"Bids placed: 2 ($50.00)",
+		"Bids filled: 1 ($25.00)",
+		"2000-1: CURRENT -> CHARGEOFF",
+	} {
+		if !strings.Contains(m.Text, want) {
+			t.Errorf("expected text digest to contain %q, got: %s", want, m.Text)
+		}
+	}
+	for _, want := range []string{
+		"<h1>ProsperBot digest for Monday, May 2, 2016</h1>",
+		"<li>2000-1: CURRENT &rarr; CHARGEOFF</li>",
+	} {
+		if !strings.Contains(m.HTML, want) {
+			t.Errorf("expected HTML digest to contain %q, got: %s", want, m.HTML)
+		}
+	}
+}
+
+func TestRenderNoStatusChanges(t *testing.T) {
+	m, err := Render(Digest{End: periodEnd, StatusChanges: []StatusChange{}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(m.Text, "Note status changes: none") {
+		t.Errorf("expected text digest to report no status changes, got: %s", m.Text)
+	}
+	if !strings.Contains(m.HTML, "<p>None</p>") {
+		t.Errorf("expected HTML digest to report no status changes, got: %s", m.HTML)
+	}
+}
@@ -0,0 +1,98 @@
+package digest
+
+import (
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/mtlynch/prosperbot/clock"
+	"github.com/mtlynch/prosperbot/config"
+	"github.com/mtlynch/prosperbot/notify"
+	"github.com/mtlynch/prosperbot/redis"
+)
+
+type scheduler struct {
+	redis     redis.RedisReader
+	hour      int
+	minute    int
+	directory string
+	notifiers []notify.Notifier
+	clock     clock.Timer
+}
+
+// NewScheduler creates a scheduler that publishes a digest every day at the
+// time given in c. notifiers are used only if c.Notify is set.
+func NewScheduler(c config.Digest, notifiers []notify.Notifier, clk clock.Timer) (scheduler, error) {
+	t, err := time.Parse("15:04", c.Time)
+	if err != nil {
+		return scheduler{}, err
+	}
+	r, err := redis.New()
+	if err != nil {
+		return scheduler{}, err
+	}
+	if !c.Notify {
+		notifiers = []notify.Notifier{}
+	}
+	return scheduler{
+		redis:     r,
+		hour:      t.Hour(),
+		minute:    t.Minute(),
+		directory: c.Directory,
+		notifiers: notifiers,
+		clock:     clk,
+	}, nil
+}
+
+// nextRun returns the first time after now that falls at hour:minute in now's
+// time zone.
+func nextRun(now time.Time, hour, minute int) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+func (s scheduler) Run() {
+	log.Printf("starting daily digest at %02d:%02d", s.hour, s.minute)
+	for {
+		now := s.clock.Now()
+		next := nextRun(now, s.hour, s.minute)
+		s.clock.Sleep(next.Sub(now))
+		if err := s.publish(next.AddDate(0, 0, -1), next); err != nil {
+			log.Printf("failed to publish daily digest: %v", err)
+		}
+	}
+}
+
+func (s scheduler) publish(start, end time.Time) error {
+	d, err := Build(s.redis, start, end)
+	if err != nil {
+		return err
+	}
+	m, err := Render(d)
+	if err != nil {
+		return err
+	}
+	if s.directory != "" {
+		if err = writeFiles(s.directory, end, m); err != nil {
+			log.Printf("failed to write digest to %s: %v", s.directory, err)
+		}
+	}
+	for _, n := range s.notifiers {
+		if err = n.SendMessage(m); err != nil {
+			log.Printf("failed to send digest: %v", err)
+		}
+	}
+	return nil
+}
+
+func writeFiles(directory string, date time.Time, m notify.Message) error {
+	base := filepath.Join(directory, "digest-"+date.Format("2006-01-02"))
+	if err := ioutil.WriteFile(base+".txt", []byte(m.Text), 0644); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(base+".html", []byte(m.HTML), 0644)
+}
@@ -0,0 +1,90 @@
+package digest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mtlynch/prosperbot/clock"
+	"github.com/mtlynch/prosperbot/notify"
+)
+
+func TestNextRun(t *testing.T) {
+	var tests = []struct {
+		now  time.Time
+		want time.Time
+		msg  string
+	}{
+		{
+			now:  time.Date(2016, 5, 1, 6, 0, 0, 0, time.UTC),
+			want: time.Date(2016, 5, 1, 8, 0, 0, 0, time.UTC),
+			msg:  "before the digest time, run later the same day",
+		},
+		{
+			now:  time.Date(2016, 5, 1, 8, 0, 0, 0, time.UTC),
+			want: time.Date(2016, 5, 2, 8, 0, 0, 0, time.UTC),
+			msg:  "exactly at the digest time, run the next day",
+		},
+		{
+			now:  time.Date(2016, 12, 31, 9, 0, 0, 0, time.UTC),
+			want: time.Date(2017, 1, 1, 8, 0, 0, 0, time.UTC),
+			msg:  "after the digest time, run the next day",
+		},
+	}
+	for _, tt := range tests {
+		if got := nextRun(tt.now, 8, 0); !got.Equal(tt.want) {
+			t.Errorf("%s: unexpected next run. got: %v, want: %v", tt.msg, got, tt.want)
+		}
+	}
+}
+
+type mockNotifier struct {
+	messages []notify.Message
+}
+
+func (n *mockNotifier) Notify(events []notify.Event) error {
+	return nil
+}
+
+func (n *mockNotifier) SendMessage(m notify.Message) error {
+	n.messages = append(n.messages, m)
+	return nil
+}
+
+func TestPublish(t *testing.T) {
+	dir, err := ioutil.TempDir("", "prosperbot-digest")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	notifier := mockNotifier{}
+	s := scheduler{
+		redis:     mockHistory,
+		directory: dir,
+		notifiers: []notify.Notifier{&notifier},
+		clock:     clock.NewVirtual(periodEnd),
+	}
+	if err = s.publish(periodStart, periodEnd); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(notifier.messages) != 1 {
+		t.Fatalf("unexpected messages sent. got: %d, want: 1", len(notifier.messages))
+	}
+	text, err := ioutil.ReadFile(filepath.Join(dir, "digest-2016-05-02.txt"))
+	if err != nil {
+		t.Fatalf("failed to read text digest: %v", err)
+	}
+	if string(text) != notifier.messages[0].Text {
+		t.Errorf("text digest file doesn't match sent digest. got: %s, want: %s", text, notifier.messages[0].Text)
+	}
+	html, err := ioutil.ReadFile(filepath.Join(dir, "digest-2016-05-02.html"))
+	if err != nil {
+		t.Fatalf("failed to read HTML digest: %v", err)
+	}
+	if !strings.HasPrefix(string(html), "<html>") {
+		t.Errorf("unexpected HTML digest file: %s", html)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+	"github.com/mtlynch/gofn-prosper/prosper/auth"
+
+	"github.com/mtlynch/prosperbot/clock"
+	"github.com/mtlynch/prosperbot/config"
+	"github.com/mtlynch/prosperbot/prospertest"
+	"github.com/mtlynch/prosperbot/redis"
+)
+
+// waitFor polls check until it returns true or the timeout passes.
+func waitFor(timeout time.Duration, check func() bool) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if check() {
+			return true
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return false
+}
+
+func TestEndToEnd(t *testing.T) {
+	store := redis.NewMemoryStore(clock.DefaultClock{})
+	defer redis.UseMemoryStore(store)()
+	r := store.Client()
+
+	server := prospertest.NewServer(prospertest.Scenario{
+		Listings: []prospertest.Object{
+			{
+				"listing_number":                123,
+				"listing_start_date":            time.Now().UTC().Format("2006-01-02 15:04:05 -0700"),
+				"listing_status":                2,
+				"prosper_rating":                "A",
+				"estimated_return":              0.1,
+				"income_range":                  4,
+				"inquiries_last6_months":        0,
+				"dti_wprosper_loan":             0.2,
+				"current_delinquencies":         0,
+				"employment_status_description": "Employed",
+				"prior_prosper_loans_late_payments_one_month_plus": 0,
+				"prior_prosper_loans_balance_outstanding":          0,
+			},
+		},
+		Account:    prospertest.Object{"available_cash_balance": 100.0, "total_account_value": 100.0},
+		IssueNotes: true,
+	})
+	defer server.Close()
+	defer server.InstallDefaultTransport()()
+
+	c := prosper.NewClient(auth.ClientCredentials{
+		ClientID:     "mock-client-id",
+		ClientSecret: "mock-client-secret",
+		Username:     "mock-user",
+		Password:     "mock-password",
+	})
+	if err := startBot(c, config.Config{}, true, clock.DefaultClock{}); err != nil {
+		t.Fatalf("failed to start bot: %v", err)
+	}
+
+	if !waitFor(10*time.Second, func() bool {
+		history, err := redis.AccountHistory(r)
+		return err == nil && len(history) > 0
+	}) {
+		t.Errorf("account information was never saved")
+	}
+	if !waitFor(10*time.Second, func() bool {
+		orders, err := redis.Orders(r)
+		if err != nil || len(orders) == 0 {
+			return false
+		}
+		return orders[len(orders)-1].Order.OrderStatus == prosper.OrderCompleted
+	}) {
+		t.Fatalf("no completed order was saved. requests: %v", server.Requests())
+	}
+	lineage, err := redis.LineageForListing(r, 123)
+	if err != nil {
+		t.Fatalf("failed to read lineage: %v", err)
+	}
+	if lineage.Listing == nil || lineage.Decision == nil || !lineage.Decision.Passed || lineage.Order == nil {
+		t.Errorf("lineage should link the listing, decision and order. got: %+v", lineage)
+	}
+}
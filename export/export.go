@@ -0,0 +1,255 @@
+package export
+
+import (
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/redis"
+)
+
+// TimeRange limits an export to records within [Since, Until). A zero bound is
+// unbounded.
+type TimeRange struct {
+	Since time.Time
+	Until time.Time
+}
+
+func (r TimeRange) Contains(t time.Time) bool {
+	if !r.Since.IsZero() && t.Before(r.Since) {
+		return false
+	}
+	if !r.Until.IsZero() && !t.Before(r.Until) {
+		return false
+	}
+	return true
+}
+
+// Columns are never reordered or removed so that scripts reading the output
+// keep working. Enumerated values are written as their numeric codes.
+
+var AccountColumns = []string{
+	"Timestamp",
+	"AvailableCashBalance",
+	"PendingInvestmentsPrimaryMarket",
+	"TotalAmountInvestedOnActiveNotes",
+	"OutstandingPrincipalOnActiveNotes",
+	"TotalPrincipalReceivedOnActiveNotes",
+	"TotalAccountValue",
+	"LastDepositAmount",
+	"LastDepositDate",
+	"LastWithdrawAmount",
+	"LastWithdrawDate",
+}
+
+// Accounts writes every account record saved within the time range.
+func Accounts(r redis.RedisReader, w RowWriter, tr TimeRange) error {
+	history, err := redis.AccountHistory(r)
+	if err != nil {
+		return err
+	}
+	for _, rec := range history {
+		if !tr.Contains(rec.Timestamp) {
+			continue
+		}
+		a := rec.Value
+		err = w.WriteRow([]interface{}{
+			rec.Timestamp,
+			a.AvailableCashBalance,
+			a.PendingInvestmentsPrimaryMarket,
+			a.TotalAmountInvestedOnActiveNotes,
+			a.OutstandingPrincipalOnActiveNotes,
+			a.TotalPrincipalReceivedOnActiveNotes,
+			a.TotalAccountValue,
+			a.LastDepositAmount,
+			a.LastDepositDate,
+			a.LastWithdrawAmount,
+			a.LastWithdrawDate,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+var NoteColumns = []string{
+	"Timestamp",
+	"LoanNoteID",
+	"ListingNumber",
+	"LoanNumber",
+	"Rating",
+	"Term",
+	"BorrowerRate",
+	"OriginationDate",
+	"NoteOwnershipAmount",
+	"NoteStatus",
+	"NoteStatusDescription",
+	"DaysPastDue",
+	"NextPaymentDueDate",
+	"PrincipalBalanceProRataShare",
+	"PrincipalPaidProRataShare",
+	"InterestPaidProRataShare",
+	"LateFeesPaidProRataShare",
+	"ServiceFeesPaidProRataShare",
+	"DebtSaleProceedsReceivedProRataShare",
+}
+
+func noteRow(rec redis.NoteRecord) []interface{} {
+	n := rec.Note
+	return []interface{}{
+		rec.Timestamp,
+		n.LoanNoteID,
+		int64(n.ListingNumber),
+		n.LoanNumber,
+		int(n.Rating),
+		n.Term,
+		n.BorrowerRate,
+		n.OriginationDate,
+		n.NoteOwnershipAmount,
+		int(n.NoteStatus),
+		n.NoteStatusDescription,
+		n.DaysPastDue,
+		n.NextPaymentDueDate,
+		n.PrincipalBalanceProRataShare,
+		n.PrincipalPaidProRataShare,
+		n.InterestPaidProRataShare,
+		n.LateFeesPaidProRataShare,
+		n.ServiceFeesPaidProRataShare,
+		n.DebtSaleProceedsReceivedProRataShare,
+	}
+}
+
+// Notes writes note snapshots saved within the time range. Unless full is
+// set, only the latest snapshot in the range is written for each note. Notes
+// are read one at a time so that the whole history never has to fit in
+// memory.
+func Notes(r redis.RedisReader, w RowWriter, tr TimeRange, full bool) error {
+	ids, err := redis.NoteIDs(r)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		history, err := redis.NoteHistory(r, id)
+		if err != nil {
+			return err
+		}
+		inRange := []redis.NoteRecord{}
+		for _, rec := range history {
+			if tr.Contains(rec.Timestamp) {
+				inRange = append(inRange, rec)
+			}
+		}
+		if !full && len(inRange) > 0 {
+			inRange = inRange[len(inRange)-1:]
+		}
+		for _, rec := range inRange {
+			if err = w.WriteRow(noteRow(rec)); err != nil {
+				return err
+			}
+		}
+	}
+	return w.Flush()
+}
+
+var OrderColumns = []string{
+	"OrderID",
+	"OrderDate",
+	"OrderStatus",
+	"ListingID",
+	"BidAmount",
+	"BidAmountPlaced",
+	"BidStatus",
+	"BidResult",
+	"Timestamp",
+}
+
+// Orders writes one row per bid for orders placed within the time range.
+func Orders(r redis.RedisReader, w RowWriter, tr TimeRange) error {
+	orders, err := redis.Orders(r)
+	if err != nil {
+		return err
+	}
+	for _, rec := range orders {
+		o := rec.Order
+		if !tr.Contains(o.OrderDate) {
+			continue
+		}
+		for _, b := range o.BidStatus {
+			err = w.WriteRow([]interface{}{
+				string(o.OrderID),
+				o.OrderDate,
+				int(o.OrderStatus),
+				int64(b.ListingID),
+				b.BidAmount,
+				b.BidAmountPlaced,
+				int(b.Status),
+				int(b.Result),
+				rec.Timestamp,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return w.Flush()
+}
+
+var ListingColumns = []string{
+	"ListingNumber",
+	"ListingStartDate",
+	"ListingStatus",
+	"ListingAmount",
+	"ProsperRating",
+	"ListingTerm",
+	"BorrowerRate",
+	"LenderYield",
+	"EstimatedReturn",
+	"EstimatedLossRate",
+	"IncomeRange",
+	"StatedMonthlyIncome",
+	"DtiWprosperLoan",
+	"EmploymentStatusDescription",
+	"FicoScore",
+	"CurrentDelinquencies",
+	"InquiriesLast6Months",
+	"PriorProsperLoans",
+	"PriorProsperLoansBalanceOutstanding",
+	"PriorProsperLoansLatePaymentsOneMonthPlus",
+}
+
+// Listings writes every saved listing that started within the time range, in
+// no particular order. Listings are streamed from the store one at a time.
+func Listings(r redis.RedisScanner, w RowWriter, tr TimeRange) error {
+	err := redis.ForEachListing(r, func(l prosper.Listing) error {
+		if !tr.Contains(l.ListingStartDate) {
+			return nil
+		}
+		return w.WriteRow([]interface{}{
+			int64(l.ListingNumber),
+			l.ListingStartDate,
+			int(l.ListingStatus),
+			l.ListingAmount,
+			int(l.ProsperRating),
+			l.ListingTerm,
+			l.BorrowerRate,
+			l.LenderYield,
+			l.EstimatedReturn,
+			l.EstimatedLossRate,
+			int(l.IncomeRange),
+			l.StatedMonthlyIncome,
+			l.DtiWprosperLoan,
+			l.EmploymentStatusDescription,
+			l.FicoScore,
+			l.CurrentDelinquencies,
+			l.InquiriesLast6Months,
+			l.PriorProsperLoans,
+			l.PriorProsperLoansBalanceOutstanding,
+			l.PriorProsperLoansLatePaymentsOneMonthPlus,
+		})
+	})
+	if err != nil {
+		return err
+	}
+	return w.Flush()
+}
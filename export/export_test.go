@@ -0,0 +1,178 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/redis"
+)
+
+type mockRedisReader struct {
+	values map[string]string
+	lists  map[string][]string
+}
+
+func (r mockRedisReader) Get(key string) (string, error) {
+	return r.values[key], nil
+}
+
+func (r mockRedisReader) Keys(pattern string) ([]string, error) {
+	prefix := strings.TrimSuffix(pattern, "*")
+	keys := []string{}
+	for k := range r.values {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	for k := range r.lists {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+
+func (r mockRedisReader) Scan(cursor int64, arguments ...interface{}) (int64, []string, error) {
+	keys, err := r.Keys(arguments[1].(string))
+	sort.Strings(keys)
+	return 0, keys, err
+}
+
+func (r mockRedisReader) LRange(key string, start int64, stop int64) ([]string, error) {
+	return r.lists[key], nil
+}
+
+func mustMarshal(t *testing.T, v interface{}) string {
+	s, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("failed to serialize %v: %v", v, err)
+	}
+	return string(s)
+}
+
+var (
+	jan1 = time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb1 = time.Date(2016, 2, 1, 0, 0, 0, 0, time.UTC)
+	mar1 = time.Date(2016, 3, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func TestTimeRangeContains(t *testing.T) {
+	var tests = []struct {
+		tr   TimeRange
+		t    time.Time
+		want bool
+		msg  string
+	}{
+		{TimeRange{}, jan1, true, "unbounded range should contain everything"},
+		{TimeRange{Since: feb1}, jan1, false, "time before Since should be excluded"},
+		{TimeRange{Since: feb1}, feb1, true, "Since should be inclusive"},
+		{TimeRange{Until: feb1}, feb1, false, "Until should be exclusive"},
+		{TimeRange{Since: jan1, Until: mar1}, feb1, true, "time within bounds should be included"},
+	}
+	for _, tt := range tests {
+		if got := tt.tr.Contains(tt.t); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.msg, got, tt.want)
+		}
+	}
+}
+
+func noteStore(t *testing.T) mockRedisReader {
+	history := []string{}
+	for _, ts := range []time.Time{jan1, feb1, mar1} {
+		rec := redis.NoteRecord{
+			Note: prosper.Note{
+				LoanNoteID:                   "1619-2",
+				ListingNumber:                1619,
+				PrincipalBalanceProRataShare: 25,
+			},
+			Timestamp: ts,
+		}
+		// History lists are stored newest first.
+		history = append([]string{mustMarshal(t, rec)}, history...)
+	}
+	return mockRedisReader{
+		lists: map[string][]string{redis.KeyPrefixNote + "1619-2": history},
+	}
+}
+
+func TestNotesCSV(t *testing.T) {
+	var tests = []struct {
+		full     bool
+		wantRows []string
+		msg      string
+	}{
+		{false, []string{"2016-02-01T00:00:00Z"}, "should write latest snapshot in range"},
+		{true, []string{"2016-01-01T00:00:00Z", "2016-02-01T00:00:00Z"}, "should write every snapshot in range"},
+	}
+	for _, tt := range tests {
+		var b bytes.Buffer
+		w, err := NewRowWriter(&b, "csv", NoteColumns)
+		if err != nil {
+			t.Fatalf("%s: failed to create writer: %v", tt.msg, err)
+		}
+		err = Notes(noteStore(t), w, TimeRange{Until: mar1}, tt.full)
+		if err != nil {
+			t.Fatalf("%s: export failed: %v", tt.msg, err)
+		}
+		lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+		if lines[0] != strings.Join(NoteColumns, ",") {
+			t.Errorf("%s: unexpected header: %s", tt.msg, lines[0])
+		}
+		if len(lines)-1 != len(tt.wantRows) {
+			t.Fatalf("%s: got %d rows, want %d", tt.msg, len(lines)-1, len(tt.wantRows))
+		}
+		for i, want := range tt.wantRows {
+			if !strings.HasPrefix(lines[i+1], want+",1619-2,1619,") {
+				t.Errorf("%s: unexpected row: %s", tt.msg, lines[i+1])
+			}
+		}
+	}
+}
+
+func TestListingsNDJSON(t *testing.T) {
+	r := mockRedisReader{values: map[string]string{}}
+	for _, l := range []prosper.Listing{
+		{ListingNumber: 1, ListingStartDate: jan1, ProsperRating: 2, FicoScore: "700-719"},
+		{ListingNumber: 2, ListingStartDate: mar1},
+	} {
+		r.values[redis.KeyPrefixListing+mustMarshal(t, l.ListingNumber)] = mustMarshal(t, l)
+	}
+	var b bytes.Buffer
+	w, err := NewRowWriter(&b, "ndjson", ListingColumns)
+	if err != nil {
+		t.Fatalf("failed to create writer: %v", err)
+	}
+	if err = Listings(r, w, TimeRange{Until: feb1}); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("got %d lines, want 1: %s", len(lines), b.String())
+	}
+	want := `{"ListingNumber":1,"ListingStartDate":"2016-01-01T00:00:00Z","ListingStatus":0,"ListingAmount":0,"ProsperRating":2,`
+	if !strings.HasPrefix(lines[0], want) {
+		t.Errorf("unexpected line. got: %s, want prefix: %s", lines[0], want)
+	}
+	var decoded map[string]interface{}
+	if err = json.Unmarshal([]byte(lines[0]), &decoded); err != nil {
+		t.Fatalf("line is not valid JSON: %v", err)
+	}
+	if len(decoded) != len(ListingColumns) {
+		t.Errorf("got %d fields, want %d", len(decoded), len(ListingColumns))
+	}
+	if decoded["FicoScore"] != "700-719" {
+		t.Errorf("unexpected FicoScore. got: %v, want: %v", decoded["FicoScore"], "700-719")
+	}
+}
+
+func TestNewRowWriterUnrecognizedFormat(t *testing.T) {
+	if _, err := NewRowWriter(&bytes.Buffer{}, "parquet", AccountColumns); err == nil {
+		t.Error("expected error for unrecognized format")
+	}
+}
@@ -0,0 +1,113 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// RowWriter writes rows that share a fixed set of columns.
+type RowWriter interface {
+	WriteRow(values []interface{}) error
+	Flush() error
+}
+
+// NewRowWriter creates a writer for the given format, either "csv" or
+// "ndjson". CSV output starts with a header row. Each NDJSON line is an object
+// with the columns as keys, in column order.
+func NewRowWriter(w io.Writer, format string, columns []string) (RowWriter, error) {
+	switch format {
+	case "csv":
+		return newCSVWriter(w, columns)
+	case "ndjson":
+		return ndjsonWriter{w, columns}, nil
+	}
+	return nil, fmt.Errorf("unrecognized export format: %s", format)
+}
+
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.Format(time.RFC3339)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return fmt.Sprint(v)
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer, columns []string) (csvWriter, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return csvWriter{}, err
+	}
+	return csvWriter{cw}, nil
+}
+
+func (cw csvWriter) WriteRow(values []interface{}) error {
+	row := make([]string, len(values))
+	for i, v := range values {
+		row[i] = formatValue(v)
+	}
+	return cw.w.Write(row)
+}
+
+func (cw csvWriter) Flush() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+type ndjsonWriter struct {
+	w       io.Writer
+	columns []string
+}
+
+func (nw ndjsonWriter) WriteRow(values []interface{}) error {
+	if len(values) != len(nw.columns) {
+		return fmt.Errorf("row has %d values, want %d", len(values), len(nw.columns))
+	}
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, c := range nw.columns {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, err := json.Marshal(c)
+		if err != nil {
+			return err
+		}
+		v := values[i]
+		if t, ok := v.(time.Time); ok {
+			// Match the CSV output so both formats share one schema.
+			v = formatValue(t)
+		}
+		value, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteString("}\n")
+	_, err := nw.w.Write(b.Bytes())
+	return err
+}
+
+func (nw ndjsonWriter) Flush() error {
+	return nil
+}
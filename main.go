@@ -1,62 +1,94 @@
 package main
 
 import (
-	"encoding/json"
 	"flag"
-	"io/ioutil"
+	"fmt"
 	"log"
-	"time"
+	"os"
+	"strings"
 
-	"github.com/mtlynch/gofn-prosper/interval"
-	"github.com/mtlynch/gofn-prosper/prosper"
-	"github.com/mtlynch/gofn-prosper/prosper/auth"
-
-	"github.com/mtlynch/prosperbot/account"
-	"github.com/mtlynch/prosperbot/buyer"
-	"github.com/mtlynch/prosperbot/notes"
+	"github.com/mtlynch/prosperbot/config"
+	"github.com/mtlynch/prosperbot/redis"
 )
 
-func parseCredentials(path string) (creds auth.ClientCredentials, err error) {
-	file, err := ioutil.ReadFile(path)
-	if err != nil {
-		return auth.ClientCredentials{}, err
+type command struct {
+	name        string
+	description string
+	run         func(args []string) error
+}
+
+var commands = []command{
+	{"run", "run the bot (the default when no command is given)", runCommand},
+	{"status", "show the latest account information and pending orders", statusCommand},
+	{"notes", "list notes, optionally filtered by status", notesCommand},
+	{"orders", "list orders and their outcomes", ordersCommand},
+	{"listing", "show a saved listing and what the bot decided about it", listingCommand},
+	{"lineage", "trace a listing, order or note to everything linked to it", lineageCommand},
+	{"reconcile", "check completed orders against owned notes", reconcileCommand},
+	{"snapshot", "show the portfolio as it was at a past time", snapshotCommand},
+	{"tax-report", "write the income report for a tax year", taxReportCommand},
+	{"export", "dump saved history as CSV or newline-delimited JSON", exportCommand},
+	{"backup", "save all bot data to an archive file", backupCommand},
+	{"restore", "load an archive file into an empty store", restoreCommand},
+	{"backtest", "replay saved listings through a candidate buying strategy", backtestCommand},
+	{"train", "fit a default model to the saved notes and their listings", trainCommand},
+	{"replay", "rerun the bot's buying decisions against a recorded API log", replayCommand},
+	{"simulate", "run a day of synthetic listings through the bot on a virtual clock", simulateCommand},
+	{"config", "check the config file (config validate)", configCommand},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [command] [flags]\n\nCommands:\n", os.Args[0])
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", c.name, c.description)
 	}
-	err = json.Unmarshal(file, &creds)
+	fmt.Fprintf(os.Stderr, "\nRun '%s <command> -h' for a command's flags.\n", os.Args[0])
+}
+
+// newFlagSet creates the flag set for a command.
+func newFlagSet(name string) *flag.FlagSet {
+	return flag.NewFlagSet(name, flag.ExitOnError)
+}
+
+// configFlag adds the -config flag to fs, for commands that load the config
+// file.
+func configFlag(fs *flag.FlagSet) *string {
+	return fs.String("config", "", "ProsperBot config file")
+}
+
+func loadConfig(path string) (config.Config, error) {
+	c, err := config.Load(path)
 	if err != nil {
-		return auth.ClientCredentials{}, err
+		return config.Config{}, err
+	}
+	if err = c.Validate(); err != nil {
+		return config.Config{}, err
 	}
-	return creds, nil
+	return c, nil
+}
+
+// openStore connects to the Redis server that holds the bot's state.
+func openStore() (redis.RedisInspector, error) {
+	return redis.New()
 }
 
 func main() {
-	log.Println("Starting up!")
-	credsPath := flag.String("creds", "prosper-creds.json", "Prosper client credentials file")
-	isBuyingEnabled := flag.Bool("enable-buying", false, "is listing buying enabled?")
-	flag.Parse()
-	creds, err := parseCredentials(*credsPath)
-	if err != nil {
-		log.Fatalf("failed to parse credentials: %v", err)
-	}
-	c := prosper.NewClient(creds)
-	f := prosper.SearchFilter{
-		EstimatedReturn: interval.Float64Range{Min: interval.CreateFloat64(0.0849)},
-		ListingStatus:   []prosper.ListingStatus{prosper.ListingActive},
-		IncomeRange:     []prosper.IncomeRange{prosper.Between25kAnd50k, prosper.Between50kAnd75k, prosper.Between75kAnd100k, prosper.Over100k},
-		// Re-enable when Prosper fixes their bug here.
-		/*PriorProsperLoansLatePaymentsOneMonthPlus: interval.Int32Range{
-			Max: interval.CreateInt32(0),
-		},
-		PriorProsperLoansBalanceOutstanding: interval.Float64Range{
-			Max: interval.CreateFloat64(0.0),
-		},*/
-		InquiriesLast6Months: interval.Int32Range{Max: interval.CreateInt32(3)},
-		DtiWprosperLoan:      interval.Float64Range{Max: interval.CreateFloat64(0.4)},
-		Rating:               []prosper.Rating{prosper.RatingAA, prosper.RatingA, prosper.RatingB, prosper.RatingC, prosper.RatingD, prosper.RatingE},
+	args := os.Args[1:]
+	name := "run"
+	// For compatibility with older deployments, flags without a command run the
+	// bot.
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
 	}
-	buyer.Poll(1*time.Second, f, *isBuyingEnabled, c)
-	account.Poll(1*time.Minute, c)
-	notes.Poll(10*time.Minute, c)
-	for {
-		time.Sleep(10 * time.Minute)
+	for _, c := range commands {
+		if c.name != name {
+			continue
+		}
+		if err := c.run(args); err != nil {
+			log.Fatalf("%s failed: %v", name, err)
+		}
+		return
 	}
+	usage()
+	os.Exit(2)
 }
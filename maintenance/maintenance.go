@@ -0,0 +1,334 @@
+package maintenance
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/clock"
+	"github.com/mtlynch/prosperbot/config"
+	"github.com/mtlynch/prosperbot/redis"
+)
+
+const defaultInterval = 24 * time.Hour
+
+type job struct {
+	redis    redis.RedisMaintainer
+	policy   config.Retention
+	interval time.Duration
+	clock    clock.Timer
+}
+
+// Result counts what a maintenance run changed.
+type Result struct {
+	ListingsExpired         int
+	ListingHistoriesExpired int
+	AccountRecordsRemoved   int
+	NoteEntriesCompacted    int
+}
+
+// NewJob creates a job that applies the retention policies in c on a fixed
+// interval.
+func NewJob(c config.Retention, clk clock.Timer) (job, error) {
+	r, err := redis.New()
+	if err != nil {
+		return job{}, err
+	}
+	interval := c.Interval.Duration
+	if interval == 0 {
+		interval = defaultInterval
+	}
+	return job{
+		redis:    r,
+		policy:   c,
+		interval: interval,
+		clock:    clk,
+	}, nil
+}
+
+func (j job) Run() {
+	log.Printf("starting history maintenance every %v", j.interval)
+	for {
+		result, err := j.RunOnce()
+		if err != nil {
+			log.Printf("history maintenance failed: %v", err)
+		} else {
+			log.Printf("history maintenance finished: %+v", result)
+		}
+		j.clock.Sleep(j.interval)
+	}
+}
+
+// RunOnce applies every enabled retention policy.
+func (j job) RunOnce() (Result, error) {
+	now := j.clock.Now()
+	var result Result
+	var err error
+	if j.policy.ListingDays > 0 {
+		result.ListingsExpired, err = expireListings(j.redis, now, j.policy.ListingDays)
+		if err != nil {
+			return result, fmt.Errorf("failed to expire listings: %v", err)
+		}
+		result.ListingHistoriesExpired, err = expireListingHistories(j.redis, now, j.policy.ListingDays)
+		if err != nil {
+			return result, fmt.Errorf("failed to expire listing history: %v", err)
+		}
+	}
+	if j.policy.AccountDailyAfterDays > 0 {
+		cutoff := now.AddDate(0, 0, -j.policy.AccountDailyAfterDays)
+		result.AccountRecordsRemoved, err = downsampleAccountHistory(j.redis, cutoff)
+		if err != nil {
+			return result, fmt.Errorf("failed to downsample account history: %v", err)
+		}
+	}
+	if j.policy.NoteDiffAfterDays > 0 {
+		cutoff := now.AddDate(0, 0, -j.policy.NoteDiffAfterDays)
+		result.NoteEntriesCompacted, err = compactNoteHistories(j.redis, cutoff)
+		if err != nil {
+			return result, fmt.Errorf("failed to compact note history: %v", err)
+		}
+	}
+	return result, nil
+}
+
+// expireListings sets a TTL on every listing snapshot that doesn't have one,
+// so that it expires the given number of days after the listing started. The
+// listing number is first added to the set of seen listings so that the bot
+// still recognizes the listing once its snapshot is gone.
+func expireListings(r redis.RedisMaintainer, now time.Time, days int) (int, error) {
+	expired := 0
+	err := redis.ForEachKey(r, redis.KeyPrefixListing+"*", func(k string) error {
+		ttl, err := r.TTL(k)
+		if err != nil {
+			return err
+		}
+		// A TTL of -1 means the key has no expiry. Keys that already expire or
+		// no longer exist are left alone, which also skips keys the scan
+		// returns twice.
+		if ttl != -1 {
+			return nil
+		}
+		serialized, err := r.Get(k)
+		if err != nil {
+			return err
+		}
+		var l prosper.Listing
+		if err = json.Unmarshal([]byte(serialized), &l); err != nil {
+			return fmt.Errorf("failed to parse %s: %v", k, err)
+		}
+		if _, err = r.SAdd(redis.KeySeenListings, int64(l.ListingNumber)); err != nil {
+			return err
+		}
+		if _, err = r.Expire(k, listingTTL(l, now, days)); err != nil {
+			return err
+		}
+		expired++
+		return nil
+	})
+	return expired, err
+}
+
+// expireListingHistories sets a TTL on every listing history that doesn't
+// have one, so that it expires along with the listing's snapshot. Histories
+// are checked apart from snapshots because a history can be created after
+// its snapshot already has a TTL.
+func expireListingHistories(r redis.RedisMaintainer, now time.Time, days int) (int, error) {
+	expired := 0
+	err := redis.ForEachKey(r, redis.KeyPrefixListingHistory+"*", func(k string) error {
+		ttl, err := r.TTL(k)
+		if err != nil {
+			return err
+		}
+		if ttl != -1 {
+			return nil
+		}
+		oldest, err := r.LRange(k, -1, -1)
+		if err != nil {
+			return err
+		}
+		if len(oldest) == 0 {
+			return nil
+		}
+		record, err := redis.DecodeListingRecord(oldest[len(oldest)-1])
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %v", k, err)
+		}
+		if _, err = r.Expire(k, listingTTL(record.Listing, now, days)); err != nil {
+			return err
+		}
+		expired++
+		return nil
+	})
+	return expired, err
+}
+
+// listingTTL returns the number of seconds until the given number of days
+// after a listing started, or one second if that time has passed.
+func listingTTL(l prosper.Listing, now time.Time, days int) uint64 {
+	remaining := l.ListingStartDate.AddDate(0, 0, days).Sub(now)
+	if remaining > time.Second {
+		return uint64(remaining / time.Second)
+	}
+	return 1
+}
+
+// replaceTail replaces the last n entries of a list with tail, in one
+// transaction so that readers never see the list with its tail missing.
+// Entries added to the head of the list in the meantime are unaffected.
+func replaceTail(r redis.RedisMaintainer, key string, n int, tail []string) error {
+	if _, err := r.Multi(); err != nil {
+		return err
+	}
+	// Commands in a transaction are only queued, so their replies carry no
+	// results. Any failure is reported by Exec.
+	r.LTrim(key, 0, -int64(n)-1)
+	if len(tail) > 0 {
+		values := make([]interface{}, len(tail))
+		for i, v := range tail {
+			values[i] = v
+		}
+		r.RPush(key, values...)
+	}
+	replies, err := r.Exec()
+	if err != nil {
+		return err
+	}
+	for _, reply := range replies {
+		if err, ok := reply.(error); ok {
+			return err
+		}
+	}
+	return nil
+}
+
+// downsampleAccountHistory keeps only the last account record of each day for
+// records saved before cutoff, and returns the number of records removed.
+func downsampleAccountHistory(r redis.RedisMaintainer, cutoff time.Time) (int, error) {
+	serialized, err := r.LRange(redis.KeyAccountInformation, 0, -1)
+	if err != nil {
+		return 0, err
+	}
+	// History is stored newest first, so old records are at the tail.
+	first := len(serialized)
+	for i, s := range serialized {
+		record, err := redis.DecodeAccountRecord(s)
+		if err != nil {
+			return 0, err
+		}
+		if record.Timestamp.Before(cutoff) {
+			first = i
+			break
+		}
+	}
+	old := serialized[first:]
+	kept := []string{}
+	lastDay := ""
+	for _, s := range old {
+		record, err := redis.DecodeAccountRecord(s)
+		if err != nil {
+			return 0, err
+		}
+		// The first record seen for each day is the day's last.
+		day := record.Timestamp.In(cutoff.Location()).Format("2006-01-02")
+		if day != lastDay {
+			kept = append(kept, s)
+			lastDay = day
+		}
+	}
+	if len(kept) == len(old) {
+		return 0, nil
+	}
+	if err = replaceTail(r, redis.KeyAccountInformation, len(old), kept); err != nil {
+		return 0, err
+	}
+	return len(old) - len(kept), nil
+}
+
+// compactNoteHistories stores every note history entry saved before cutoff as
+// a diff against the entry before it. The notes logger already saves changes
+// as diffs, so this mostly compacts history saved by older versions of the
+// bot. The oldest entry of each note stays a full record, as does one entry in
+// every redis.NoteCheckpointInterval+1, so that no more diffs are saved in a
+// row than the notes logger would save. The newest entry is left alone. It
+// returns the number of entries compacted.
+func compactNoteHistories(r redis.RedisMaintainer, cutoff time.Time) (int, error) {
+	compacted := 0
+	// Compacting a history twice changes nothing, so keys the scan returns
+	// twice are harmless.
+	err := redis.ForEachKey(r, redis.KeyPrefixNote+"*", func(k string) error {
+		n, err := compactNoteHistory(r, k, cutoff)
+		if err != nil {
+			return fmt.Errorf("failed to compact %s: %v", k, err)
+		}
+		compacted += n
+		return nil
+	})
+	return compacted, err
+}
+
+func compactNoteHistory(r redis.RedisMaintainer, key string, cutoff time.Time) (int, error) {
+	serialized, err := r.LRange(key, 0, -1)
+	if err != nil {
+		return 0, err
+	}
+	records, err := redis.DecodeNoteHistory(serialized)
+	if err != nil {
+		return 0, err
+	}
+	// Count old entries, oldest first, never including the newest entry.
+	old := 0
+	for old < len(records)-1 && records[old].Timestamp.Before(cutoff) {
+		old++
+	}
+	// Count the diffs saved after the old entries, up to the next full record,
+	// because they follow the newest full record that compaction keeps.
+	run := 0
+	for i := old; i < len(records) && redis.IsNoteDiff(serialized[len(serialized)-1-i]); i++ {
+		run++
+	}
+	compacted := 0
+	// Build the new tail newest first, to match the stored order. Entries are
+	// visited newest first too, so that run counts the diffs that follow each
+	// one.
+	tail := make([]string, old)
+	for i := old - 1; i >= 0; i-- {
+		stored := serialized[len(serialized)-1-i]
+		tail[old-1-i] = stored
+		if redis.IsNoteDiff(stored) {
+			run++
+			continue
+		}
+		if i == 0 || run >= redis.NoteCheckpointInterval {
+			run = 0
+			continue
+		}
+		changes, err := redis.DiffNotes(records[i-1].Note, records[i].Note)
+		if err != nil {
+			return 0, err
+		}
+		if len(changes) == 0 {
+			run = 0
+			continue
+		}
+		diff, err := redis.EncodeNoteDiff(redis.NoteDiff{
+			Changes:   changes,
+			Timestamp: records[i].Timestamp,
+		})
+		if err != nil {
+			return 0, err
+		}
+		tail[old-1-i] = diff
+		run++
+		compacted++
+	}
+	if compacted == 0 {
+		return 0, nil
+	}
+	if err = replaceTail(r, key, old, tail); err != nil {
+		return 0, err
+	}
+	return compacted, nil
+}
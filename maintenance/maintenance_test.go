@@ -0,0 +1,364 @@
+package maintenance
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/clock"
+	"github.com/mtlynch/prosperbot/config"
+	"github.com/mtlynch/prosperbot/redis"
+)
+
+type mockRedis struct {
+	values map[string]string
+	lists  map[string][]string
+	ttls   map[string]int64
+	sets   map[string][]string
+	// entriesRead counts the list entries returned by LRange.
+	entriesRead int
+}
+
+func newMockRedis() *mockRedis {
+	return &mockRedis{
+		values: map[string]string{},
+		lists:  map[string][]string{},
+		ttls:   map[string]int64{},
+		sets:   map[string][]string{},
+	}
+}
+
+func (r *mockRedis) Keys(pattern string) ([]string, error) {
+	prefix := strings.TrimSuffix(pattern, "*")
+	keys := []string{}
+	for k := range r.values {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	for k := range r.lists {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+
+// Scan returns every key matching the pattern in a single batch.
+func (r *mockRedis) Scan(cursor int64, arguments ...interface{}) (int64, []string, error) {
+	keys, err := r.Keys(arguments[1].(string))
+	return 0, keys, err
+}
+
+func (r *mockRedis) Get(key string) (string, error) {
+	return r.values[key], nil
+}
+
+func (r *mockRedis) LRange(key string, start int64, stop int64) ([]string, error) {
+	l := r.lists[key]
+	n := int64(len(l))
+	if start < 0 {
+		start += n
+	}
+	if stop < 0 {
+		stop += n
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= n {
+		stop = n - 1
+	}
+	if start > stop {
+		return []string{}, nil
+	}
+	r.entriesRead += int(stop - start + 1)
+	return l[start : stop+1], nil
+}
+
+func (r *mockRedis) LTrim(key string, start int64, stop int64) (string, error) {
+	l := r.lists[key]
+	end := int64(len(l)) + stop + 1
+	if end < 0 {
+		end = 0
+	}
+	r.lists[key] = l[:end]
+	return "OK", nil
+}
+
+func (r *mockRedis) RPush(key string, values ...interface{}) (int64, error) {
+	for _, v := range values {
+		r.lists[key] = append(r.lists[key], v.(string))
+	}
+	return int64(len(r.lists[key])), nil
+}
+
+func (r *mockRedis) SAdd(key string, members ...interface{}) (int64, error) {
+	for _, m := range members {
+		r.sets[key] = append(r.sets[key], fmt.Sprint(m))
+	}
+	return int64(len(members)), nil
+}
+
+func (r *mockRedis) TTL(key string) (int64, error) {
+	if ttl, ok := r.ttls[key]; ok {
+		return ttl, nil
+	}
+	return -1, nil
+}
+
+func (r *mockRedis) Expire(key string, seconds uint64) (bool, error) {
+	r.ttls[key] = int64(seconds)
+	return true, nil
+}
+
+// Multi and Exec don't queue commands; the mock runs each command as it
+// arrives.
+func (r *mockRedis) Multi() (string, error) {
+	return "OK", nil
+}
+
+func (r *mockRedis) Exec() ([]interface{}, error) {
+	return []interface{}{}, nil
+}
+
+var now = time.Date(2016, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func mustEncode(t *testing.T, encode func() (string, error)) string {
+	s, err := encode()
+	if err != nil {
+		t.Fatalf("failed to encode record: %v", err)
+	}
+	return s
+}
+
+func accountRecord(t *testing.T, ts time.Time, cash float64) string {
+	return mustEncode(t, func() (string, error) {
+		return redis.EncodeAccountRecord(redis.AccountRecord{
+			Value:     prosper.AccountInformation{AvailableCashBalance: cash},
+			Timestamp: ts,
+		})
+	})
+}
+
+func noteRecord(t *testing.T, ts time.Time, daysPastDue int) string {
+	return mustEncode(t, func() (string, error) {
+		return redis.EncodeNoteRecord(redis.NoteRecord{
+			Note:      prosper.Note{LoanNoteID: "1619-2", DaysPastDue: daysPastDue},
+			Timestamp: ts,
+		})
+	})
+}
+
+func TestExpireListings(t *testing.T) {
+	r := newMockRedis()
+	r.values["listing:1"] = `{"ListingNumber":1,"ListingStartDate":"2016-05-01T12:00:00Z"}`
+	r.values["listing:2"] = `{"ListingNumber":2,"ListingStartDate":"2016-01-01T12:00:00Z"}`
+	r.values["listing:3"] = `{"ListingNumber":3,"ListingStartDate":"2016-05-01T12:00:00Z"}`
+	r.ttls["listing:3"] = 100
+
+	got, err := expireListings(r, now, 60)
+	if err != nil {
+		t.Fatalf("failed to expire listings: %v", err)
+	}
+	if got != 2 {
+		t.Errorf("unexpected number of listings expired. got: %d, want: %d", got, 2)
+	}
+	wantTTLs := map[string]int64{
+		"listing:1": int64(29 * 24 * time.Hour / time.Second),
+		"listing:2": 1,
+		"listing:3": 100,
+	}
+	if !reflect.DeepEqual(r.ttls, wantTTLs) {
+		t.Errorf("unexpected TTLs. got: %v, want: %v", r.ttls, wantTTLs)
+	}
+	// Scan() order is random, so compare without relying on it.
+	if len(r.sets[redis.KeySeenListings]) != 2 {
+		t.Errorf("expected expiring listings to be marked as seen. got: %v", r.sets[redis.KeySeenListings])
+	}
+}
+
+func listingRecord(t *testing.T, ts time.Time, percentFunded float64) string {
+	return mustEncode(t, func() (string, error) {
+		return redis.EncodeListingRecord(redis.ListingRecord{
+			Listing: prosper.Listing{
+				ListingNumber:    1,
+				ListingStartDate: time.Date(2016, 5, 1, 12, 0, 0, 0, time.UTC),
+				PercentFunded:    percentFunded,
+			},
+			Timestamp: ts,
+		})
+	})
+}
+
+func TestExpireListingHistories(t *testing.T) {
+	r := newMockRedis()
+	// The snapshot already expires, but the history was created afterward.
+	r.values["listing:1"] = `{"ListingNumber":1,"ListingStartDate":"2016-05-01T12:00:00Z"}`
+	r.ttls["listing:1"] = 100
+	r.lists["listingHistory:1"] = []string{
+		listingRecord(t, time.Date(2016, 5, 1, 12, 5, 0, 0, time.UTC), 0.5),
+		listingRecord(t, time.Date(2016, 5, 1, 12, 0, 0, 0, time.UTC), 0.1),
+	}
+	r.lists["listingHistory:2"] = []string{listingRecord(t, time.Date(2016, 5, 1, 12, 0, 0, 0, time.UTC), 0.1)}
+	r.ttls["listingHistory:2"] = 100
+
+	got, err := expireListingHistories(r, now, 60)
+	if err != nil {
+		t.Fatalf("failed to expire listing histories: %v", err)
+	}
+	if got != 1 {
+		t.Errorf("unexpected number of histories expired. got: %d, want: %d", got, 1)
+	}
+	wantTTLs := map[string]int64{
+		"listing:1":        100,
+		"listingHistory:1": int64(29 * 24 * time.Hour / time.Second),
+		"listingHistory:2": 100,
+	}
+	if !reflect.DeepEqual(r.ttls, wantTTLs) {
+		t.Errorf("unexpected TTLs. got: %v, want: %v", r.ttls, wantTTLs)
+	}
+}
+
+func TestDownsampleAccountHistory(t *testing.T) {
+	day := func(d, h int) time.Time { return time.Date(2016, 5, d, h, 0, 0, 0, time.UTC) }
+	recent := accountRecord(t, day(31, 9), 500)
+	may2Late := accountRecord(t, day(2, 20), 400)
+	may2Early := accountRecord(t, day(2, 8), 300)
+	may1Late := accountRecord(t, day(1, 18), 200)
+	may1Early := accountRecord(t, day(1, 6), 100)
+
+	r := newMockRedis()
+	r.lists[redis.KeyAccountInformation] = []string{recent, may2Late, may2Early, may1Late, may1Early}
+	removed, err := downsampleAccountHistory(r, now.AddDate(0, 0, -7))
+	if err != nil {
+		t.Fatalf("failed to downsample: %v", err)
+	}
+	if removed != 2 {
+		t.Errorf("unexpected number of records removed. got: %d, want: %d", removed, 2)
+	}
+	want := []string{recent, may2Late, may1Late}
+	if !reflect.DeepEqual(r.lists[redis.KeyAccountInformation], want) {
+		t.Errorf("unexpected account history. got: %v, want: %v", r.lists[redis.KeyAccountInformation], want)
+	}
+
+	removed, err = downsampleAccountHistory(r, now.AddDate(0, 0, -7))
+	if err != nil || removed != 0 {
+		t.Errorf("downsampling twice should change nothing. got: %d removed, err: %v", removed, err)
+	}
+}
+
+func TestCompactNoteHistory(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2016, 5, d, 12, 0, 0, 0, time.UTC) }
+	key := redis.KeyPrefixNote + "1619-2"
+	r := newMockRedis()
+	r.lists[key] = []string{
+		noteRecord(t, day(31), 0),
+		noteRecord(t, day(3), 0),
+		noteRecord(t, day(2), 16),
+		noteRecord(t, day(1), 0),
+	}
+	want, err := redis.NoteHistory(r, "1619-2")
+	if err != nil {
+		t.Fatalf("failed to read history: %v", err)
+	}
+
+	compacted, err := compactNoteHistories(r, now.AddDate(0, 0, -7))
+	if err != nil {
+		t.Fatalf("failed to compact: %v", err)
+	}
+	if compacted != 2 {
+		t.Errorf("unexpected number of entries compacted. got: %d, want: %d", compacted, 2)
+	}
+	stored := r.lists[key]
+	isDiff := []bool{}
+	for _, s := range stored {
+		isDiff = append(isDiff, redis.IsNoteDiff(s))
+	}
+	if !reflect.DeepEqual(isDiff, []bool{false, true, true, false}) {
+		t.Errorf("unexpected compacted entries. got: %v", stored)
+	}
+	got, err := redis.NoteHistory(r, "1619-2")
+	if err != nil {
+		t.Fatalf("failed to read compacted history: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("compacted history should read the same. got: %+v, want: %+v", got, want)
+	}
+
+	compacted, err = compactNoteHistories(r, now.AddDate(0, 0, -7))
+	if err != nil || compacted != 0 {
+		t.Errorf("compacting twice should change nothing. got: %d compacted, err: %v", compacted, err)
+	}
+}
+
+func TestCompactLongNoteHistory(t *testing.T) {
+	key := redis.KeyPrefixNote + "1619-2"
+	r := newMockRedis()
+	// Forty full records saved a day apart, newest first, followed by a diff
+	// saved by the notes logger.
+	start := time.Date(2016, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 40; i++ {
+		r.lists[key] = append([]string{noteRecord(t, start.AddDate(0, 0, i), i)}, r.lists[key]...)
+	}
+	changes, err := redis.DiffNotes(prosper.Note{LoanNoteID: "1619-2", DaysPastDue: 39}, prosper.Note{LoanNoteID: "1619-2", DaysPastDue: 40})
+	if err != nil {
+		t.Fatalf("failed to diff notes: %v", err)
+	}
+	latest := mustEncode(t, func() (string, error) {
+		return redis.EncodeNoteDiff(redis.NoteDiff{Changes: changes, Timestamp: now})
+	})
+	r.lists[key] = append([]string{latest}, r.lists[key]...)
+	want, err := redis.NoteHistory(r, "1619-2")
+	if err != nil {
+		t.Fatalf("failed to read history: %v", err)
+	}
+
+	if _, err = compactNoteHistories(r, now); err != nil {
+		t.Fatalf("failed to compact: %v", err)
+	}
+	got, err := redis.NoteHistory(r, "1619-2")
+	if err != nil {
+		t.Fatalf("failed to read compacted history: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("compacted history should read the same. got: %+v, want: %+v", got, want)
+	}
+	diffs := 0
+	for _, s := range r.lists[key] {
+		if !redis.IsNoteDiff(s) {
+			diffs = 0
+		} else if diffs++; diffs > redis.NoteCheckpointInterval {
+			t.Fatalf("compacted history has more than %d diffs in a row", redis.NoteCheckpointInterval)
+		}
+	}
+
+	r.entriesRead = 0
+	record, _, err := redis.LatestNoteRecord(r, "1619-2")
+	if err != nil {
+		t.Fatalf("failed to read latest note: %v", err)
+	}
+	if record.Note.DaysPastDue != 40 {
+		t.Errorf("unexpected latest note. got: %+v", record.Note)
+	}
+	if r.entriesRead != redis.NoteCheckpointInterval+1 {
+		t.Errorf("unexpected number of entries read for the latest note. got: %d, want: %d", r.entriesRead, redis.NoteCheckpointInterval+1)
+	}
+}
+
+func TestRunOnceDisabledPolicies(t *testing.T) {
+	r := newMockRedis()
+	r.values["listing:1"] = `{"ListingNumber":1,"ListingStartDate":"2016-01-01T12:00:00Z"}`
+	j := job{redis: r, policy: config.Retention{}, clock: clock.NewVirtual(now)}
+	result, err := j.RunOnce()
+	if err != nil {
+		t.Fatalf("maintenance failed: %v", err)
+	}
+	if result != (Result{}) || len(r.ttls) != 0 {
+		t.Errorf("disabled policies should change nothing. got: %+v, ttls: %v", result, r.ttls)
+	}
+}
@@ -5,12 +5,15 @@ import (
 	"time"
 
 	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/clock"
 )
 
 type notePoller struct {
 	nf           prosper.NoteFetcher
 	notes        chan<- prosper.Note
 	pollInterval time.Duration
+	clock        clock.Timer
 }
 
 const MaxAttempts = 3
@@ -52,7 +55,7 @@ func (np notePoller) Run() {
 	go func() {
 		for {
 			go getListings()
-			time.Sleep(np.pollInterval)
+			np.clock.Sleep(np.pollInterval)
 		}
 	}()
 }
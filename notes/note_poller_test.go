@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/clock"
 )
 
 type mockNoteFetcher struct {
@@ -92,6 +94,7 @@ func TestNotePoller(t *testing.T) {
 			nf:           &noteFetcher,
 			notes:        notes,
 			pollInterval: 10 * time.Second,
+			clock:        clock.NewVirtual(time.Date(2016, 3, 5, 11, 40, 15, 0, time.UTC)),
 		}
 		go notePoller.Run()
 		var gotNotes []prosper.Note
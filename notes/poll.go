@@ -5,20 +5,26 @@ import (
 	"time"
 
 	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/clock"
 )
 
-func Poll(pollInterval time.Duration, nf prosper.NoteFetcher) error {
+// Poll periodically fetches the account's notes and saves changes to Redis.
+// If transitions is non-nil, changes to previously saved notes are sent on it.
+func Poll(pollInterval time.Duration, nf prosper.NoteFetcher, transitions chan<- Transition, clk clock.Timer) error {
 	log.Printf("starting note polling")
 	notes := make(chan prosper.Note)
 	notePoller := notePoller{
 		nf:           nf,
 		notes:        notes,
 		pollInterval: pollInterval,
+		clock:        clk,
 	}
-	redisLogger, err := newRedisLogger(notes)
+	redisLogger, err := newRedisLogger(notes, transitions)
 	if err != nil {
 		return err
 	}
+	redisLogger.clock = clk
 
 	go redisLogger.Run()
 	go notePoller.Run()
@@ -1,10 +1,9 @@
 package notes
 
 import (
-	"encoding/json"
-	"errors"
 	"log"
 	"reflect"
+	"time"
 
 	"github.com/mtlynch/gofn-prosper/prosper"
 
@@ -12,26 +11,41 @@ import (
 	"github.com/mtlynch/prosperbot/redis"
 )
 
+// Transition is a change to a note that already had saved history.
+type Transition struct {
+	Previous  prosper.Note
+	Current   prosper.Note
+	Timestamp time.Time
+}
+
 type redisLogger struct {
 	noteUpdates <-chan prosper.Note
+	transitions chan<- Transition
 	done        chan<- bool
-	redis       redis.RedisListPrepender
+	redis       redis.RedisListIndexer
 	clock       clock.Clock
 }
 
-func newRedisLogger(noteUpdates <-chan prosper.Note) (redisLogger, error) {
+func newRedisLogger(noteUpdates <-chan prosper.Note, transitions chan<- Transition) (redisLogger, error) {
 	r, err := redis.New()
 	if err != nil {
 		return redisLogger{}, err
 	}
 	return redisLogger{
 		noteUpdates: noteUpdates,
+		transitions: transitions,
 		redis:       r,
 		clock:       clock.DefaultClock{},
 	}, nil
 }
 
-var errNotFound = errors.New("note not found")
+// Values of NoteStatusDescription that the bot acts on.
+const (
+	StatusCurrent   = "CURRENT"
+	StatusChargeOff = "CHARGEOFF"
+	StatusDefaulted = "DEFAULTED"
+	StatusCompleted = "COMPLETED"
+)
 
 func noteEqual(a, b prosper.Note) bool {
 	if a.AgeInMonths != b.AgeInMonths {
@@ -133,27 +147,59 @@ func NewRedisLogger(updates <-chan prosper.Note) (redisLogger, error) {
 }
 
 func (r redisLogger) Run() {
+	// Notes are indexed the first time they're seen in each run, so that
+	// notes saved before indexing existed are indexed too.
+	indexed := map[string]bool{}
 	for {
 		n, more := <-r.noteUpdates
 		if !more {
 			r.done <- true
 			return
 		}
-		nSaved, err := r.getLatestNoteState(n)
-		if err == errNotFound {
+		nSaved, diffs, err := r.getLatestNoteState(n)
+		isNew := false
+		if err == redis.ErrNoteNotFound {
 			// New note, proceed.
+			isNew = true
 		} else if err != nil {
 			log.Printf("failed to get note history for %v, err: %v", n.LoanNoteID, err)
 			continue
 		} else {
 			if noteEqual(n, nSaved) {
 				// No change, don't save.
+				if !indexed[n.LoanNoteID] {
+					if err = r.index(n); err != nil {
+						log.Printf("failed to index note %v, err: %v", n.LoanNoteID, err)
+					} else {
+						indexed[n.LoanNoteID] = true
+					}
+				}
 				continue
 			}
 		}
 		log.Printf("update to note: %v", n.LoanNoteID)
-		if err = r.saveNoteState(n); err != nil {
+		if isNew {
+			err = r.saveNoteState(n, []string{})
+		} else {
+			err = r.saveNoteChanges(nSaved, n, diffs)
+		}
+		if err != nil {
 			log.Printf("failed to save note %+v, err: %v", n, err)
+			continue
+		}
+		indexed[n.LoanNoteID] = true
+		if isNew {
+			if err = r.recordBorrower(n); err != nil {
+				log.Printf("failed to record note %v against its borrower: %v", n.LoanNoteID, err)
+			}
+		}
+		if !isNew && r.transitions != nil {
+			t := Transition{
+				Previous:  nSaved,
+				Current:   n,
+				Timestamp: r.clock.Now(),
+			}
+			go func() { r.transitions <- t }()
 		}
 	}
 }
@@ -162,34 +208,115 @@ func noteToKey(n prosper.Note) string {
 	return redis.KeyPrefixNote + n.LoanNoteID
 }
 
-func (r redisLogger) getLatestNoteState(n prosper.Note) (prosper.Note, error) {
-	noteSerialized, err := r.redis.LRange(noteToKey(n), 0, 0)
-	if err != nil {
-		return prosper.Note{}, err
-	}
-	if len(noteSerialized) < 1 {
-		return prosper.Note{}, errNotFound
-	}
-	var record redis.NoteRecord
-	err = json.Unmarshal([]byte(noteSerialized[0]), &record)
+// getLatestNoteState rebuilds the current saved state of a note from its last
+// full record and the diffs saved since, and returns how many diffs that was.
+func (r redisLogger) getLatestNoteState(n prosper.Note) (prosper.Note, int, error) {
+	latest, diffs, err := redis.LatestNoteRecord(r.redis, n.LoanNoteID)
 	if err != nil {
-		return prosper.Note{}, err
+		return prosper.Note{}, 0, err
 	}
-	return record.Note, nil
+	return latest.Note, diffs, nil
 }
 
-func (r redisLogger) saveNoteState(n prosper.Note) error {
+// saveNoteState saves a full snapshot of a note.
+func (r redisLogger) saveNoteState(n prosper.Note, staleIndexes []string) error {
 	record := redis.NoteRecord{
 		Note:      n,
 		Timestamp: r.clock.Now(),
 	}
-	serialized, err := json.Marshal(record)
+	serialized, err := redis.EncodeNoteRecord(record)
 	if err != nil {
 		return err
 	}
-	_, err = r.redis.LPush(noteToKey(n), string(serialized))
+	return r.save(n, serialized, staleIndexes)
+}
+
+// saveNoteChanges saves only the fields of a note that changed since its last
+// saved state, unless enough diffs have been saved since the last full record
+// that it's time for another.
+func (r redisLogger) saveNoteChanges(previous, current prosper.Note, diffs int) error {
+	staleIndexes := redis.StaleNoteIndexes(previous, current)
+	if diffs >= redis.NoteCheckpointInterval {
+		return r.saveNoteState(current, staleIndexes)
+	}
+	changes, err := redis.DiffNotes(previous, current)
 	if err != nil {
 		return err
 	}
+	if len(changes) == 0 {
+		// The notes differ in a way JSON can't show, such as time zone, so a
+		// diff would be empty.
+		return r.saveNoteState(current, staleIndexes)
+	}
+	serialized, err := redis.EncodeNoteDiff(redis.NoteDiff{
+		Changes:   changes,
+		Timestamp: r.clock.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	return r.save(current, serialized, staleIndexes)
+}
+
+// save prepends a history entry for the note, moves the note from its stale
+// indexes to its current ones and links it to its listing's lineage, all in
+// one transaction.
+func (r redisLogger) save(n prosper.Note, entry string, staleIndexes []string) error {
+	if _, err := r.redis.Multi(); err != nil {
+		return err
+	}
+	// Commands in a transaction are only queued, so their replies carry no
+	// results. Any failure is reported by Exec.
+	r.redis.LPush(noteToKey(n), entry)
+	for _, k := range staleIndexes {
+		r.redis.SRem(k, n.LoanNoteID)
+	}
+	for _, k := range redis.NoteIndexes(n) {
+		r.redis.SAdd(k, n.LoanNoteID)
+	}
+	r.redis.HSet(redis.LineageKey(n.ListingNumber), redis.LineageFieldNotePrefix+n.LoanNoteID, n.LoanNoteID)
+	return r.exec()
+}
+
+// recordBorrower adds a new note to the exposure of its borrower, so that notes
+// the bot did not bid on count toward the exposure limit before the borrower
+// index is next rebuilt at startup. Notes whose listing snapshot has expired
+// or has no member key cannot be counted.
+func (r redisLogger) recordBorrower(n prosper.Note) error {
+	l, err := redis.Listing(r.redis, n.ListingNumber)
+	if err == redis.ErrListingNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	id, ok := redis.BorrowerID(l)
+	if !ok {
+		return nil
+	}
+	return redis.RecordBorrowerBid(r.redis, id, n.ListingNumber, n.NoteOwnershipAmount)
+}
+
+// index adds the note to its indexes and its listing's lineage.
+func (r redisLogger) index(n prosper.Note) error {
+	if _, err := r.redis.Multi(); err != nil {
+		return err
+	}
+	for _, k := range redis.NoteIndexes(n) {
+		r.redis.SAdd(k, n.LoanNoteID)
+	}
+	r.redis.HSet(redis.LineageKey(n.ListingNumber), redis.LineageFieldNotePrefix+n.LoanNoteID, n.LoanNoteID)
+	return r.exec()
+}
+
+func (r redisLogger) exec() error {
+	replies, err := r.redis.Exec()
+	if err != nil {
+		return err
+	}
+	for _, reply := range replies {
+		if err, ok := reply.(error); ok {
+			return err
+		}
+	}
 	return nil
 }
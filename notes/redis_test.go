@@ -7,13 +7,62 @@ import (
 	"time"
 
 	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/redis"
 )
 
 type mockRedisListPrepender struct {
 	LRangeErr   error
 	LPushCalled bool
 	LPushErr    error
+	Values      map[string]string
 	State       map[string][]string
+	Sets        map[string]map[string]bool
+	Hashes      map[string]map[string]string
+}
+
+func (p *mockRedisListPrepender) Get(key string) (string, error) {
+	return p.Values[key], nil
+}
+
+func (p *mockRedisListPrepender) HSet(key string, field string, value interface{}) (bool, error) {
+	if p.Hashes == nil {
+		p.Hashes = map[string]map[string]string{}
+	}
+	if p.Hashes[key] == nil {
+		p.Hashes[key] = map[string]string{}
+	}
+	_, exists := p.Hashes[key][field]
+	p.Hashes[key][field] = value.(string)
+	return !exists, nil
+}
+
+func (p *mockRedisListPrepender) SAdd(key string, members ...interface{}) (int64, error) {
+	if p.Sets == nil {
+		p.Sets = map[string]map[string]bool{}
+	}
+	if p.Sets[key] == nil {
+		p.Sets[key] = map[string]bool{}
+	}
+	for _, m := range members {
+		p.Sets[key][m.(string)] = true
+	}
+	return int64(len(members)), nil
+}
+
+func (p *mockRedisListPrepender) SRem(key string, members ...interface{}) (int64, error) {
+	for _, m := range members {
+		delete(p.Sets[key], m.(string))
+	}
+	return int64(len(members)), nil
+}
+
+func (p *mockRedisListPrepender) Multi() (string, error) {
+	return "OK", nil
+}
+
+func (p *mockRedisListPrepender) Exec() ([]interface{}, error) {
+	return []interface{}{}, p.LPushErr
 }
 
 func (p *mockRedisListPrepender) LRange(key string, start int64, stop int64) ([]string, error) {
@@ -24,7 +73,14 @@ func (p *mockRedisListPrepender) LRange(key string, start int64, stop int64) ([]
 	if !ok {
 		return []string{}, nil
 	}
-	if (start > int64(len(list))) || ((stop + 1) > int64(len(list))) {
+	if stop < 0 {
+		stop += int64(len(list))
+	}
+	// As with Redis, a stop past the end of the list means the end.
+	if stop >= int64(len(list)) {
+		stop = int64(len(list)) - 1
+	}
+	if start > stop {
 		return []string{}, nil
 	}
 	return list[start : stop+1], nil
@@ -53,11 +109,12 @@ func (c mockClock) Now() time.Time {
 }
 
 const (
-	noteASerializedOld     = `{"Note":{"AgeInMonths":0,"AmountBorrowed":0,"BorrowerRate":0,"DaysPastDue":0,"DebtSaleProceedsReceivedProRataShare":0,"InterestPaidProRataShare":0,"IsSold":false,"LateFeesPaidProRataShare":0,"ListingNumber":0,"LoanNoteID":"noteA","LoanNumber":0,"NextPaymentDueAmountProRataShare":0,"NextPaymentDueDate":"0001-01-01T00:00:00Z","NoteDefaultReasonDescription":"","NoteDefaultReason":null,"NoteOwnershipAmount":0,"NoteSaleFeesPaid":0,"NoteSaleGrossAmountReceived":0,"NoteStatusDescription":"","NoteStatus":0,"OriginationDate":"0001-01-01T00:00:00Z","PrincipalBalanceProRataShare":0,"PrincipalPaidProRataShare":0,"ProsperFeesPaidProRataShare":0,"Rating":0,"ServiceFeesPaidProRataShare":0,"Term":0},"Timestamp":"2016-03-04T23:19:22.000000022Z"}`
-	noteASerializedNew     = `{"Note":{"AgeInMonths":0,"AmountBorrowed":0,"BorrowerRate":0,"DaysPastDue":0,"DebtSaleProceedsReceivedProRataShare":0,"InterestPaidProRataShare":0,"IsSold":false,"LateFeesPaidProRataShare":0,"ListingNumber":0,"LoanNoteID":"noteA","LoanNumber":0,"NextPaymentDueAmountProRataShare":0,"NextPaymentDueDate":"0001-01-01T00:00:00Z","NoteDefaultReasonDescription":"","NoteDefaultReason":null,"NoteOwnershipAmount":0,"NoteSaleFeesPaid":0,"NoteSaleGrossAmountReceived":0,"NoteStatusDescription":"","NoteStatus":0,"OriginationDate":"0001-01-01T00:00:00Z","PrincipalBalanceProRataShare":0,"PrincipalPaidProRataShare":0,"ProsperFeesPaidProRataShare":0,"Rating":0,"ServiceFeesPaidProRataShare":0,"Term":0},"Timestamp":"2016-03-05T11:40:15.000000022Z"}`
-	noteAChangedSerialized = `{"Note":{"AgeInMonths":1,"AmountBorrowed":0,"BorrowerRate":0,"DaysPastDue":0,"DebtSaleProceedsReceivedProRataShare":0,"InterestPaidProRataShare":0,"IsSold":false,"LateFeesPaidProRataShare":0,"ListingNumber":0,"LoanNoteID":"noteA","LoanNumber":0,"NextPaymentDueAmountProRataShare":0,"NextPaymentDueDate":"0001-01-01T00:00:00Z","NoteDefaultReasonDescription":"","NoteDefaultReason":null,"NoteOwnershipAmount":0,"NoteSaleFeesPaid":0,"NoteSaleGrossAmountReceived":0,"NoteStatusDescription":"","NoteStatus":0,"OriginationDate":"0001-01-01T00:00:00Z","PrincipalBalanceProRataShare":0,"PrincipalPaidProRataShare":0,"ProsperFeesPaidProRataShare":0,"Rating":0,"ServiceFeesPaidProRataShare":0,"Term":0},"Timestamp":"2016-03-05T11:40:15.000000022Z"}`
-	noteBSerialized        = `{"Note":{"AgeInMonths":0,"AmountBorrowed":0,"BorrowerRate":0,"DaysPastDue":0,"DebtSaleProceedsReceivedProRataShare":0,"InterestPaidProRataShare":0,"IsSold":false,"LateFeesPaidProRataShare":0,"ListingNumber":0,"LoanNoteID":"noteB","LoanNumber":0,"NextPaymentDueAmountProRataShare":0,"NextPaymentDueDate":"0001-01-01T00:00:00Z","NoteDefaultReasonDescription":"","NoteDefaultReason":2,"NoteOwnershipAmount":0,"NoteSaleFeesPaid":0,"NoteSaleGrossAmountReceived":0,"NoteStatusDescription":"","NoteStatus":0,"OriginationDate":"0001-01-01T00:00:00Z","PrincipalBalanceProRataShare":0,"PrincipalPaidProRataShare":0,"ProsperFeesPaidProRataShare":0,"Rating":0,"ServiceFeesPaidProRataShare":0,"Term":0},"Timestamp":"2016-03-05T11:40:15.000000022Z"}`
-	badJSON                = "{{mock bad JSON"
+	noteASerializedOld      = `{"Note":{"AgeInMonths":0,"AmountBorrowed":0,"BorrowerRate":0,"DaysPastDue":0,"DebtSaleProceedsReceivedProRataShare":0,"InterestPaidProRataShare":0,"IsSold":false,"LateFeesPaidProRataShare":0,"ListingNumber":0,"LoanNoteID":"noteA","LoanNumber":0,"NextPaymentDueAmountProRataShare":0,"NextPaymentDueDate":"0001-01-01T00:00:00Z","NoteDefaultReasonDescription":"","NoteDefaultReason":null,"NoteOwnershipAmount":0,"NoteSaleFeesPaid":0,"NoteSaleGrossAmountReceived":0,"NoteStatusDescription":"","NoteStatus":0,"OriginationDate":"0001-01-01T00:00:00Z","PrincipalBalanceProRataShare":0,"PrincipalPaidProRataShare":0,"ProsperFeesPaidProRataShare":0,"Rating":0,"ServiceFeesPaidProRataShare":0,"Term":0},"Timestamp":"2016-03-04T23:19:22.000000022Z"}`
+	noteASerializedNew      = `{"Note":{"AgeInMonths":0,"AmountBorrowed":0,"BorrowerRate":0,"DaysPastDue":0,"DebtSaleProceedsReceivedProRataShare":0,"InterestPaidProRataShare":0,"IsSold":false,"LateFeesPaidProRataShare":0,"ListingNumber":0,"LoanNoteID":"noteA","LoanNumber":0,"NextPaymentDueAmountProRataShare":0,"NextPaymentDueDate":"0001-01-01T00:00:00Z","NoteDefaultReasonDescription":"","NoteDefaultReason":null,"NoteOwnershipAmount":0,"NoteSaleFeesPaid":0,"NoteSaleGrossAmountReceived":0,"NoteStatusDescription":"","NoteStatus":0,"OriginationDate":"0001-01-01T00:00:00Z","PrincipalBalanceProRataShare":0,"PrincipalPaidProRataShare":0,"ProsperFeesPaidProRataShare":0,"Rating":0,"ServiceFeesPaidProRataShare":0,"Term":0},"Timestamp":"2016-03-05T11:40:15.000000022Z","Version":1}`
+	noteARevertedSerialized = `{"Changes":[{"Field":"AgeInMonths","Old":1,"New":0}],"Timestamp":"2016-03-05T11:40:15.000000022Z","Version":1}`
+	noteAChangedSerialized  = `{"Changes":[{"Field":"AgeInMonths","Old":0,"New":1}],"Timestamp":"2016-03-05T11:40:15.000000022Z","Version":1}`
+	noteBSerialized         = `{"Note":{"AgeInMonths":0,"AmountBorrowed":0,"BorrowerRate":0,"DaysPastDue":0,"DebtSaleProceedsReceivedProRataShare":0,"InterestPaidProRataShare":0,"IsSold":false,"LateFeesPaidProRataShare":0,"ListingNumber":0,"LoanNoteID":"noteB","LoanNumber":0,"NextPaymentDueAmountProRataShare":0,"NextPaymentDueDate":"0001-01-01T00:00:00Z","NoteDefaultReasonDescription":"","NoteDefaultReason":2,"NoteOwnershipAmount":0,"NoteSaleFeesPaid":0,"NoteSaleGrossAmountReceived":0,"NoteStatusDescription":"","NoteStatus":0,"OriginationDate":"0001-01-01T00:00:00Z","PrincipalBalanceProRataShare":0,"PrincipalPaidProRataShare":0,"ProsperFeesPaidProRataShare":0,"Rating":0,"ServiceFeesPaidProRataShare":0,"Term":0},"Timestamp":"2016-03-05T11:40:15.000000022Z","Version":1}`
+	badJSON                 = "{{mock bad JSON"
 )
 
 var (
@@ -131,7 +188,29 @@ func TestRedisLogger(t *testing.T) {
 			wantEndState: map[string][]string{
 				"note:noteA": {noteAChangedSerialized, noteASerializedOld},
 			},
-			msg: "if there is a note update with changes, push",
+			msg: "if there is a note update with changes, push the changed fields",
+		},
+		{
+			startingRedisState: map[string][]string{
+				"note:noteA": {noteAChangedSerialized, noteASerializedOld},
+			},
+			updates:         []prosper.Note{noteAChanged},
+			wantLPushCalled: false,
+			wantEndState: map[string][]string{
+				"note:noteA": {noteAChangedSerialized, noteASerializedOld},
+			},
+			msg: "saved diffs should be applied when checking for changes",
+		},
+		{
+			startingRedisState: map[string][]string{
+				"note:noteA": {noteAChangedSerialized, noteASerializedOld},
+			},
+			updates:         []prosper.Note{noteA},
+			wantLPushCalled: true,
+			wantEndState: map[string][]string{
+				"note:noteA": {noteARevertedSerialized, noteAChangedSerialized, noteASerializedOld},
+			},
+			msg: "changes should be diffed against the latest rebuilt state",
 		},
 		{
 			startingRedisState: map[string][]string{},
@@ -179,6 +258,53 @@ func TestRedisLogger(t *testing.T) {
 	}
 }
 
+func TestRedisLoggerCheckpoints(t *testing.T) {
+	// Diffs newest first, alternating so that the latest state is noteAChanged.
+	diffs := []string{}
+	for i := 0; i < redis.NoteCheckpointInterval; i++ {
+		diffs = append(diffs, noteAChangedSerialized, noteARevertedSerialized)
+	}
+	var tests = []struct {
+		history   []string
+		wantFirst string
+		msg       string
+	}{
+		{
+			history:   []string{noteAChangedSerialized, noteASerializedOld},
+			wantFirst: noteARevertedSerialized,
+			msg:       "changes soon after a full record should be saved as a diff",
+		},
+		{
+			history:   append(append([]string{}, diffs[:redis.NoteCheckpointInterval]...), noteASerializedOld),
+			wantFirst: noteASerializedNew,
+			msg:       "changes after a run of diffs should be saved as a full record",
+		},
+		{
+			history:   append(append([]string{}, diffs[:len(diffs)-1]...), noteASerializedOld),
+			wantFirst: noteASerializedNew,
+			msg:       "a long run of diffs saved before checkpoints should be read in full",
+		},
+	}
+	for _, tt := range tests {
+		noteUpdates := make(chan prosper.Note)
+		done := make(chan bool)
+		prepender := mockRedisListPrepender{State: map[string][]string{"note:noteA": tt.history}}
+		redisLogger := redisLogger{
+			noteUpdates: noteUpdates,
+			done:        done,
+			redis:       &prepender,
+			clock:       mockClock{time.Date(2016, 3, 5, 11, 40, 15, 22, time.UTC)},
+		}
+		go redisLogger.Run()
+		noteUpdates <- noteA
+		close(noteUpdates)
+		<-done
+		if got := prepender.State["note:noteA"][0]; got != tt.wantFirst {
+			t.Errorf("%s: unexpected latest entry. got: %s, want: %s", tt.msg, got, tt.wantFirst)
+		}
+	}
+}
+
 func TestNoteEqual(t *testing.T) {
 	tests := []struct {
 		a    prosper.Note
@@ -254,3 +380,181 @@ func TestNoteEqual(t *testing.T) {
 		}
 	}
 }
+
+func TestRedisLoggerTransitions(t *testing.T) {
+	var tests = []struct {
+		startingRedisState map[string][]string
+		updates            []prosper.Note
+		wantTransitions    []Transition
+		msg                string
+	}{
+		{
+			startingRedisState: map[string][]string{},
+			updates:            []prosper.Note{noteA},
+			wantTransitions:    []Transition{},
+			msg:                "a note with no history should not produce a transition",
+		},
+		{
+			startingRedisState: map[string][]string{
+				"note:noteA": {noteASerializedOld},
+			},
+			updates:         []prosper.Note{noteA},
+			wantTransitions: []Transition{},
+			msg:             "an unchanged note should not produce a transition",
+		},
+		{
+			startingRedisState: map[string][]string{
+				"note:noteA": {noteASerializedOld},
+			},
+			updates: []prosper.Note{noteAChanged},
+			wantTransitions: []Transition{
+				{
+					Previous:  noteA,
+					Current:   noteAChanged,
+					Timestamp: time.Date(2016, 3, 5, 11, 40, 15, 22, time.UTC),
+				},
+			},
+			msg: "a changed note should produce a transition from its saved state",
+		},
+	}
+	for _, tt := range tests {
+		noteUpdates := make(chan prosper.Note)
+		transitions := make(chan Transition, len(tt.updates))
+		done := make(chan bool)
+		redisLogger := redisLogger{
+			noteUpdates: noteUpdates,
+			transitions: transitions,
+			done:        done,
+			redis:       &mockRedisListPrepender{State: tt.startingRedisState},
+			clock:       mockClock{time.Date(2016, 3, 5, 11, 40, 15, 22, time.UTC)},
+		}
+		go redisLogger.Run()
+		for _, u := range tt.updates {
+			noteUpdates <- u
+		}
+		close(noteUpdates)
+		<-done
+		gotTransitions := []Transition{}
+		for i := 0; i < len(tt.wantTransitions); i++ {
+			gotTransitions = append(gotTransitions, <-transitions)
+		}
+		if !reflect.DeepEqual(gotTransitions, tt.wantTransitions) {
+			t.Errorf("%s: unexpected transitions. got: %+v, want: %+v", tt.msg, gotTransitions, tt.wantTransitions)
+		}
+	}
+}
+
+func TestRedisLoggerIndexes(t *testing.T) {
+	originated := time.Date(2016, 2, 10, 0, 0, 0, 0, time.UTC)
+	current := prosper.Note{LoanNoteID: "1619-2", ListingNumber: 1619, NoteStatus: 1, NoteStatusDescription: "CURRENT", Rating: 2, Term: 36, OriginationDate: originated}
+	late := current
+	late.NoteStatus = 2
+	late.NoteStatusDescription = "CHARGEOFF"
+	late.DaysPastDue = 16
+	var tests = []struct {
+		updates  []prosper.Note
+		wantSets map[string]map[string]bool
+		msg      string
+	}{
+		{
+			updates: []prosper.Note{current},
+			wantSets: map[string]map[string]bool{
+				"noteIndex:status:1":                  {"1619-2": true},
+				"noteIndex:statusDescription:CURRENT": {"1619-2": true},
+				"noteIndex:rating:2":                  {"1619-2": true},
+				"noteIndex:term:36":                   {"1619-2": true},
+				"noteIndex:listing:1619":              {"1619-2": true},
+				"noteIndex:origination:2016-02":       {"1619-2": true},
+			},
+			msg: "a new note should be added to its indexes",
+		},
+		{
+			updates: []prosper.Note{current, late},
+			wantSets: map[string]map[string]bool{
+				"noteIndex:status:1":                    {},
+				"noteIndex:status:2":                    {"1619-2": true},
+				"noteIndex:statusDescription:CURRENT":   {},
+				"noteIndex:statusDescription:CHARGEOFF": {"1619-2": true},
+				"noteIndex:late":                        {"1619-2": true},
+				"noteIndex:rating:2":                    {"1619-2": true},
+				"noteIndex:term:36":                     {"1619-2": true},
+				"noteIndex:listing:1619":                {"1619-2": true},
+				"noteIndex:origination:2016-02":         {"1619-2": true},
+			},
+			msg: "a note should move to the indexes of its new state",
+		},
+	}
+	for _, tt := range tests {
+		noteUpdates := make(chan prosper.Note)
+		done := make(chan bool)
+		store := mockRedisListPrepender{State: map[string][]string{}}
+		redisLogger := redisLogger{
+			noteUpdates: noteUpdates,
+			done:        done,
+			redis:       &store,
+			clock:       mockClock{time.Date(2016, 3, 5, 11, 40, 15, 22, time.UTC)},
+		}
+		go redisLogger.Run()
+		for _, u := range tt.updates {
+			noteUpdates <- u
+		}
+		close(noteUpdates)
+		<-done
+		if !reflect.DeepEqual(store.Sets, tt.wantSets) {
+			t.Errorf("%s: unexpected indexes. got: %+v, want: %+v", tt.msg, store.Sets, tt.wantSets)
+		}
+		wantLineage := map[string]string{"note:1619-2": "1619-2"}
+		if got := store.Hashes["lineage:1619"]; !reflect.DeepEqual(got, wantLineage) {
+			t.Errorf("%s: unexpected lineage. got: %+v, want: %+v", tt.msg, got, wantLineage)
+		}
+	}
+}
+
+func TestRedisLoggerBorrowers(t *testing.T) {
+	note := prosper.Note{LoanNoteID: "1619-2", ListingNumber: 1619, NoteOwnershipAmount: 25.0}
+	var tests = []struct {
+		values     map[string]string
+		updates    []prosper.Note
+		wantHashes map[string]string
+		msg        string
+	}{
+		{
+			values:     map[string]string{"listing:1619": `{"ListingNumber":1619,"MemberKey":"ABC123"}`},
+			updates:    []prosper.Note{note},
+			wantHashes: map[string]string{"1619": "25.00"},
+			msg:        "a new note should count toward its borrower's exposure",
+		},
+		{
+			values:     map[string]string{},
+			updates:    []prosper.Note{note},
+			wantHashes: nil,
+			msg:        "a new note whose listing snapshot expired cannot be counted",
+		},
+		{
+			values:     map[string]string{"listing:1619": `{"ListingNumber":1619}`},
+			updates:    []prosper.Note{note},
+			wantHashes: nil,
+			msg:        "a new note whose listing has no member key cannot be counted",
+		},
+	}
+	for _, tt := range tests {
+		noteUpdates := make(chan prosper.Note)
+		done := make(chan bool)
+		store := mockRedisListPrepender{Values: tt.values, State: map[string][]string{}}
+		redisLogger := redisLogger{
+			noteUpdates: noteUpdates,
+			done:        done,
+			redis:       &store,
+			clock:       mockClock{time.Date(2016, 3, 5, 11, 40, 15, 22, time.UTC)},
+		}
+		go redisLogger.Run()
+		for _, u := range tt.updates {
+			noteUpdates <- u
+		}
+		close(noteUpdates)
+		<-done
+		if got := store.Hashes["borrower:member:ABC123"]; !reflect.DeepEqual(got, tt.wantHashes) {
+			t.Errorf("%s: unexpected borrower exposure. got: %+v, want: %+v", tt.msg, got, tt.wantHashes)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package notify
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/mtlynch/prosperbot/clock"
+	"github.com/mtlynch/prosperbot/config"
+	"github.com/mtlynch/prosperbot/notes"
+	"github.com/mtlynch/prosperbot/redis"
+)
+
+type dispatcher struct {
+	transitions <-chan notes.Transition
+	enabled     map[EventType]bool
+	notifiers   []Notifier
+	batchWindow time.Duration
+	redis       redis.RedisNotificationQueue
+	clock       clock.Timer
+	done        chan<- bool
+}
+
+const defaultBatchWindow = 5 * time.Minute
+
+func NewDispatcher(transitions <-chan notes.Transition, c config.Notifications, notifiers []Notifier, clk clock.Timer) (dispatcher, error) {
+	r, err := redis.New()
+	if err != nil {
+		return dispatcher{}, err
+	}
+	batchWindow := c.BatchWindow.Duration
+	if batchWindow == 0 {
+		batchWindow = defaultBatchWindow
+	}
+	return dispatcher{
+		transitions: transitions,
+		enabled: map[EventType]bool{
+			EventLate:      c.NotifyLate,
+			EventChargeOff: c.NotifyChargeOff,
+			EventDefault:   c.NotifyDefault,
+		},
+		notifiers:   notifiers,
+		batchWindow: batchWindow,
+		redis:       r,
+		clock:       clk,
+	}, nil
+}
+
+// Run collects events from note transitions and sends them in batches. The
+// first event in a batch starts a timer, and everything that arrives before it
+// fires is sent together, so a single poll that finds many late notes produces
+// a single alert. If every notifier fails, the batch is retried after another
+// window. Pending events are saved as they arrive, so that a batch cut short by
+// a restart is sent when the dispatcher starts again.
+func (d dispatcher) Run() {
+	pending := d.loadPending()
+	var flush <-chan time.Time
+	if len(pending) > 0 {
+		flush = d.clock.After(d.batchWindow)
+	}
+	for {
+		select {
+		case t, more := <-d.transitions:
+			if !more {
+				d.flush(pending)
+				if d.done != nil {
+					d.done <- true
+				}
+				return
+			}
+			for _, e := range eventsFromTransition(t) {
+				if !d.enabled[e.Type] {
+					continue
+				}
+				d.savePending(e)
+				pending = append(pending, e)
+			}
+			if len(pending) > 0 && flush == nil {
+				flush = d.clock.After(d.batchWindow)
+			}
+		case <-flush:
+			pending = d.flush(pending)
+			flush = nil
+			if len(pending) > 0 {
+				flush = d.clock.After(d.batchWindow)
+			}
+		}
+	}
+}
+
+// loadPending returns the events that were waiting to be sent when the
+// dispatcher last stopped.
+func (d dispatcher) loadPending() []Event {
+	saved, err := d.redis.LRange(redis.KeyPendingNotifications, 0, -1)
+	if err != nil {
+		log.Printf("failed to load pending notifications: %v", err)
+		return nil
+	}
+	pending := []Event{}
+	for _, s := range saved {
+		var e Event
+		if err = json.Unmarshal([]byte(s), &e); err != nil {
+			log.Printf("dropping unreadable pending notification %q: %v", s, err)
+			continue
+		}
+		pending = append(pending, e)
+	}
+	if len(pending) > 0 {
+		log.Printf("loaded %d pending note alert(s)", len(pending))
+	}
+	return pending
+}
+
+func (d dispatcher) savePending(e Event) {
+	serialized, err := json.Marshal(e)
+	if err != nil {
+		log.Printf("failed to serialize notification for %s: %v", e.key(), err)
+		return
+	}
+	if _, err = d.redis.RPush(redis.KeyPendingNotifications, string(serialized)); err != nil {
+		log.Printf("failed to save pending notification for %s: %v", e.key(), err)
+	}
+}
+
+// flush sends the pending events and returns the ones to retry. Once nothing
+// is left to retry, every saved event has been either sent or found to have
+// been sent before, so the saved events are cleared.
+func (d dispatcher) flush(pending []Event) []Event {
+	retry := d.send(pending)
+	if len(retry) == 0 {
+		if _, err := d.redis.Del(redis.KeyPendingNotifications); err != nil {
+			log.Printf("failed to clear pending notifications: %v", err)
+		}
+	}
+	return retry
+}
+
+// send delivers events that have not been notified before and returns the
+// events that could not be delivered. Events are only marked as notified once
+// a notifier has delivered them, so a failure or restart can cause a
+// duplicate alert but never a missed one.
+func (d dispatcher) send(events []Event) []Event {
+	unsent := d.unsent(events)
+	if len(unsent) == 0 {
+		return nil
+	}
+	log.Printf("sending %d note alert(s)", len(unsent))
+	failures := 0
+	for _, n := range d.notifiers {
+		if err := n.Notify(unsent); err != nil {
+			log.Printf("failed to send notification: %v", err)
+			failures++
+		}
+	}
+	if failures > 0 && failures == len(d.notifiers) {
+		return unsent
+	}
+	d.markSent(unsent)
+	return nil
+}
+
+// unsent returns the events that have not been notified before, each once.
+func (d dispatcher) unsent(events []Event) []Event {
+	unsent := []Event{}
+	seen := map[string]bool{}
+	for _, e := range events {
+		if seen[e.key()] {
+			continue
+		}
+		seen[e.key()] = true
+		sent, err := d.redis.Exists(redis.KeyPrefixNotification + e.key())
+		if err != nil {
+			// Err on the side of a duplicate alert rather than a missed one.
+			log.Printf("failed to check notification record for %s: %v", e.key(), err)
+		} else if sent {
+			continue
+		}
+		unsent = append(unsent, e)
+	}
+	return unsent
+}
+
+// markSent records that events were notified so that they are never sent
+// again.
+func (d dispatcher) markSent(events []Event) {
+	for _, e := range events {
+		if _, err := d.redis.SetNX(redis.KeyPrefixNotification+e.key(), d.clock.Now().String()); err != nil {
+			log.Printf("failed to record notification for %s: %v", e.key(), err)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package notify
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/clock"
+	"github.com/mtlynch/prosperbot/notes"
+)
+
+type mockNotifier struct {
+	mu      sync.Mutex
+	batches [][]Event
+	err     error
+}
+
+func (n *mockNotifier) Notify(events []Event) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.batches = append(n.batches, events)
+	return n.err
+}
+
+func (n *mockNotifier) SendMessage(m Message) error {
+	return n.err
+}
+
+type mockRedisNotificationQueue struct {
+	mu     sync.Mutex
+	values map[string]string
+	lists  map[string][]string
+	err    error
+}
+
+func (r *mockRedisNotificationQueue) Exists(key string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return false, r.err
+	}
+	_, exists := r.values[key]
+	return exists, nil
+}
+
+func (r *mockRedisNotificationQueue) SetNX(key string, value interface{}) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return false, r.err
+	}
+	if _, exists := r.values[key]; exists {
+		return false, nil
+	}
+	r.values[key] = value.(string)
+	return true, nil
+}
+
+func (r *mockRedisNotificationQueue) Del(keys ...string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, k := range keys {
+		delete(r.values, k)
+		delete(r.lists, k)
+	}
+	return int64(len(keys)), nil
+}
+
+func (r *mockRedisNotificationQueue) LRange(key string, start int64, stop int64) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return nil, r.err
+	}
+	return append([]string{}, r.lists[key]...), nil
+}
+
+func (r *mockRedisNotificationQueue) RPush(key string, values ...interface{}) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return 0, r.err
+	}
+	if r.lists == nil {
+		r.lists = map[string][]string{}
+	}
+	for _, v := range values {
+		r.lists[key] = append(r.lists[key], v.(string))
+	}
+	return int64(len(r.lists[key])), nil
+}
+
+func withNoteID(n prosper.Note, id string) prosper.Note {
+	n.LoanNoteID = id
+	return n
+}
+
+func withEventNoteID(e Event, id string) Event {
+	e.LoanNoteID = id
+	return e
+}
+
+func TestDispatcher(t *testing.T) {
+	var tests = []struct {
+		transitions       []notes.Transition
+		enabled           map[EventType]bool
+		redisStartingKeys map[string]string
+		redisErr          error
+		wantBatches       [][]Event
+		wantRedisKeys     []string
+		msg               string
+	}{
+		{
+			transitions: []notes.Transition{
+				{Previous: noteCurrent, Current: noteLate, Timestamp: mockTimestamp},
+			},
+			enabled:       map[EventType]bool{EventLate: true},
+			wantBatches:   [][]Event{{lateEvent}},
+			wantRedisKeys: []string{"notification:1619-2:late:2016-05-01"},
+			msg:           "a late note should be notified",
+		},
+		{
+			transitions: []notes.Transition{
+				{Previous: noteCurrent, Current: noteLate, Timestamp: mockTimestamp},
+			},
+			enabled:       map[EventType]bool{EventChargeOff: true},
+			wantBatches:   [][]Event{},
+			wantRedisKeys: []string{},
+			msg:           "disabled event types should not be notified",
+		},
+		{
+			transitions: []notes.Transition{
+				{Previous: noteCurrent, Current: noteLate, Timestamp: mockTimestamp},
+				{Previous: withNoteID(noteCurrent, "2000-1"), Current: withNoteID(noteDefaulted, "2000-1"), Timestamp: mockTimestamp},
+			},
+			enabled:     map[EventType]bool{EventLate: true, EventDefault: true},
+			wantBatches: [][]Event{{lateEvent, withEventNoteID(defaultEvent, "2000-1")}},
+			wantRedisKeys: []string{
+				"notification:1619-2:late:2016-05-01",
+				"notification:2000-1:default",
+			},
+			msg: "events within the batch window should be sent together",
+		},
+		{
+			transitions: []notes.Transition{
+				{Previous: noteCurrent, Current: noteLate, Timestamp: mockTimestamp},
+			},
+			enabled: map[EventType]bool{EventLate: true},
+			redisStartingKeys: map[string]string{
+				"notification:1619-2:late:2016-05-01": "2016-05-01 12:00:00 +0000 UTC",
+			},
+			wantBatches:   [][]Event{},
+			wantRedisKeys: []string{"notification:1619-2:late:2016-05-01"},
+			msg:           "previously notified events should not be notified again",
+		},
+		{
+			transitions: []notes.Transition{
+				{Previous: noteCurrent, Current: noteLate, Timestamp: mockTimestamp},
+			},
+			enabled:       map[EventType]bool{EventLate: true},
+			redisErr:      errors.New("mock redis error"),
+			wantBatches:   [][]Event{{lateEvent}},
+			wantRedisKeys: []string{},
+			msg:           "redis errors should not prevent notification",
+		},
+	}
+	for _, tt := range tests {
+		transitions := make(chan notes.Transition)
+		done := make(chan bool)
+		notifier := mockNotifier{}
+		startingKeys := tt.redisStartingKeys
+		if startingKeys == nil {
+			startingKeys = map[string]string{}
+		}
+		r := mockRedisNotificationQueue{
+			values: startingKeys,
+			err:    tt.redisErr,
+		}
+		d := dispatcher{
+			transitions: transitions,
+			enabled:     tt.enabled,
+			notifiers:   []Notifier{&notifier},
+			batchWindow: time.Hour,
+			redis:       &r,
+			clock:       clock.NewVirtual(mockTimestamp),
+			done:        done,
+		}
+		go d.Run()
+		for _, tr := range tt.transitions {
+			transitions <- tr
+		}
+		close(transitions)
+		<-done
+		gotBatches := notifier.batches
+		if gotBatches == nil {
+			gotBatches = [][]Event{}
+		}
+		if !reflect.DeepEqual(gotBatches, tt.wantBatches) {
+			t.Errorf("%s: unexpected batches. got: %+v, want: %+v", tt.msg, gotBatches, tt.wantBatches)
+		}
+		gotKeys := []string{}
+		for _, k := range tt.wantRedisKeys {
+			if _, ok := r.values[k]; ok {
+				gotKeys = append(gotKeys, k)
+			}
+		}
+		if len(r.values) != len(tt.wantRedisKeys) || !reflect.DeepEqual(gotKeys, tt.wantRedisKeys) {
+			t.Errorf("%s: unexpected redis keys. got: %v, want: %v", tt.msg, r.values, tt.wantRedisKeys)
+		}
+	}
+}
+
+func TestDispatcherBatchWindow(t *testing.T) {
+	transitions := make(chan notes.Transition)
+	notifier := mockNotifier{}
+	v := clock.NewVirtual(mockTimestamp)
+	d := dispatcher{
+		transitions: transitions,
+		enabled:     map[EventType]bool{EventLate: true},
+		notifiers:   []Notifier{&notifier},
+		batchWindow: time.Hour,
+		redis:       &mockRedisNotificationQueue{values: map[string]string{}},
+		clock:       v,
+	}
+	go d.Run()
+	transitions <- notes.Transition{Previous: noteCurrent, Current: noteLate, Timestamp: mockTimestamp}
+	v.BlockUntil(1)
+	notifier.mu.Lock()
+	if len(notifier.batches) != 0 {
+		t.Errorf("expected no batch before window expired. got: %+v", notifier.batches)
+	}
+	notifier.mu.Unlock()
+	v.Advance(time.Hour)
+	deadline := time.Now().Add(time.Second)
+	for {
+		notifier.mu.Lock()
+		sent := len(notifier.batches) > 0
+		notifier.mu.Unlock()
+		if sent || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	notifier.mu.Lock()
+	defer notifier.mu.Unlock()
+	if !reflect.DeepEqual(notifier.batches, [][]Event{{lateEvent}}) {
+		t.Errorf("expected batch to be sent after window expired. got: %+v", notifier.batches)
+	}
+}
+
+func TestDispatcherFailedNotificationIsNotRecorded(t *testing.T) {
+	r := mockRedisNotificationQueue{values: map[string]string{}}
+	d := dispatcher{
+		notifiers: []Notifier{&mockNotifier{err: errors.New("mock notifier error")}},
+		redis:     &r,
+		clock:     clock.NewVirtual(mockTimestamp),
+	}
+	retry := d.send([]Event{lateEvent})
+	if !reflect.DeepEqual(retry, []Event{lateEvent}) {
+		t.Errorf("unexpected events to retry. got: %+v, want: %+v", retry, []Event{lateEvent})
+	}
+	if len(r.values) != 0 {
+		t.Errorf("expected no notification records after failure, got: %v", r.values)
+	}
+}
+
+func TestDispatcherSendsPendingEventsAfterRestart(t *testing.T) {
+	r := mockRedisNotificationQueue{values: map[string]string{}}
+	first := dispatcher{
+		enabled:   map[EventType]bool{EventLate: true},
+		notifiers: []Notifier{&mockNotifier{}},
+		redis:     &r,
+		clock:     clock.NewVirtual(mockTimestamp),
+	}
+	// The bot stops before the first batch window ends.
+	first.savePending(lateEvent)
+
+	transitions := make(chan notes.Transition)
+	done := make(chan bool)
+	notifier := mockNotifier{}
+	second := dispatcher{
+		transitions: transitions,
+		enabled:     map[EventType]bool{EventLate: true},
+		notifiers:   []Notifier{&notifier},
+		batchWindow: time.Hour,
+		redis:       &r,
+		clock:       clock.NewVirtual(mockTimestamp),
+		done:        done,
+	}
+	go second.Run()
+	close(transitions)
+	<-done
+	if !reflect.DeepEqual(notifier.batches, [][]Event{{lateEvent}}) {
+		t.Errorf("expected pending event to be sent after restart. got: %+v", notifier.batches)
+	}
+	if pending := r.lists["pendingNotifications"]; len(pending) != 0 {
+		t.Errorf("expected pending events to be cleared once sent, got: %v", pending)
+	}
+	if _, ok := r.values["notification:1619-2:late:2016-05-01"]; !ok {
+		t.Errorf("expected sent event to be recorded, got: %v", r.values)
+	}
+}
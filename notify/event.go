@@ -0,0 +1,80 @@
+package notify
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/notes"
+)
+
+type EventType string
+
+const (
+	EventLate      EventType = "late"
+	EventChargeOff EventType = "chargeoff"
+	EventDefault   EventType = "default"
+)
+
+// Event is a single note status change worth alerting on.
+type Event struct {
+	Type               EventType
+	LoanNoteID         string
+	ListingNumber      prosper.ListingNumber
+	PreviousStatus     string
+	Status             string
+	DaysPastDue        int
+	NextPaymentDueDate time.Time
+	PrincipalBalance   float64
+	Timestamp          time.Time
+}
+
+func (e Event) String() string {
+	switch e.Type {
+	case EventLate:
+		return fmt.Sprintf("note %s (listing %d) is %d days past due, principal balance $%.2f", e.LoanNoteID, e.ListingNumber, e.DaysPastDue, e.PrincipalBalance)
+	case EventChargeOff:
+		return fmt.Sprintf("note %s (listing %d) was charged off, principal balance $%.2f", e.LoanNoteID, e.ListingNumber, e.PrincipalBalance)
+	case EventDefault:
+		return fmt.Sprintf("note %s (listing %d) defaulted, principal balance $%.2f", e.LoanNoteID, e.ListingNumber, e.PrincipalBalance)
+	}
+	return fmt.Sprintf("note %s (listing %d) changed from %s to %s", e.LoanNoteID, e.ListingNumber, e.PreviousStatus, e.Status)
+}
+
+// key identifies the event for deduplication. A note can go late more than
+// once, so late events are distinguished by the payment that was missed.
+func (e Event) key() string {
+	if e.Type == EventLate {
+		return fmt.Sprintf("%s:%s:%s", e.LoanNoteID, e.Type, e.NextPaymentDueDate.Format("2006-01-02"))
+	}
+	return fmt.Sprintf("%s:%s", e.LoanNoteID, e.Type)
+}
+
+func eventsFromTransition(t notes.Transition) []Event {
+	prev, cur := t.Previous, t.Current
+	newEvent := func(eventType EventType) Event {
+		return Event{
+			Type:               eventType,
+			LoanNoteID:         cur.LoanNoteID,
+			ListingNumber:      cur.ListingNumber,
+			PreviousStatus:     prev.NoteStatusDescription,
+			Status:             cur.NoteStatusDescription,
+			DaysPastDue:        int(cur.DaysPastDue),
+			NextPaymentDueDate: cur.NextPaymentDueDate,
+			PrincipalBalance:   cur.PrincipalBalanceProRataShare,
+			Timestamp:          t.Timestamp,
+		}
+	}
+	events := []Event{}
+	if prev.DaysPastDue == 0 && cur.DaysPastDue > 0 {
+		events = append(events, newEvent(EventLate))
+	}
+	if prev.NoteStatusDescription != notes.StatusChargeOff && cur.NoteStatusDescription == notes.StatusChargeOff {
+		events = append(events, newEvent(EventChargeOff))
+	}
+	if prev.NoteStatusDescription != notes.StatusDefaulted && cur.NoteStatusDescription == notes.StatusDefaulted {
+		events = append(events, newEvent(EventDefault))
+	}
+	return events
+}
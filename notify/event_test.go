@@ -0,0 +1,184 @@
+package notify
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/notes"
+)
+
+var (
+	mockTimestamp = time.Date(2016, 5, 2, 10, 15, 0, 0, time.UTC)
+	mockDueDate   = time.Date(2016, 5, 1, 0, 0, 0, 0, time.UTC)
+	noteCurrent   = prosper.Note{
+		LoanNoteID:                   "1619-2",
+		ListingNumber:                994439,
+		NoteStatusDescription:        "CURRENT",
+		NextPaymentDueDate:           mockDueDate,
+		PrincipalBalanceProRataShare: 21.5,
+	}
+	noteLate = prosper.Note{
+		LoanNoteID:                   "1619-2",
+		ListingNumber:                994439,
+		NoteStatusDescription:        "CURRENT",
+		DaysPastDue:                  16,
+		NextPaymentDueDate:           mockDueDate,
+		PrincipalBalanceProRataShare: 21.5,
+	}
+	noteLater = prosper.Note{
+		LoanNoteID:                   "1619-2",
+		ListingNumber:                994439,
+		NoteStatusDescription:        "CURRENT",
+		DaysPastDue:                  46,
+		NextPaymentDueDate:           mockDueDate,
+		PrincipalBalanceProRataShare: 21.5,
+	}
+	noteChargedOff = prosper.Note{
+		LoanNoteID:                   "1619-2",
+		ListingNumber:                994439,
+		NoteStatusDescription:        "CHARGEOFF",
+		DaysPastDue:                  121,
+		NextPaymentDueDate:           mockDueDate,
+		PrincipalBalanceProRataShare: 21.5,
+	}
+	noteDefaulted = prosper.Note{
+		LoanNoteID:                   "1619-2",
+		ListingNumber:                994439,
+		NoteStatusDescription:        "DEFAULTED",
+		NextPaymentDueDate:           mockDueDate,
+		PrincipalBalanceProRataShare: 21.5,
+	}
+	lateEvent = Event{
+		Type:               EventLate,
+		LoanNoteID:         "1619-2",
+		ListingNumber:      994439,
+		PreviousStatus:     "CURRENT",
+		Status:             "CURRENT",
+		DaysPastDue:        16,
+		NextPaymentDueDate: mockDueDate,
+		PrincipalBalance:   21.5,
+		Timestamp:          mockTimestamp,
+	}
+	chargeOffEvent = Event{
+		Type:               EventChargeOff,
+		LoanNoteID:         "1619-2",
+		ListingNumber:      994439,
+		PreviousStatus:     "CURRENT",
+		Status:             "CHARGEOFF",
+		DaysPastDue:        121,
+		NextPaymentDueDate: mockDueDate,
+		PrincipalBalance:   21.5,
+		Timestamp:          mockTimestamp,
+	}
+	defaultEvent = Event{
+		Type:               EventDefault,
+		LoanNoteID:         "1619-2",
+		ListingNumber:      994439,
+		PreviousStatus:     "CURRENT",
+		Status:             "DEFAULTED",
+		NextPaymentDueDate: mockDueDate,
+		PrincipalBalance:   21.5,
+		Timestamp:          mockTimestamp,
+	}
+)
+
+func TestEventsFromTransition(t *testing.T) {
+	var tests = []struct {
+		previous prosper.Note
+		current  prosper.Note
+		want     []Event
+		msg      string
+	}{
+		{
+			previous: noteCurrent,
+			current:  noteCurrent,
+			want:     []Event{},
+			msg:      "no change in status should produce no events",
+		},
+		{
+			previous: noteCurrent,
+			current:  noteLate,
+			want:     []Event{lateEvent},
+			msg:      "note becoming past due should produce a late event",
+		},
+		{
+			previous: noteLate,
+			current:  noteLater,
+			want:     []Event{},
+			msg:      "note that was already late should not produce another late event",
+		},
+		{
+			previous: noteLater,
+			current:  noteChargedOff,
+			want: []Event{
+				{
+					Type:               EventChargeOff,
+					LoanNoteID:         "1619-2",
+					ListingNumber:      994439,
+					PreviousStatus:     "CURRENT",
+					Status:             "CHARGEOFF",
+					DaysPastDue:        121,
+					NextPaymentDueDate: mockDueDate,
+					PrincipalBalance:   21.5,
+					Timestamp:          mockTimestamp,
+				},
+			},
+			msg: "late note being charged off should produce a charge off event",
+		},
+		{
+			previous: noteCurrent,
+			current:  noteChargedOff,
+			want: []Event{
+				{
+					Type:               EventLate,
+					LoanNoteID:         "1619-2",
+					ListingNumber:      994439,
+					PreviousStatus:     "CURRENT",
+					Status:             "CHARGEOFF",
+					DaysPastDue:        121,
+					NextPaymentDueDate: mockDueDate,
+					PrincipalBalance:   21.5,
+					Timestamp:          mockTimestamp,
+				},
+				chargeOffEvent,
+			},
+			msg: "jumping from current to charged off should produce late and charge off events",
+		},
+		{
+			previous: noteCurrent,
+			current:  noteDefaulted,
+			want:     []Event{defaultEvent},
+			msg:      "note defaulting should produce a default event",
+		},
+		{
+			previous: noteChargedOff,
+			current:  noteChargedOff,
+			want:     []Event{},
+			msg:      "note that was already charged off should produce no events",
+		},
+	}
+	for _, tt := range tests {
+		got := eventsFromTransition(notes.Transition{
+			Previous:  tt.previous,
+			Current:   tt.current,
+			Timestamp: mockTimestamp,
+		})
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: unexpected events. got: %+v, want: %+v", tt.msg, got, tt.want)
+		}
+	}
+}
+
+func TestEventKey(t *testing.T) {
+	laterLateEvent := lateEvent
+	laterLateEvent.NextPaymentDueDate = mockDueDate.AddDate(0, 1, 0)
+	if lateEvent.key() == laterLateEvent.key() {
+		t.Errorf("late events for different missed payments should have different keys, got %s for both", lateEvent.key())
+	}
+	if got, want := chargeOffEvent.key(), "1619-2:chargeoff"; got != want {
+		t.Errorf("unexpected key for charge off event. got: %s, want: %s", got, want)
+	}
+}
@@ -0,0 +1,39 @@
+package notify
+
+import (
+	"net/smtp"
+	"strconv"
+	"time"
+
+	"github.com/mtlynch/prosperbot/config"
+)
+
+// Message is a free-form report, such as a daily digest, with plain text and
+// HTML renderings of the same content.
+type Message struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Notifier delivers batches of events and reports to some destination.
+type Notifier interface {
+	Notify(events []Event) error
+	SendMessage(m Message) error
+}
+
+// NewNotifiers creates a notifier for each backend configured in c.
+func NewNotifiers(c config.Notifications) []Notifier {
+	notifiers := []Notifier{}
+	if c.SMTP != nil {
+		var auth smtp.Auth
+		if c.SMTP.Username != "" {
+			auth = smtp.PlainAuth("", c.SMTP.Username, c.SMTP.Password, c.SMTP.Host)
+		}
+		notifiers = append(notifiers, NewSMTPNotifier(c.SMTP.Host+":"+strconv.Itoa(c.SMTP.Port), auth, c.SMTP.From, c.SMTP.To))
+	}
+	if c.Webhook != nil {
+		notifiers = append(notifiers, NewWebhookNotifier(c.Webhook.URL, 30*time.Second))
+	}
+	return notifiers
+}
@@ -0,0 +1,81 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"net/smtp"
+	"strings"
+)
+
+type sendMailFn func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+
+type smtpNotifier struct {
+	addr     string
+	auth     smtp.Auth
+	from     string
+	to       []string
+	sendMail sendMailFn
+}
+
+// NewSMTPNotifier creates a Notifier that emails events through the SMTP
+// server at addr. auth may be nil if the server does not require it.
+func NewSMTPNotifier(addr string, auth smtp.Auth, from string, to []string) Notifier {
+	return smtpNotifier{
+		addr:     addr,
+		auth:     auth,
+		from:     from,
+		to:       to,
+		sendMail: smtp.SendMail,
+	}
+}
+
+func (n smtpNotifier) Notify(events []Event) error {
+	return n.sendMail(n.addr, n.auth, n.from, n.to, n.message(events))
+}
+
+func (n smtpNotifier) SendMessage(m Message) error {
+	return n.sendMail(n.addr, n.auth, n.from, n.to, n.multipartMessage(m))
+}
+
+func (n smtpNotifier) writeHeaders(b *bytes.Buffer, subject string) {
+	fmt.Fprintf(b, "From: %s\r\n", n.from)
+	fmt.Fprintf(b, "To: %s\r\n", strings.Join(n.to, ", "))
+	fmt.Fprintf(b, "Subject: %s\r\n", subject)
+}
+
+func (n smtpNotifier) message(events []Event) []byte {
+	var b bytes.Buffer
+	n.writeHeaders(&b, subject(events))
+	b.WriteString("\r\n")
+	for _, e := range events {
+		fmt.Fprintf(&b, "%s\r\n", e)
+	}
+	return b.Bytes()
+}
+
+const mimeBoundary = "prosperbot-alternative-boundary"
+
+// multipartMessage builds an email with both the text and HTML versions of m
+// so that mail clients can show whichever they support.
+func (n smtpNotifier) multipartMessage(m Message) []byte {
+	var b bytes.Buffer
+	n.writeHeaders(&b, m.Subject)
+	b.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&b, "Content-Type: multipart/alternative; boundary=%s\r\n", mimeBoundary)
+	b.WriteString("\r\n")
+	fmt.Fprintf(&b, "--%s\r\n", mimeBoundary)
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(m.Text)
+	fmt.Fprintf(&b, "\r\n--%s\r\n", mimeBoundary)
+	b.WriteString("Content-Type: text/html; charset=UTF-8\r\n\r\n")
+	b.WriteString(m.HTML)
+	fmt.Fprintf(&b, "\r\n--%s--\r\n", mimeBoundary)
+	return b.Bytes()
+}
+
+func subject(events []Event) string {
+	if len(events) == 1 {
+		return "ProsperBot: 1 note alert"
+	}
+	return fmt.Sprintf("ProsperBot: %d note alerts", len(events))
+}
@@ -0,0 +1,92 @@
+package notify
+
+import (
+	"errors"
+	"net/smtp"
+	"testing"
+)
+
+func TestSMTPNotifier(t *testing.T) {
+	var gotAddr, gotFrom string
+	var gotTo []string
+	var gotMsg []byte
+	n := smtpNotifier{
+		addr: "mail.example.com:25",
+		from: "bot@example.com",
+		to:   []string{"a@example.com", "b@example.com"},
+		sendMail: func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+			gotAddr, gotFrom, gotTo, gotMsg = addr, from, to, msg
+			return nil
+		},
+	}
+	if err := n.Notify([]Event{lateEvent, chargeOffEvent}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotAddr != "mail.example.com:25" {
+		t.Errorf("unexpected server address. got: %s, want: %s", gotAddr, "mail.example.com:25")
+	}
+	if gotFrom != "bot@example.com" {
+		t.Errorf("unexpected sender. got: %s, want: %s", gotFrom, "bot@example.com")
+	}
+	if len(gotTo) != 2 {
+		t.Errorf("unexpected recipients. got: %v, want: 2 recipients", gotTo)
+	}
+	wantMsg := "From: bot@example.com\r\n" +
+		"To: a@example.com, b@example.com\r\n" +
+		"Subject: ProsperBot: 2 note alerts\r\n" +
+		"\r\n" +
+		"note 1619-2 (listing 994439) is 16 days past due, principal balance $21.50\r\n" +
+		"note 1619-2 (listing 994439) was charged off, principal balance $21.50\r\n"
+	if string(gotMsg) != wantMsg {
+		t.Errorf("unexpected message. got: %q, want: %q", gotMsg, wantMsg)
+	}
+}
+
+func TestSMTPNotifierError(t *testing.T) {
+	mockErr := errors.New("mock SMTP error")
+	n := smtpNotifier{
+		sendMail: func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+			return mockErr
+		},
+	}
+	if err := n.Notify([]Event{lateEvent}); err != mockErr {
+		t.Errorf("unexpected error. got: %v, want: %v", err, mockErr)
+	}
+}
+
+func TestSMTPNotifierSendMessage(t *testing.T) {
+	var gotMsg []byte
+	n := smtpNotifier{
+		addr: "mail.example.com:25",
+		from: "bot@example.com",
+		to:   []string{"a@example.com"},
+		sendMail: func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+			gotMsg = msg
+			return nil
+		},
+	}
+	err := n.SendMessage(Message{
+		Subject: "Daily digest",
+		Text:    "plain body",
+		HTML:    "<p>html body</p>",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wantMsg := "From: bot@example.com\r\n" +
+		"To: a@example.com\r\n" +
+		"Subject: Daily digest\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: multipart/alternative; boundary=prosperbot-alternative-boundary\r\n" +
+		"\r\n" +
+		"--prosperbot-alternative-boundary\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n\r\n" +
+		"plain body\r\n" +
+		"--prosperbot-alternative-boundary\r\n" +
+		"Content-Type: text/html; charset=UTF-8\r\n\r\n" +
+		"<p>html body</p>\r\n" +
+		"--prosperbot-alternative-boundary--\r\n"
+	if string(gotMsg) != wantMsg {
+		t.Errorf("unexpected message. got: %q, want: %q", gotMsg, wantMsg)
+	}
+}
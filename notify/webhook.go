@@ -0,0 +1,50 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+type webhookPayload struct {
+	Events []Event
+}
+
+// NewWebhookNotifier creates a Notifier that POSTs events as JSON to url.
+func NewWebhookNotifier(url string, timeout time.Duration) Notifier {
+	return webhookNotifier{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (n webhookNotifier) Notify(events []Event) error {
+	return n.post(webhookPayload{Events: events})
+}
+
+func (n webhookNotifier) SendMessage(m Message) error {
+	return n.post(m)
+}
+
+func (n webhookNotifier) post(payload interface{}) error {
+	serialized, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	resp, err := n.client.Post(n.url, "application/json", bytes.NewReader(serialized))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned unexpected status: %s", resp.Status)
+	}
+	return nil
+}
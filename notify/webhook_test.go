@@ -0,0 +1,57 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWebhookNotifier(t *testing.T) {
+	var tests = []struct {
+		status  int
+		wantErr bool
+		msg     string
+	}{
+		{
+			status: http.StatusOK,
+			msg:    "successful response should not return an error",
+		},
+		{
+			status: http.StatusNoContent,
+			msg:    "any 2xx response should be treated as success",
+		},
+		{
+			status:  http.StatusInternalServerError,
+			wantErr: true,
+			msg:     "server errors should be returned as errors",
+		},
+	}
+	for _, tt := range tests {
+		var gotPayload webhookPayload
+		var gotContentType string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotContentType = r.Header.Get("Content-Type")
+			if err := json.NewDecoder(r.Body).Decode(&gotPayload); err != nil {
+				t.Errorf("%s: failed to decode webhook payload: %v", tt.msg, err)
+			}
+			w.WriteHeader(tt.status)
+		}))
+		n := NewWebhookNotifier(server.URL, 5*time.Second)
+		err := n.Notify([]Event{lateEvent})
+		server.Close()
+		if tt.wantErr && err == nil {
+			t.Errorf("%s: expected error, got nil", tt.msg)
+		} else if !tt.wantErr && err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.msg, err)
+		}
+		if gotContentType != "application/json" {
+			t.Errorf("%s: unexpected content type. got: %s, want: %s", tt.msg, gotContentType, "application/json")
+		}
+		if !reflect.DeepEqual(gotPayload.Events, []Event{lateEvent}) {
+			t.Errorf("%s: unexpected payload. got: %+v, want: %+v", tt.msg, gotPayload.Events, []Event{lateEvent})
+		}
+	}
+}
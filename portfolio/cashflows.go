@@ -0,0 +1,136 @@
+package portfolio
+
+import (
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/notes"
+	"github.com/mtlynch/prosperbot/redis"
+)
+
+// NoteFlows is the money that moved in and out of a single note.
+type NoteFlows struct {
+	// Note is the latest saved state of the note.
+	Note prosper.Note
+	// Flows are the dated cash flows, starting with the purchase of the note.
+	// Payments are dated when the bot first saw them, which may lag the actual
+	// payment by up to one note polling interval.
+	Flows            []CashFlow
+	Invested         float64
+	PrincipalPaid    float64
+	Interest         float64
+	LateFees         float64
+	ServiceFees      float64
+	DebtSaleProceeds float64
+	NoteSaleProceeds float64
+	// Outstanding is the principal still owed on an active note.
+	Outstanding float64
+	// PrincipalLost is the principal that will never be repaid on a charged off
+	// or defaulted note.
+	PrincipalLost float64
+	// PrincipalYears is the integral of outstanding principal over time, the
+	// denominator of an annualized return.
+	PrincipalYears float64
+	// HeldYears is how long the note was held, from origination until it was
+	// closed or until the as-of time.
+	HeldYears float64
+}
+
+// Received is the total amount received from the note, net of fees.
+func (f NoteFlows) Received() float64 {
+	return f.PrincipalPaid + f.Interest + f.LateFees - f.ServiceFees + f.DebtSaleProceeds + f.NoteSaleProceeds
+}
+
+func isClosed(n prosper.Note) bool {
+	switch n.NoteStatusDescription {
+	case notes.StatusChargeOff, notes.StatusDefaulted, notes.StatusCompleted:
+		return true
+	}
+	return n.IsSold
+}
+
+func isLost(n prosper.Note) bool {
+	return n.NoteStatusDescription == notes.StatusChargeOff || n.NoteStatusDescription == notes.StatusDefaulted
+}
+
+func noteSaleProceeds(n prosper.Note) float64 {
+	return n.NoteSaleGrossAmountReceived - n.NoteSaleFeesPaid
+}
+
+// NoteCashFlows reconstructs the cash flows of a note from its chronological
+// history, considering only records saved at or before asOf. Each amount is
+// the difference between successive snapshots of the note's running totals.
+func NoteCashFlows(history []redis.NoteRecord, asOf time.Time) NoteFlows {
+	latest, ok := redis.NoteAt(history, asOf)
+	if !ok {
+		return NoteFlows{Flows: []CashFlow{}}
+	}
+	n := latest.Note
+	f := NoteFlows{
+		Note:             n,
+		Invested:         n.NoteOwnershipAmount,
+		PrincipalPaid:    n.PrincipalPaidProRataShare,
+		Interest:         n.InterestPaidProRataShare,
+		LateFees:         n.LateFeesPaidProRataShare,
+		ServiceFees:      n.ServiceFeesPaidProRataShare,
+		DebtSaleProceeds: n.DebtSaleProceedsReceivedProRataShare,
+		NoteSaleProceeds: noteSaleProceeds(n),
+	}
+	originated := n.OriginationDate
+	if originated.IsZero() || originated.After(history[0].Timestamp) {
+		originated = history[0].Timestamp
+	}
+	f.Flows = []CashFlow{{Date: originated, Amount: -n.NoteOwnershipAmount}}
+
+	var prev prosper.Note
+	balance := n.NoteOwnershipAmount
+	balanceSince := originated
+	closedAt := asOf
+	closed := false
+	for _, r := range history {
+		if r.Timestamp.After(asOf) {
+			break
+		}
+		cur := r.Note
+		received := (cur.PrincipalPaidProRataShare - prev.PrincipalPaidProRataShare) +
+			(cur.InterestPaidProRataShare - prev.InterestPaidProRataShare) +
+			(cur.LateFeesPaidProRataShare - prev.LateFeesPaidProRataShare) -
+			(cur.ServiceFeesPaidProRataShare - prev.ServiceFeesPaidProRataShare) +
+			(cur.DebtSaleProceedsReceivedProRataShare - prev.DebtSaleProceedsReceivedProRataShare) +
+			(noteSaleProceeds(cur) - noteSaleProceeds(prev))
+		if received != 0 {
+			f.Flows = append(f.Flows, CashFlow{Date: r.Timestamp, Amount: received})
+		}
+		f.PrincipalYears += balance * yearsBetween(balanceSince, r.Timestamp)
+		balanceSince = r.Timestamp
+		balance = cur.NoteOwnershipAmount - cur.PrincipalPaidProRataShare
+		if isClosed(cur) {
+			balance = 0
+			if !closed {
+				closed = true
+				closedAt = r.Timestamp
+			}
+		}
+		prev = cur
+	}
+	f.PrincipalYears += balance * yearsBetween(balanceSince, asOf)
+	f.HeldYears = yearsBetween(originated, closedAt)
+
+	if isLost(n) {
+		f.PrincipalLost = n.NoteOwnershipAmount - n.PrincipalPaidProRataShare
+	} else if !isClosed(n) {
+		f.Outstanding = n.PrincipalBalanceProRataShare
+		// Value the remaining principal at par so that returns on active notes
+		// can be computed.
+		f.Flows = append(f.Flows, CashFlow{Date: asOf, Amount: f.Outstanding})
+	}
+	return f
+}
+
+func yearsBetween(a, b time.Time) float64 {
+	if !b.After(a) {
+		return 0
+	}
+	return b.Sub(a).Hours() / 24 / daysPerYear
+}
@@ -0,0 +1,151 @@
+package portfolio
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/redis"
+)
+
+var (
+	jan1  = time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb1  = time.Date(2016, 2, 1, 0, 0, 0, 0, time.UTC)
+	feb15 = time.Date(2016, 2, 15, 0, 0, 0, 0, time.UTC)
+	mar1  = time.Date(2016, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	activeNoteHistory = []redis.NoteRecord{
+		{
+			Note: prosper.Note{
+				LoanNoteID:                   "1619-2",
+				NoteOwnershipAmount:          100,
+				OriginationDate:              jan1,
+				PrincipalBalanceProRataShare: 100,
+				NoteStatusDescription:        "CURRENT",
+				Rating:                       prosper.RatingA,
+				Term:                         36,
+			},
+			Timestamp: jan1,
+		},
+		{
+			Note: prosper.Note{
+				LoanNoteID:                   "1619-2",
+				NoteOwnershipAmount:          100,
+				OriginationDate:              jan1,
+				PrincipalBalanceProRataShare: 98,
+				PrincipalPaidProRataShare:    2,
+				InterestPaidProRataShare:     1,
+				ServiceFeesPaidProRataShare:  0.25,
+				NoteStatusDescription:        "CURRENT",
+				Rating:                       prosper.RatingA,
+				Term:                         36,
+			},
+			Timestamp: feb1,
+		},
+	}
+	chargedOffNoteHistory = []redis.NoteRecord{
+		{
+			Note: prosper.Note{
+				LoanNoteID:                   "2000-1",
+				NoteOwnershipAmount:          50,
+				OriginationDate:              jan1,
+				PrincipalBalanceProRataShare: 50,
+				NoteStatusDescription:        "CURRENT",
+				Rating:                       prosper.RatingD,
+				Term:                         60,
+			},
+			Timestamp: jan1,
+		},
+		{
+			Note: prosper.Note{
+				LoanNoteID:                   "2000-1",
+				NoteOwnershipAmount:          50,
+				OriginationDate:              jan1,
+				PrincipalBalanceProRataShare: 50,
+				NoteStatusDescription:        "CHARGEOFF",
+				Rating:                       prosper.RatingD,
+				Term:                         60,
+			},
+			Timestamp: feb1,
+		},
+		{
+			Note: prosper.Note{
+				LoanNoteID:                           "2000-1",
+				NoteOwnershipAmount:                  50,
+				OriginationDate:                      jan1,
+				PrincipalBalanceProRataShare:         50,
+				DebtSaleProceedsReceivedProRataShare: 5,
+				NoteStatusDescription:                "CHARGEOFF",
+				Rating:                               prosper.RatingD,
+				Term:                                 60,
+			},
+			Timestamp: feb15,
+		},
+	}
+)
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestNoteCashFlowsActive(t *testing.T) {
+	got := NoteCashFlows(activeNoteHistory, mar1)
+	wantFlows := []CashFlow{
+		{Date: jan1, Amount: -100},
+		{Date: feb1, Amount: 2.75},
+		{Date: mar1, Amount: 98},
+	}
+	if !reflect.DeepEqual(got.Flows, wantFlows) {
+		t.Errorf("unexpected cash flows. got: %+v, want: %+v", got.Flows, wantFlows)
+	}
+	if got.Invested != 100 || got.Outstanding != 98 || got.PrincipalLost != 0 {
+		t.Errorf("unexpected totals. got invested: %v, outstanding: %v, lost: %v", got.Invested, got.Outstanding, got.PrincipalLost)
+	}
+	if !approxEqual(got.Received(), 2.75) {
+		t.Errorf("unexpected amount received. got: %v, want: %v", got.Received(), 2.75)
+	}
+	wantPrincipalYears := (100*31 + 98*29) / 365.0
+	if !approxEqual(got.PrincipalYears, wantPrincipalYears) {
+		t.Errorf("unexpected principal years. got: %v, want: %v", got.PrincipalYears, wantPrincipalYears)
+	}
+	if !approxEqual(got.HeldYears, 60/365.0) {
+		t.Errorf("unexpected years held. got: %v, want: %v", got.HeldYears, 60/365.0)
+	}
+}
+
+func TestNoteCashFlowsChargedOff(t *testing.T) {
+	got := NoteCashFlows(chargedOffNoteHistory, mar1)
+	wantFlows := []CashFlow{
+		{Date: jan1, Amount: -50},
+		{Date: feb15, Amount: 5},
+	}
+	if !reflect.DeepEqual(got.Flows, wantFlows) {
+		t.Errorf("unexpected cash flows. got: %+v, want: %+v", got.Flows, wantFlows)
+	}
+	if got.Outstanding != 0 || got.PrincipalLost != 50 {
+		t.Errorf("unexpected totals. got outstanding: %v, lost: %v", got.Outstanding, got.PrincipalLost)
+	}
+	if !approxEqual(got.PrincipalYears, 50*31/365.0) {
+		t.Errorf("charged off notes should have no outstanding principal after charge off. got: %v, want: %v", got.PrincipalYears, 50*31/365.0)
+	}
+	if !approxEqual(got.HeldYears, 31/365.0) {
+		t.Errorf("unexpected years held. got: %v, want: %v", got.HeldYears, 31/365.0)
+	}
+}
+
+func TestNoteCashFlowsAsOf(t *testing.T) {
+	got := NoteCashFlows(chargedOffNoteHistory, jan1.Add(time.Hour))
+	wantFlows := []CashFlow{
+		{Date: jan1, Amount: -50},
+		{Date: jan1.Add(time.Hour), Amount: 50},
+	}
+	if !reflect.DeepEqual(got.Flows, wantFlows) {
+		t.Errorf("records after the as-of time should be ignored. got: %+v, want: %+v", got.Flows, wantFlows)
+	}
+	if empty := NoteCashFlows(chargedOffNoteHistory, jan1.Add(-time.Hour)); empty.Invested != 0 {
+		t.Errorf("notes not yet seen at the as-of time should have no cash flows. got: %+v", empty)
+	}
+}
@@ -0,0 +1,114 @@
+package portfolio
+
+import (
+	"math"
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/redis"
+)
+
+// MonthlyProjection is the principal and interest expected in a calendar month.
+type MonthlyProjection struct {
+	// Month is midnight UTC on the first day of the month.
+	Month     time.Time
+	Principal float64
+	Interest  float64
+}
+
+// Total is the total cash expected in the month.
+func (p MonthlyProjection) Total() float64 {
+	return p.Principal + p.Interest
+}
+
+// DefaultRates maps a rating name (see RatingName) to the expected fraction of
+// notes with that rating that default each year.
+type DefaultRates map[string]float64
+
+// Project estimates the principal and interest that notes will pay in each of
+// the given number of calendar months, starting with the month of asOf. Each
+// note is assumed to pay its next scheduled payment every month, split between
+// interest and principal on a standard amortization schedule, until its
+// balance is repaid. Payments that were due before the month of asOf are
+// counted in the first month. If defaultRates is non-nil, each expected payment
+// is reduced by the probability that the note defaults before it is made. The
+// projection is empty if months is not positive.
+func Project(notes []prosper.Note, asOf time.Time, months int, defaultRates DefaultRates) []MonthlyProjection {
+	if months <= 0 {
+		return []MonthlyProjection{}
+	}
+	asOf = asOf.UTC()
+	first := time.Date(asOf.Year(), asOf.Month(), 1, 0, 0, 0, 0, time.UTC)
+	projections := make([]MonthlyProjection, months)
+	for i := range projections {
+		projections[i].Month = first.AddDate(0, i, 0)
+	}
+	for _, n := range notes {
+		if isClosed(n) || n.PrincipalBalanceProRataShare <= 0 {
+			continue
+		}
+		survival := 1.0
+		if rate, ok := defaultRates[RatingName(n.Rating)]; ok {
+			survival = math.Pow(1-rate, 1.0/12)
+		}
+		balance := n.PrincipalBalanceProRataShare
+		monthlyRate := n.BorrowerRate / 12
+		payment := n.NextPaymentDueAmountProRataShare
+		if payment <= 0 {
+			payment = amortizedPayment(balance, monthlyRate, int(n.Term)-int(n.AgeInMonths))
+		}
+		// Start from the month of the next due date, which may be later than the
+		// first month of the projection for newly originated notes.
+		due := n.NextPaymentDueDate.UTC()
+		if due.IsZero() {
+			due = first
+		}
+		offset := (due.Year()-first.Year())*12 + int(due.Month()) - int(first.Month())
+		probability := 1.0
+		for i := offset; i < months && balance > 0.005; i++ {
+			interest := balance * monthlyRate
+			principal := math.Min(payment-interest, balance)
+			if principal < 0 {
+				principal = 0
+			}
+			balance -= principal
+			probability *= survival
+			// Overdue payments are still owed, so expect them in the first month.
+			month := i
+			if month < 0 {
+				month = 0
+			}
+			projections[month].Principal += principal * probability
+			projections[month].Interest += interest * probability
+		}
+	}
+	return projections
+}
+
+// amortizedPayment is the fixed monthly payment that repays balance over the
+// given number of months.
+func amortizedPayment(balance, monthlyRate float64, months int) float64 {
+	if months <= 0 {
+		return balance * (1 + monthlyRate)
+	}
+	if monthlyRate == 0 {
+		return balance / float64(months)
+	}
+	return balance * monthlyRate / (1 - math.Pow(1+monthlyRate, -float64(months)))
+}
+
+// LoadProjection projects cash flows from the latest saved state of every note.
+func LoadProjection(r redis.RedisReader, asOf time.Time, months int, defaultRates DefaultRates) ([]MonthlyProjection, error) {
+	histories, err := redis.AllNoteHistories(r)
+	if err != nil {
+		return []MonthlyProjection{}, err
+	}
+	notes := []prosper.Note{}
+	for _, h := range histories {
+		if latest, ok := redis.NoteAt(h, asOf); ok {
+			notes = append(notes, latest.Note)
+		}
+	}
+	return Project(notes, asOf, months, defaultRates), nil
+}
@@ -0,0 +1,114 @@
+package portfolio
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+)
+
+var (
+	projectionStart = time.Date(2016, 5, 10, 0, 0, 0, 0, time.UTC)
+	projectedNote   = prosper.Note{
+		LoanNoteID:                   "1619-2",
+		PrincipalBalanceProRataShare: 1000,
+		BorrowerRate:                 0.12,
+		Term:                         12,
+		NextPaymentDueDate:           time.Date(2016, 5, 15, 0, 0, 0, 0, time.UTC),
+		NoteStatusDescription:        "CURRENT",
+		Rating:                       prosper.RatingC,
+	}
+)
+
+func TestProject(t *testing.T) {
+	got := Project([]prosper.Note{projectedNote}, projectionStart, 14, nil)
+	if len(got) != 14 {
+		t.Fatalf("unexpected number of months. got: %d, want: %d", len(got), 14)
+	}
+	if want := time.Date(2016, 5, 1, 0, 0, 0, 0, time.UTC); !got[0].Month.Equal(want) {
+		t.Errorf("unexpected first month. got: %v, want: %v", got[0].Month, want)
+	}
+	if want := time.Date(2017, 6, 1, 0, 0, 0, 0, time.UTC); !got[13].Month.Equal(want) {
+		t.Errorf("unexpected last month. got: %v, want: %v", got[13].Month, want)
+	}
+	payment := amortizedPayment(1000, 0.01, 12)
+	if !approxEqual(got[0].Interest, 10) || !approxEqual(got[0].Principal, payment-10) {
+		t.Errorf("unexpected first payment. got: %+v, want interest: 10, principal: %v", got[0], payment-10)
+	}
+	principal := 0.0
+	for i, p := range got {
+		principal += p.Principal
+		if i < 12 && !approxEqual(p.Total(), payment) {
+			t.Errorf("unexpected payment in month %d. got: %v, want: %v", i, p.Total(), payment)
+		}
+		if i >= 12 && p.Total() > 0.01 {
+			t.Errorf("expected no payments after the note is repaid, got %v in month %d", p.Total(), i)
+		}
+	}
+	if math.Abs(principal-1000) > 0.01 {
+		t.Errorf("expected all principal to be repaid. got: %v, want: %v", principal, 1000)
+	}
+}
+
+func TestProjectScheduledPayment(t *testing.T) {
+	n := projectedNote
+	n.NextPaymentDueAmountProRataShare = 50
+	got := Project([]prosper.Note{n}, projectionStart, 1, nil)
+	if !approxEqual(got[0].Interest, 10) || !approxEqual(got[0].Principal, 40) {
+		t.Errorf("expected the scheduled payment to be used. got: %+v", got[0])
+	}
+}
+
+func TestProjectDefaultRates(t *testing.T) {
+	got := Project([]prosper.Note{projectedNote}, projectionStart, 2, DefaultRates{"C": 0.12})
+	unadjusted := Project([]prosper.Note{projectedNote}, projectionStart, 2, nil)
+	survival := math.Pow(0.88, 1.0/12)
+	for i := range got {
+		want := unadjusted[i].Total() * math.Pow(survival, float64(i+1))
+		if !approxEqual(got[i].Total(), want) {
+			t.Errorf("unexpected adjusted total in month %d. got: %v, want: %v", i, got[i].Total(), want)
+		}
+	}
+	other := Project([]prosper.Note{projectedNote}, projectionStart, 2, DefaultRates{"AA": 0.5})
+	if !approxEqual(other[0].Total(), unadjusted[0].Total()) {
+		t.Errorf("default rates for other ratings should not apply. got: %v, want: %v", other[0].Total(), unadjusted[0].Total())
+	}
+}
+
+func TestProjectSkipsClosedNotes(t *testing.T) {
+	n := projectedNote
+	n.NoteStatusDescription = "CHARGEOFF"
+	got := Project([]prosper.Note{n}, projectionStart, 1, nil)
+	if got[0].Total() != 0 {
+		t.Errorf("expected no payments from charged off notes, got: %+v", got[0])
+	}
+}
+
+func TestProjectFutureFirstPayment(t *testing.T) {
+	n := projectedNote
+	n.NextPaymentDueDate = time.Date(2016, 7, 1, 0, 0, 0, 0, time.UTC)
+	got := Project([]prosper.Note{n}, projectionStart, 3, nil)
+	if got[0].Total() != 0 || got[1].Total() != 0 || got[2].Total() == 0 {
+		t.Errorf("expected payments to start in the month of the next due date, got: %+v", got)
+	}
+}
+
+func TestProjectOverduePayments(t *testing.T) {
+	n := projectedNote
+	n.NextPaymentDueDate = time.Date(2016, 3, 1, 0, 0, 0, 0, time.UTC)
+	got := Project([]prosper.Note{n}, projectionStart, 1, nil)
+	current := Project([]prosper.Note{projectedNote}, projectionStart, 3, nil)
+	want := current[0].Total() + current[1].Total() + current[2].Total()
+	if !approxEqual(got[0].Total(), want) {
+		t.Errorf("expected overdue payments in the first month. got: %v, want: %v", got[0].Total(), want)
+	}
+}
+
+func TestProjectNoMonths(t *testing.T) {
+	for _, months := range []int{0, -1} {
+		if got := Project([]prosper.Note{projectedNote}, projectionStart, months, nil); len(got) != 0 {
+			t.Errorf("expected an empty projection for %d months, got: %+v", months, got)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package portfolio
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/redis"
+)
+
+// Returns summarizes the performance of a group of notes.
+type Returns struct {
+	Notes            int
+	Invested         float64
+	Received         float64
+	Interest         float64
+	LateFees         float64
+	ServiceFees      float64
+	DebtSaleProceeds float64
+	Outstanding      float64
+	PrincipalLost    float64
+	// XIRR is the internal rate of return of the group's cash flows, with
+	// outstanding principal valued at par. It is only meaningful if HasXIRR is
+	// true.
+	XIRR    float64
+	HasXIRR bool
+	// AnnualizedNetReturn is the total return, counting outstanding principal
+	// at par, compounded annually over the investment-weighted holding period.
+	AnnualizedNetReturn float64
+	// NetAnnualizedReturn is Prosper's measure: interest, late fees and debt
+	// sale proceeds, less service fees and principal lost, divided by the
+	// average principal outstanding per year.
+	NetAnnualizedReturn float64
+
+	flows          []CashFlow
+	principalYears float64
+	investedYears  float64
+}
+
+func (r *Returns) add(f NoteFlows) {
+	r.Notes++
+	r.Invested += f.Invested
+	r.Received += f.Received()
+	r.Interest += f.Interest
+	r.LateFees += f.LateFees
+	r.ServiceFees += f.ServiceFees
+	r.DebtSaleProceeds += f.DebtSaleProceeds
+	r.Outstanding += f.Outstanding
+	r.PrincipalLost += f.PrincipalLost
+	r.flows = append(r.flows, f.Flows...)
+	r.principalYears += f.PrincipalYears
+	r.investedYears += f.Invested * f.HeldYears
+}
+
+func (r *Returns) finish() {
+	if rate, err := XIRR(r.flows); err == nil {
+		r.XIRR = rate
+		r.HasXIRR = true
+	}
+	if r.principalYears > 0 {
+		r.NetAnnualizedReturn = (r.Interest + r.LateFees + r.DebtSaleProceeds - r.ServiceFees - r.PrincipalLost) / r.principalYears
+	}
+	if r.Invested > 0 && r.investedYears > 0 {
+		totalReturn := (r.Received + r.Outstanding) / r.Invested
+		years := r.investedYears / r.Invested
+		r.AnnualizedNetReturn = math.Pow(totalReturn, 1/years) - 1
+	}
+}
+
+// Report is portfolio performance overall and broken down by note attributes.
+type Report struct {
+	AsOf      time.Time
+	Overall   Returns
+	ByRating  map[string]Returns
+	ByTerm    map[string]Returns
+	ByVintage map[string]Returns
+}
+
+// Calculate computes portfolio returns as of a point in time from the
+// histories of every note.
+func Calculate(histories map[string][]redis.NoteRecord, asOf time.Time) Report {
+	report := Report{
+		AsOf:      asOf,
+		ByRating:  map[string]Returns{},
+		ByTerm:    map[string]Returns{},
+		ByVintage: map[string]Returns{},
+	}
+	addTo := func(buckets map[string]Returns, key string, f NoteFlows) {
+		r := buckets[key]
+		r.add(f)
+		buckets[key] = r
+	}
+	// Iterate in a fixed order so that floating point sums are reproducible.
+	ids := []string{}
+	for id := range histories {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		f := NoteCashFlows(histories[id], asOf)
+		if f.Invested == 0 {
+			continue
+		}
+		report.Overall.add(f)
+		addTo(report.ByRating, RatingName(f.Note.Rating), f)
+		addTo(report.ByTerm, strconv.Itoa(int(f.Note.Term)), f)
+		addTo(report.ByVintage, Vintage(f.Flows[0].Date), f)
+	}
+	report.Overall.finish()
+	for _, buckets := range []map[string]Returns{report.ByRating, report.ByTerm, report.ByVintage} {
+		for k, r := range buckets {
+			r.finish()
+			buckets[k] = r
+		}
+	}
+	return report
+}
+
+// Load computes portfolio returns as of a point in time from saved history.
+func Load(r redis.RedisReader, asOf time.Time) (Report, error) {
+	histories, err := redis.AllNoteHistories(r)
+	if err != nil {
+		return Report{}, err
+	}
+	return Calculate(histories, asOf), nil
+}
+
+var ratingNames = map[prosper.Rating]string{
+	prosper.RatingAA: "AA",
+	prosper.RatingA:  "A",
+	prosper.RatingB:  "B",
+	prosper.RatingC:  "C",
+	prosper.RatingD:  "D",
+	prosper.RatingE:  "E",
+}
+
+// RatingName returns the letter grade for a Prosper rating.
+func RatingName(r prosper.Rating) string {
+	if name, ok := ratingNames[r]; ok {
+		return name
+	}
+	return fmt.Sprintf("%v", r)
+}
+
+// Vintage returns the calendar quarter in which a note originated, such as
+// "2016-Q2".
+func Vintage(t time.Time) string {
+	return fmt.Sprintf("%d-Q%d", t.Year(), (int(t.Month())-1)/3+1)
+}
@@ -0,0 +1,97 @@
+package portfolio
+
+import (
+	"math"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/redis"
+)
+
+func TestCalculate(t *testing.T) {
+	histories := map[string][]redis.NoteRecord{
+		"1619-2": activeNoteHistory,
+		"2000-1": chargedOffNoteHistory,
+	}
+	got := Calculate(histories, mar1)
+	if got.Overall.Notes != 2 {
+		t.Errorf("unexpected note count. got: %d, want: %d", got.Overall.Notes, 2)
+	}
+	if got.Overall.Invested != 150 {
+		t.Errorf("unexpected amount invested. got: %v, want: %v", got.Overall.Invested, 150)
+	}
+	if !approxEqual(got.Overall.Received, 7.75) {
+		t.Errorf("unexpected amount received. got: %v, want: %v", got.Overall.Received, 7.75)
+	}
+	principalYears := (100*31+98*29)/365.0 + 50*31/365.0
+	wantNAR := (1 + 5 - 0.25 - 50) / principalYears
+	if !approxEqual(got.Overall.NetAnnualizedReturn, wantNAR) {
+		t.Errorf("unexpected net annualized return. got: %v, want: %v", got.Overall.NetAnnualizedReturn, wantNAR)
+	}
+	if !got.Overall.HasXIRR || got.Overall.XIRR >= 0 {
+		t.Errorf("expected a negative XIRR for a portfolio with a large charge off. got: %v (valid: %v)", got.Overall.XIRR, got.Overall.HasXIRR)
+	}
+	years := (100*60/365.0 + 50*31/365.0) / 150
+	wantANR := math.Pow((7.75+98)/150, 1/years) - 1
+	if !approxEqual(got.Overall.AnnualizedNetReturn, wantANR) {
+		t.Errorf("unexpected annualized net return. got: %v, want: %v", got.Overall.AnnualizedNetReturn, wantANR)
+	}
+
+	for _, tt := range []struct {
+		buckets map[string]Returns
+		want    []string
+	}{
+		{got.ByRating, []string{"A", "D"}},
+		{got.ByTerm, []string{"36", "60"}},
+		{got.ByVintage, []string{"2016-Q1"}},
+	} {
+		keys := []string{}
+		for k := range tt.buckets {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		if !reflect.DeepEqual(keys, tt.want) {
+			t.Errorf("unexpected buckets. got: %v, want: %v", keys, tt.want)
+		}
+	}
+	if got.ByRating["D"].PrincipalLost != 50 || got.ByRating["A"].PrincipalLost != 0 {
+		t.Errorf("unexpected principal lost by rating. got: %+v", got.ByRating)
+	}
+	if got.ByVintage["2016-Q1"].Notes != 2 {
+		t.Errorf("unexpected note count for vintage. got: %d, want: %d", got.ByVintage["2016-Q1"].Notes, 2)
+	}
+}
+
+func TestCalculateEmpty(t *testing.T) {
+	got := Calculate(map[string][]redis.NoteRecord{}, mar1)
+	if got.Overall.Notes != 0 || got.Overall.HasXIRR {
+		t.Errorf("unexpected returns for empty portfolio: %+v", got.Overall)
+	}
+}
+
+func TestRatingName(t *testing.T) {
+	if got := RatingName(prosper.RatingAA); got != "AA" {
+		t.Errorf("unexpected rating name. got: %s, want: %s", got, "AA")
+	}
+}
+
+func TestVintage(t *testing.T) {
+	var tests = []struct {
+		t    time.Time
+		want string
+	}{
+		{time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC), "2016-Q1"},
+		{time.Date(2016, 3, 31, 0, 0, 0, 0, time.UTC), "2016-Q1"},
+		{time.Date(2016, 4, 1, 0, 0, 0, 0, time.UTC), "2016-Q2"},
+		{time.Date(2016, 12, 31, 0, 0, 0, 0, time.UTC), "2016-Q4"},
+	}
+	for _, tt := range tests {
+		if got := Vintage(tt.t); got != tt.want {
+			t.Errorf("unexpected vintage for %v. got: %s, want: %s", tt.t, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package portfolio
+
+import (
+	"sort"
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/redis"
+)
+
+// Snapshot is the portfolio as the bot last saw it at a point in time.
+type Snapshot struct {
+	AsOf time.Time
+	// Account is the latest account information saved at or before AsOf. It
+	// is the zero value if HasAccount is false.
+	Account    prosper.AccountInformation
+	HasAccount bool
+	// Notes holds the state of every note saved at or before AsOf, ordered by
+	// note ID.
+	Notes []prosper.Note
+}
+
+// OpenNotes returns the notes that were still active at the snapshot time.
+func (s Snapshot) OpenNotes() []prosper.Note {
+	open := []prosper.Note{}
+	for _, n := range s.Notes {
+		if !isClosed(n) {
+			open = append(open, n)
+		}
+	}
+	return open
+}
+
+// LateFraction returns the fraction of open notes that were past due, or zero
+// if there were no open notes.
+func (s Snapshot) LateFraction() float64 {
+	open := s.OpenNotes()
+	if len(open) == 0 {
+		return 0
+	}
+	late := 0
+	for _, n := range open {
+		if n.DaysPastDue > 0 {
+			late++
+		}
+	}
+	return float64(late) / float64(len(open))
+}
+
+// StatusCounts returns the number of notes in each status.
+func (s Snapshot) StatusCounts() map[string]int {
+	counts := map[string]int{}
+	for _, n := range s.Notes {
+		counts[n.NoteStatusDescription]++
+	}
+	return counts
+}
+
+// SnapshotAt reconstructs the portfolio at t from chronological account and
+// note histories.
+func SnapshotAt(accounts []redis.AccountRecord, histories map[string][]redis.NoteRecord, t time.Time) Snapshot {
+	s := Snapshot{AsOf: t, Notes: []prosper.Note{}}
+	if a, ok := redis.AccountAt(accounts, t); ok {
+		s.Account = a.Value
+		s.HasAccount = true
+	}
+	ids := []string{}
+	for id := range histories {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if n, ok := redis.NoteAt(histories[id], t); ok {
+			s.Notes = append(s.Notes, n.Note)
+		}
+	}
+	return s
+}
+
+// LoadSnapshot reconstructs the portfolio at t from the saved history.
+func LoadSnapshot(r redis.RedisReader, t time.Time) (Snapshot, error) {
+	accounts, err := redis.AccountHistory(r)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	histories, err := redis.AllNoteHistories(r)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	return SnapshotAt(accounts, histories, t), nil
+}
@@ -0,0 +1,65 @@
+package portfolio
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/redis"
+)
+
+func TestSnapshotAt(t *testing.T) {
+	lateNoteHistory := []redis.NoteRecord{
+		{
+			Note:      prosper.Note{LoanNoteID: "3000-1", NoteStatusDescription: "CURRENT"},
+			Timestamp: jan1,
+		},
+		{
+			Note:      prosper.Note{LoanNoteID: "3000-1", NoteStatusDescription: "CURRENT", DaysPastDue: 20},
+			Timestamp: feb15,
+		},
+	}
+	histories := map[string][]redis.NoteRecord{
+		"1619-2": activeNoteHistory,
+		"2000-1": chargedOffNoteHistory,
+		"3000-1": lateNoteHistory,
+	}
+	accounts := []redis.AccountRecord{
+		{Value: prosper.AccountInformation{TotalAccountValue: 1000}, Timestamp: jan1},
+		{Value: prosper.AccountInformation{TotalAccountValue: 950}, Timestamp: feb15},
+	}
+
+	var tests = []struct {
+		t              time.Time
+		wantAccount    float64
+		wantNoteIDs    []string
+		wantLate       float64
+		wantChargeOffs int
+		msg            string
+	}{
+		{jan1.Add(-time.Hour), 0, []string{}, 0, 0, "nothing should exist before history starts"},
+		{jan1.Add(time.Hour), 1000, []string{"1619-2", "2000-1", "3000-1"}, 0, 0, "all notes should be current at start"},
+		{mar1, 950, []string{"1619-2", "2000-1", "3000-1"}, 0.5, 1, "late fraction should cover only open notes"},
+	}
+	for _, tt := range tests {
+		s := SnapshotAt(accounts, histories, tt.t)
+		if s.Account.TotalAccountValue != tt.wantAccount {
+			t.Errorf("%s: unexpected account value. got: %v, want: %v", tt.msg, s.Account.TotalAccountValue, tt.wantAccount)
+		}
+		ids := []string{}
+		for _, n := range s.Notes {
+			ids = append(ids, n.LoanNoteID)
+		}
+		if !reflect.DeepEqual(ids, tt.wantNoteIDs) {
+			t.Errorf("%s: unexpected notes. got: %v, want: %v", tt.msg, ids, tt.wantNoteIDs)
+		}
+		if got := s.LateFraction(); !approxEqual(got, tt.wantLate) {
+			t.Errorf("%s: unexpected late fraction. got: %v, want: %v", tt.msg, got, tt.wantLate)
+		}
+		if got := s.StatusCounts()["CHARGEOFF"]; got != tt.wantChargeOffs {
+			t.Errorf("%s: unexpected charge-off count. got: %d, want: %d", tt.msg, got, tt.wantChargeOffs)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package portfolio
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// CashFlow is money moving into (positive) or out of (negative) the portfolio.
+type CashFlow struct {
+	Date   time.Time
+	Amount float64
+}
+
+var ErrNoSolution = errors.New("cash flows have no internal rate of return")
+
+const (
+	daysPerYear    = 365.0
+	xirrTolerance  = 1e-9
+	xirrIterations = 100
+	// The lowest and highest annual rates considered when bracketing the
+	// solution.
+	xirrMinRate = -0.9999
+	xirrMaxRate = 100.0
+)
+
+// XIRR returns the annualized internal rate of return of irregularly spaced
+// cash flows, as computed by the spreadsheet function of the same name.
+func XIRR(flows []CashFlow) (float64, error) {
+	if len(flows) == 0 {
+		return 0, ErrNoSolution
+	}
+	hasPositive, hasNegative := false, false
+	start := flows[0].Date
+	for _, f := range flows {
+		if f.Amount > 0 {
+			hasPositive = true
+		} else if f.Amount < 0 {
+			hasNegative = true
+		}
+		if f.Date.Before(start) {
+			start = f.Date
+		}
+	}
+	if !hasPositive || !hasNegative {
+		return 0, ErrNoSolution
+	}
+	npv := func(rate float64) float64 {
+		total := 0.0
+		for _, f := range flows {
+			years := f.Date.Sub(start).Hours() / 24 / daysPerYear
+			total += f.Amount / math.Pow(1+rate, years)
+		}
+		return total
+	}
+	lo, hi := xirrMinRate, xirrMaxRate
+	fLo, fHi := npv(lo), npv(hi)
+	if (fLo > 0) == (fHi > 0) {
+		return 0, ErrNoSolution
+	}
+	// NPV is monotonic in the rate for a conventional investment, so bisection
+	// always converges. It is slower than Newton's method, but immune to the
+	// overshooting that Newton's method suffers near -100%.
+	for i := 0; i < xirrIterations; i++ {
+		mid := (lo + hi) / 2
+		fMid := npv(mid)
+		if math.Abs(fMid) < xirrTolerance || (hi-lo)/2 < xirrTolerance {
+			return mid, nil
+		}
+		if (fMid > 0) == (fLo > 0) {
+			lo, fLo = mid, fMid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2, nil
+}
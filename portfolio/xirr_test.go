@@ -0,0 +1,67 @@
+package portfolio
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestXIRR(t *testing.T) {
+	var tests = []struct {
+		flows   []CashFlow
+		want    float64
+		wantErr error
+		msg     string
+	}{
+		{
+			flows: []CashFlow{
+				{time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC), -1000},
+				{time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC), 1100},
+			},
+			want: 0.1,
+			msg:  "single investment returned one year later with 10% gain",
+		},
+		{
+			// Matches the example in the spreadsheet documentation for XIRR.
+			flows: []CashFlow{
+				{time.Date(2008, 1, 1, 0, 0, 0, 0, time.UTC), -10000},
+				{time.Date(2008, 3, 1, 0, 0, 0, 0, time.UTC), 2750},
+				{time.Date(2008, 10, 30, 0, 0, 0, 0, time.UTC), 4250},
+				{time.Date(2009, 2, 15, 0, 0, 0, 0, time.UTC), 3250},
+				{time.Date(2009, 4, 1, 0, 0, 0, 0, time.UTC), 2750},
+			},
+			want: 0.373362535,
+			msg:  "irregular cash flows",
+		},
+		{
+			flows: []CashFlow{
+				{time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC), -1000},
+				{time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC), 500},
+			},
+			want: -0.5,
+			msg:  "losses should give a negative rate",
+		},
+		{
+			flows: []CashFlow{
+				{time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC), -1000},
+			},
+			wantErr: ErrNoSolution,
+			msg:     "investments with no returns have no solution",
+		},
+		{
+			flows:   []CashFlow{},
+			wantErr: ErrNoSolution,
+			msg:     "no cash flows have no solution",
+		},
+	}
+	for _, tt := range tests {
+		got, err := XIRR(tt.flows)
+		if err != tt.wantErr {
+			t.Errorf("%s: unexpected error. got: %v, want: %v", tt.msg, err, tt.wantErr)
+			continue
+		}
+		if err == nil && math.Abs(got-tt.want) > 1e-6 {
+			t.Errorf("%s: unexpected rate. got: %v, want: %v", tt.msg, got, tt.want)
+		}
+	}
+}
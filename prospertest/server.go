@@ -0,0 +1,448 @@
+// Package prospertest provides a fake Prosper API server so that the whole bot
+// can run in tests without network access.
+//
+// The server speaks the Prosper REST API under /v1: the OAuth token endpoint,
+// listing search, bid placement, order status, account information and notes.
+// Listings, notes and account information are plain JSON objects in the form
+// the real API returns them, so scenarios can include any field the bot reads.
+package prospertest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// APIHost is the host of the real Prosper API, which Transport redirects
+	// to the fake server.
+	APIHost = "api.prosper.com"
+
+	BidSuccessful = "BID_SUCCESSFUL"
+	BidFailed     = "BID_FAILED"
+)
+
+// Object is a JSON object in the form the Prosper API sends it.
+type Object map[string]interface{}
+
+// Scenario scripts what the fake server returns.
+type Scenario struct {
+	// Listings are the active listings that searches run against.
+	Listings []Object
+	// Account is returned by the account endpoint.
+	Account Object
+	// Notes are the notes the account already owns.
+	Notes []Object
+	// BidResult is the outcome of every bid. Defaults to BidSuccessful.
+	BidResult string
+	// PollsUntilComplete is how many order status queries report an order as
+	// in progress before it completes.
+	PollsUntilComplete int
+	// IssueNotes adds a note for each successful bid when its order completes.
+	IssueNotes bool
+}
+
+type order struct {
+	id       string
+	date     time.Time
+	bids     []bid
+	polls    int
+	complete bool
+}
+
+type bid struct {
+	ListingID float64 `json:"listing_id"`
+	BidAmount float64 `json:"bid_amount"`
+}
+
+// Server is a fake Prosper API server.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	scenario Scenario
+	tokens   map[string]bool
+	orders   map[string]*order
+	requests []string
+}
+
+// NewServer starts a fake Prosper API server that plays scenario s. Callers
+// must Close it when done.
+func NewServer(s Scenario) *Server {
+	if s.BidResult == "" {
+		s.BidResult = BidSuccessful
+	}
+	if s.Account == nil {
+		s.Account = Object{}
+	}
+	server := &Server{
+		scenario: s,
+		tokens:   map[string]bool{},
+		orders:   map[string]*order{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/security/oauth/token", server.handleToken)
+	mux.HandleFunc("/v1/search/listings/", server.authorized(server.handleListings))
+	mux.HandleFunc("/v1/bids/", server.authorized(server.handleBids))
+	mux.HandleFunc("/v1/orders/", server.authorized(server.handleOrder))
+	mux.HandleFunc("/v1/accounts/prosper/", server.authorized(server.handleAccount))
+	mux.HandleFunc("/v1/notes/", server.authorized(server.handleNotes))
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.mu.Lock()
+		server.requests = append(server.requests, r.Method+" "+r.URL.Path)
+		server.mu.Unlock()
+		mux.ServeHTTP(w, r)
+	}))
+	return server
+}
+
+// AddListing makes a new listing visible to searches.
+func (s *Server) AddListing(l Object) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scenario.Listings = append(s.scenario.Listings, l)
+}
+
+// SetAccount replaces the account information.
+func (s *Server) SetAccount(a Object) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scenario.Account = a
+}
+
+// AddNote adds a note to the account, such as one bought outside the bot.
+func (s *Server) AddNote(n Object) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scenario.Notes = append(s.scenario.Notes, n)
+}
+
+// Requests returns every request received so far, as "METHOD /path".
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.requests...)
+}
+
+// Transport returns a RoundTripper that sends requests for the real Prosper
+// API to the fake server and all other requests to base.
+func (s *Server) Transport(base http.RoundTripper) http.RoundTripper {
+	return redirectTransport{base: base, target: s.Listener.Addr()}
+}
+
+// InstallDefaultTransport points http.DefaultTransport at the fake server, so
+// that an unmodified Prosper client talks to it. The returned function
+// restores the previous transport.
+func (s *Server) InstallDefaultTransport() func() {
+	previous := http.DefaultTransport
+	http.DefaultTransport = s.Transport(previous)
+	return func() { http.DefaultTransport = previous }
+}
+
+type redirectTransport struct {
+	base   http.RoundTripper
+	target net.Addr
+}
+
+func (t redirectTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.URL.Host != APIHost {
+		return t.base.RoundTrip(r)
+	}
+	redirected := new(http.Request)
+	*redirected = *r
+	u := *r.URL
+	u.Scheme = "http"
+	u.Host = t.target.String()
+	redirected.URL = &u
+	redirected.Host = u.Host
+	return t.base.RoundTrip(redirected)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, Object{"code": code, "message": message})
+}
+
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", r.Method)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", err.Error())
+		return
+	}
+	switch r.Form.Get("grant_type") {
+	case "password", "refresh_token":
+	default:
+		writeError(w, http.StatusBadRequest, "UNSUPPORTED_GRANT_TYPE", r.Form.Get("grant_type"))
+		return
+	}
+	if r.Form.Get("client_id") == "" {
+		writeError(w, http.StatusUnauthorized, "INVALID_CLIENT", "missing client_id")
+		return
+	}
+	s.mu.Lock()
+	token := fmt.Sprintf("fake-token-%d", len(s.tokens)+1)
+	s.tokens[token] = true
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, Object{
+		"access_token":  token,
+		"token_type":    "bearer",
+		"refresh_token": "fake-refresh-" + token,
+		"expires_in":    3599,
+	})
+}
+
+// authorized rejects requests that lack a token issued by the server.
+func (s *Server) authorized(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		fields := strings.Fields(r.Header.Get("Authorization"))
+		s.mu.Lock()
+		ok := len(fields) == 2 && strings.EqualFold(fields[0], "bearer") && s.tokens[fields[1]]
+		s.mu.Unlock()
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "missing or unknown access token")
+			return
+		}
+		h(w, r)
+	}
+}
+
+// nonFilterParams are search parameters that don't filter listings.
+var nonFilterParams = map[string]bool{
+	"limit":                        true,
+	"offset":                       true,
+	"sort_by":                      true,
+	"include_credit_bureau_values": true,
+	"exclude_listings_invested":    true,
+}
+
+// matchesSearch applies Prosper's search filter semantics: a parameter ending
+// in _min or _max bounds a field, and any other parameter is a comma separated
+// list of values that the field must equal.
+func matchesSearch(l Object, q url.Values) bool {
+	for param, values := range q {
+		if nonFilterParams[param] || len(values) == 0 {
+			continue
+		}
+		if strings.HasSuffix(param, "_min") || strings.HasSuffix(param, "_max") {
+			field, ok := l[param[:len(param)-4]]
+			if !ok {
+				return false
+			}
+			cmp := compare(field, values[0])
+			if strings.HasSuffix(param, "_min") && cmp < 0 {
+				return false
+			}
+			if strings.HasSuffix(param, "_max") && cmp > 0 {
+				return false
+			}
+			continue
+		}
+		field, ok := l[param]
+		if !ok {
+			return false
+		}
+		matched := false
+		for _, allowed := range strings.Split(values[0], ",") {
+			if fmt.Sprint(field) == allowed {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// compare orders a field against a bound, numerically if both are numbers and
+// otherwise as strings, which orders dates in the API's formats correctly.
+func compare(field interface{}, bound string) int {
+	if v, ok := toFloat(field); ok {
+		if b, err := strconv.ParseFloat(bound, 64); err == nil {
+			switch {
+			case v < b:
+				return -1
+			case v > b:
+				return 1
+			}
+			return 0
+		}
+	}
+	return strings.Compare(fmt.Sprint(field), bound)
+}
+
+// toFloat converts a numeric field, whether it was decoded from JSON or set by
+// a scenario as a Go int.
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// page returns the objects selected by the limit and offset parameters.
+func page(objects []Object, q url.Values) []Object {
+	offset, _ := strconv.Atoi(q.Get("offset"))
+	if offset > len(objects) {
+		offset = len(objects)
+	}
+	end := len(objects)
+	if limit, err := strconv.Atoi(q.Get("limit")); err == nil && offset+limit < end {
+		end = offset + limit
+	}
+	return objects[offset:end]
+}
+
+func (s *Server) handleListings(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	s.mu.Lock()
+	matching := []Object{}
+	for _, l := range s.scenario.Listings {
+		if matchesSearch(l, q) {
+			matching = append(matching, l)
+		}
+	}
+	s.mu.Unlock()
+	result := page(matching, q)
+	writeJSON(w, http.StatusOK, Object{
+		"result":       result,
+		"result_count": len(result),
+		"total_count":  len(matching),
+	})
+}
+
+func (s *Server) handleBids(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", r.Method)
+		return
+	}
+	var request struct {
+		BidRequests []bid `json:"bid_requests"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || len(request.BidRequests) == 0 {
+		writeError(w, http.StatusBadRequest, "INVALID_BID_REQUEST", "expected at least one bid request")
+		return
+	}
+	s.mu.Lock()
+	o := &order{
+		id:   fmt.Sprintf("fake-order-%d", len(s.orders)+1),
+		date: time.Now().UTC(),
+		bids: request.BidRequests,
+	}
+	s.orders[o.id] = o
+	response := s.orderJSON(o)
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) handleOrder(w http.ResponseWriter, r *http.Request) {
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/orders/"), "/")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o, ok := s.orders[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "ORDER_NOT_FOUND", id)
+		return
+	}
+	if !o.complete {
+		o.polls++
+		if o.polls > s.scenario.PollsUntilComplete {
+			s.completeOrder(o)
+		}
+	}
+	writeJSON(w, http.StatusOK, s.orderJSON(o))
+}
+
+// completeOrder settles an order's bids. The caller must hold s.mu.
+func (s *Server) completeOrder(o *order) {
+	o.complete = true
+	if !s.scenario.IssueNotes || s.scenario.BidResult != BidSuccessful {
+		return
+	}
+	for _, b := range o.bids {
+		s.scenario.Notes = append(s.scenario.Notes, Object{
+			"loan_note_id":                     fmt.Sprintf("%.0f-%d", b.ListingID, len(s.scenario.Notes)+1),
+			"listing_number":                   b.ListingID,
+			"note_ownership_amount":            b.BidAmount,
+			"principal_balance_pro_rata_share": b.BidAmount,
+			"note_status":                      1,
+			"note_status_description":          "CURRENT",
+			"origination_date":                 o.date.Format("2006-01-02"),
+		})
+	}
+}
+
+// orderJSON renders an order as the bid and order endpoints return it. The
+// caller must hold s.mu.
+func (s *Server) orderJSON(o *order) Object {
+	bids := []Object{}
+	for _, b := range o.bids {
+		placed := Object{
+			"listing_id": b.ListingID,
+			"bid_amount": b.BidAmount,
+			"bid_status": "PENDING",
+		}
+		if o.complete {
+			placed["bid_result"] = s.scenario.BidResult
+			if s.scenario.BidResult == BidSuccessful {
+				placed["bid_status"] = "INVESTED"
+				placed["bid_amount_placed"] = b.BidAmount
+			} else {
+				placed["bid_status"] = "EXPIRED"
+				placed["bid_amount_placed"] = 0
+			}
+		}
+		bids = append(bids, placed)
+	}
+	status := "IN_PROGRESS"
+	if o.complete {
+		status = "COMPLETED"
+	}
+	return Object{
+		"order_id":     o.id,
+		"bid_requests": bids,
+		"order_status": status,
+		"source":       "API",
+		"order_date":   o.date.Format(time.RFC3339),
+	}
+}
+
+func (s *Server) handleAccount(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, http.StatusOK, s.scenario.Account)
+}
+
+func (s *Server) handleNotes(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	s.mu.Lock()
+	notes := append([]Object{}, s.scenario.Notes...)
+	s.mu.Unlock()
+	result := page(notes, q)
+	writeJSON(w, http.StatusOK, Object{
+		"result":       result,
+		"result_count": len(result),
+		"total_count":  len(notes),
+	})
+}
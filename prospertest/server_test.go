@@ -0,0 +1,188 @@
+package prospertest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// client talks to a fake server the way the Prosper client does: through the
+// real API host, redirected by the server's transport.
+type client struct {
+	t     *testing.T
+	http  *http.Client
+	token string
+}
+
+func newClient(t *testing.T, s *Server) *client {
+	c := &client{t: t, http: &http.Client{Transport: s.Transport(http.DefaultTransport)}}
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	resp, err := c.http.PostForm("https://"+APIHost+"/v1/security/oauth/token", url.Values{
+		"grant_type":    {"password"},
+		"client_id":     {"mock-client-id"},
+		"client_secret": {"mock-client-secret"},
+		"username":      {"mock-user"},
+		"password":      {"mock-password"},
+	})
+	if err != nil {
+		t.Fatalf("failed to get token: %v", err)
+	}
+	defer resp.Body.Close()
+	if err = json.NewDecoder(resp.Body).Decode(&token); err != nil || token.AccessToken == "" {
+		t.Fatalf("unexpected token response: %+v, err: %v", token, err)
+	}
+	c.token = token.AccessToken
+	return c
+}
+
+func (c *client) do(method, path, body string, v interface{}) int {
+	req, err := http.NewRequest(method, "https://"+APIHost+"/v1"+path, strings.NewReader(body))
+	if err != nil {
+		c.t.Fatalf("failed to create request: %v", err)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "bearer "+c.token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		c.t.Fatalf("%s %s failed: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	if v != nil {
+		if err = json.NewDecoder(resp.Body).Decode(v); err != nil {
+			c.t.Fatalf("failed to decode response to %s %s: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+type results struct {
+	Result     []Object `json:"result"`
+	TotalCount int      `json:"total_count"`
+}
+
+func listingNumbers(r results) []float64 {
+	numbers := []float64{}
+	for _, l := range r.Result {
+		numbers = append(numbers, l["listing_number"].(float64))
+	}
+	return numbers
+}
+
+func TestUnauthorizedRequestsAreRejected(t *testing.T) {
+	s := NewServer(Scenario{})
+	defer s.Close()
+	c := &client{t: t, http: &http.Client{Transport: s.Transport(http.DefaultTransport)}}
+	if status := c.do("GET", "/accounts/prosper/", "", nil); status != http.StatusUnauthorized {
+		t.Errorf("unexpected status without a token. got: %d, want: %d", status, http.StatusUnauthorized)
+	}
+}
+
+func TestSearchListings(t *testing.T) {
+	s := NewServer(Scenario{
+		Listings: []Object{
+			{"listing_number": 1, "prosper_rating": "AA", "inquiries_last6_months": 0, "listing_start_date": "2016-06-01 09:00:00 +0000"},
+			{"listing_number": 2, "prosper_rating": "B", "inquiries_last6_months": 2, "listing_start_date": "2016-06-02 09:00:00 +0000"},
+			{"listing_number": 3, "prosper_rating": "A", "inquiries_last6_months": 5, "listing_start_date": "2016-06-03 09:00:00 +0000"},
+		},
+	})
+	defer s.Close()
+	c := newClient(t, s)
+	var tests = []struct {
+		query string
+		want  []float64
+		msg   string
+	}{
+		{"", []float64{1, 2, 3}, "no filter should return every listing"},
+		{"?prosper_rating=AA,A", []float64{1, 3}, "list filter should match any listed value"},
+		{"?inquiries_last6_months_max=2", []float64{1, 2}, "max filter should be inclusive"},
+		{"?inquiries_last6_months_min=1&prosper_rating=A", []float64{3}, "filters should combine"},
+		{"?listing_start_date_min=2016-06-02", []float64{2, 3}, "date bounds should compare as dates"},
+		{"?limit=2&offset=1", []float64{2, 3}, "limit and offset should page results"},
+	}
+	for _, tt := range tests {
+		var got results
+		c.do("GET", "/search/listings/"+tt.query, "", &got)
+		if !reflect.DeepEqual(listingNumbers(got), tt.want) {
+			t.Errorf("%s: unexpected listings. got: %v, want: %v", tt.msg, listingNumbers(got), tt.want)
+		}
+	}
+	s.AddListing(Object{"listing_number": 4})
+	var got results
+	c.do("GET", "/search/listings/", "", &got)
+	if got.TotalCount != 4 {
+		t.Errorf("added listing should be searchable. got total: %d, want: 4", got.TotalCount)
+	}
+}
+
+func TestOrderLifecycle(t *testing.T) {
+	s := NewServer(Scenario{PollsUntilComplete: 1, IssueNotes: true})
+	defer s.Close()
+	c := newClient(t, s)
+	var placed Object
+	c.do("POST", "/bids/", `{"bid_requests":[{"listing_id":123,"bid_amount":25}]}`, &placed)
+	if placed["order_status"] != "IN_PROGRESS" {
+		t.Fatalf("new order should be in progress, got: %+v", placed)
+	}
+	path := "/orders/" + placed["order_id"].(string)
+	var status Object
+	c.do("GET", path, "", &status)
+	if status["order_status"] != "IN_PROGRESS" {
+		t.Errorf("order should stay in progress for the scripted polls, got: %+v", status)
+	}
+	c.do("GET", path, "", &status)
+	if status["order_status"] != "COMPLETED" {
+		t.Fatalf("order should complete after the scripted polls, got: %+v", status)
+	}
+	bid := status["bid_requests"].([]interface{})[0].(map[string]interface{})
+	if bid["bid_result"] != BidSuccessful || bid["bid_amount_placed"] != 25.0 {
+		t.Errorf("unexpected bid status: %+v", bid)
+	}
+	var notes results
+	c.do("GET", "/notes/", "", &notes)
+	if len(notes.Result) != 1 || notes.Result[0]["listing_number"] != 123.0 || notes.Result[0]["note_ownership_amount"] != 25.0 {
+		t.Errorf("completed order should issue a note, got: %+v", notes.Result)
+	}
+	if status := c.do("GET", "/orders/unknown-order", "", nil); status != http.StatusNotFound {
+		t.Errorf("unexpected status for unknown order. got: %d, want: %d", status, http.StatusNotFound)
+	}
+}
+
+func TestFailedBidsIssueNoNotes(t *testing.T) {
+	s := NewServer(Scenario{BidResult: BidFailed, IssueNotes: true})
+	defer s.Close()
+	c := newClient(t, s)
+	var placed, status Object
+	c.do("POST", "/bids/", `{"bid_requests":[{"listing_id":123,"bid_amount":25}]}`, &placed)
+	c.do("GET", "/orders/"+placed["order_id"].(string), "", &status)
+	bid := status["bid_requests"].([]interface{})[0].(map[string]interface{})
+	if bid["bid_result"] != BidFailed {
+		t.Errorf("unexpected bid result. got: %v, want: %v", bid["bid_result"], BidFailed)
+	}
+	var notes results
+	c.do("GET", "/notes/", "", &notes)
+	if len(notes.Result) != 0 {
+		t.Errorf("failed bids should not issue notes, got: %+v", notes.Result)
+	}
+}
+
+func TestAccount(t *testing.T) {
+	s := NewServer(Scenario{Account: Object{"available_cash_balance": 100.0}})
+	defer s.Close()
+	c := newClient(t, s)
+	var got Object
+	c.do("GET", "/accounts/prosper/", "", &got)
+	if got["available_cash_balance"] != 100.0 {
+		t.Errorf("unexpected account: %+v", got)
+	}
+	s.SetAccount(Object{"available_cash_balance": 75.0})
+	c.do("GET", "/accounts/prosper/", "", &got)
+	if got["available_cash_balance"] != 75.0 {
+		t.Errorf("account should reflect SetAccount, got: %+v", got)
+	}
+}
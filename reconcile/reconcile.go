@@ -0,0 +1,241 @@
+// Package reconcile cross-checks the bids the bot placed against the notes it
+// owns.
+package reconcile
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/clock"
+	"github.com/mtlynch/prosperbot/config"
+	"github.com/mtlynch/prosperbot/redis"
+)
+
+const (
+	defaultGracePeriod = 72 * time.Hour
+	// maxReports is how many past reports are kept.
+	maxReports = 90
+	// amountTolerance absorbs rounding in the amounts Prosper reports.
+	amountTolerance = 0.005
+)
+
+type DiscrepancyKind string
+
+const (
+	// MissingNote is a successful bid with no note for its listing.
+	MissingNote DiscrepancyKind = "missing note"
+	// UnexpectedNote is a note for a listing the bot never bid on, such as a
+	// manual purchase.
+	UnexpectedNote DiscrepancyKind = "unexpected note"
+	// AmountMismatch is a listing whose notes don't add up to the amount bid.
+	AmountMismatch DiscrepancyKind = "amount mismatch"
+)
+
+// Discrepancy is one disagreement between orders and notes.
+type Discrepancy struct {
+	Kind          DiscrepancyKind
+	ListingNumber prosper.ListingNumber
+	OrderIDs      []prosper.OrderID `json:",omitempty"`
+	LoanNoteIDs   []string          `json:",omitempty"`
+	BidAmount     float64
+	NoteAmount    float64
+}
+
+// Report is the outcome of one reconciliation.
+type Report struct {
+	Timestamp     time.Time
+	BidsChecked   int
+	NotesChecked  int
+	Discrepancies []Discrepancy
+}
+
+// listingTotals collects everything bid and owned on one listing.
+type listingTotals struct {
+	orderIDs    []prosper.OrderID
+	loanNoteIDs []string
+	bidAmount   float64
+	noteAmount  float64
+	// settled is false if any bid on the listing is too recent for its note
+	// to be expected yet.
+	settled bool
+}
+
+type byListingNumber []Discrepancy
+
+func (d byListingNumber) Len() int           { return len(d) }
+func (d byListingNumber) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+func (d byListingNumber) Less(i, j int) bool { return d[i].ListingNumber < d[j].ListingNumber }
+
+// Reconcile matches the successful bids of completed orders to notes by listing
+// number and amount. Bids placed less than gracePeriod before now are not
+// reported as missing notes, since Prosper takes time to originate a loan.
+func Reconcile(orders []redis.OrderRecord, notes []prosper.Note, now time.Time, gracePeriod time.Duration) Report {
+	report := Report{Timestamp: now, Discrepancies: []Discrepancy{}}
+	totals := map[prosper.ListingNumber]*listingTotals{}
+	get := func(n prosper.ListingNumber) *listingTotals {
+		t, ok := totals[n]
+		if !ok {
+			t = &listingTotals{settled: true}
+			totals[n] = t
+		}
+		return t
+	}
+	for _, o := range orders {
+		if o.Order.OrderStatus != prosper.OrderCompleted {
+			continue
+		}
+		placed := o.Order.OrderDate
+		if placed.IsZero() {
+			placed = o.Timestamp
+		}
+		for _, b := range o.Order.BidStatus {
+			if b.Result != prosper.BidSucceeded {
+				continue
+			}
+			report.BidsChecked++
+			t := get(b.ListingID)
+			t.orderIDs = append(t.orderIDs, o.Order.OrderID)
+			amount := b.BidAmountPlaced
+			if amount == 0 {
+				amount = b.BidAmount
+			}
+			t.bidAmount += amount
+			if now.Sub(placed) < gracePeriod {
+				t.settled = false
+			}
+		}
+	}
+	for _, n := range notes {
+		report.NotesChecked++
+		t := get(n.ListingNumber)
+		t.loanNoteIDs = append(t.loanNoteIDs, n.LoanNoteID)
+		t.noteAmount += n.NoteOwnershipAmount
+	}
+	for listingNumber, t := range totals {
+		var kind DiscrepancyKind
+		switch {
+		case len(t.orderIDs) == 0:
+			kind = UnexpectedNote
+		case len(t.loanNoteIDs) == 0:
+			if !t.settled {
+				continue
+			}
+			kind = MissingNote
+		case math.Abs(t.bidAmount-t.noteAmount) > amountTolerance:
+			kind = AmountMismatch
+		default:
+			continue
+		}
+		report.Discrepancies = append(report.Discrepancies, Discrepancy{
+			Kind:          kind,
+			ListingNumber: listingNumber,
+			OrderIDs:      t.orderIDs,
+			LoanNoteIDs:   t.loanNoteIDs,
+			BidAmount:     t.bidAmount,
+			NoteAmount:    t.noteAmount,
+		})
+	}
+	sort.Sort(byListingNumber(report.Discrepancies))
+	return report
+}
+
+type job struct {
+	redis       redis.RedisReconciler
+	interval    time.Duration
+	gracePeriod time.Duration
+	clock       clock.Timer
+}
+
+// NewJob creates a job that reconciles orders and notes on the interval in c.
+func NewJob(c config.Reconciliation, clk clock.Timer) (job, error) {
+	r, err := redis.New()
+	if err != nil {
+		return job{}, err
+	}
+	gracePeriod := c.GracePeriod.Duration
+	if gracePeriod == 0 {
+		gracePeriod = defaultGracePeriod
+	}
+	return job{
+		redis:       r,
+		interval:    c.Interval.Duration,
+		gracePeriod: gracePeriod,
+		clock:       clk,
+	}, nil
+}
+
+func (j job) Run() {
+	log.Printf("starting reconciliation every %v", j.interval)
+	for {
+		report, err := j.RunOnce()
+		if err != nil {
+			log.Printf("reconciliation failed: %v", err)
+		} else {
+			log.Printf("reconciliation found %d discrepancies", len(report.Discrepancies))
+		}
+		j.clock.Sleep(j.interval)
+	}
+}
+
+// RunOnce reconciles the saved orders against the latest state of every saved
+// note and saves the report.
+func (j job) RunOnce() (Report, error) {
+	orders, err := redis.Orders(j.redis)
+	if err != nil {
+		return Report{}, err
+	}
+	ids, err := redis.NoteIDs(j.redis)
+	if err != nil {
+		return Report{}, err
+	}
+	notes := []prosper.Note{}
+	for _, id := range ids {
+		history, err := redis.NoteHistory(j.redis, id)
+		if err != nil {
+			return Report{}, err
+		}
+		if len(history) > 0 {
+			notes = append(notes, history[len(history)-1].Note)
+		}
+	}
+	report := Reconcile(orders, notes, j.clock.Now(), j.gracePeriod)
+	if err = saveReport(j.redis, report); err != nil {
+		return Report{}, fmt.Errorf("failed to save reconciliation report: %v", err)
+	}
+	return report, nil
+}
+
+func saveReport(r redis.RedisReconciler, report Report) error {
+	serialized, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	if _, err = r.LPush(redis.KeyReconciliationReports, string(serialized)); err != nil {
+		return err
+	}
+	_, err = r.LTrim(redis.KeyReconciliationReports, 0, maxReports-1)
+	return err
+}
+
+// LatestReport returns the most recently saved report. The second return value
+// is false if no report has been saved.
+func LatestReport(r redis.RedisReader) (Report, bool, error) {
+	serialized, err := r.LRange(redis.KeyReconciliationReports, 0, 0)
+	if err != nil {
+		return Report{}, false, err
+	}
+	if len(serialized) == 0 {
+		return Report{}, false, nil
+	}
+	var report Report
+	if err = json.Unmarshal([]byte(serialized[0]), &report); err != nil {
+		return Report{}, false, err
+	}
+	return report, true, nil
+}
@@ -0,0 +1,177 @@
+package reconcile
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/clock"
+	"github.com/mtlynch/prosperbot/redis"
+)
+
+type mockRedis struct {
+	values map[string]string
+	lists  map[string][]string
+}
+
+func (r *mockRedis) Keys(pattern string) ([]string, error) {
+	prefix := strings.TrimSuffix(pattern, "*")
+	keys := []string{}
+	for k := range r.values {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	for k := range r.lists {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+
+func (r *mockRedis) Get(key string) (string, error) {
+	return r.values[key], nil
+}
+
+func (r *mockRedis) LRange(key string, start int64, stop int64) ([]string, error) {
+	l := r.lists[key]
+	if stop < 0 {
+		stop += int64(len(l))
+	}
+	if stop >= int64(len(l)) {
+		stop = int64(len(l)) - 1
+	}
+	if start > stop {
+		return []string{}, nil
+	}
+	return l[start : stop+1], nil
+}
+
+func (r *mockRedis) LPush(key string, values ...interface{}) (int64, error) {
+	for _, v := range values {
+		r.lists[key] = append([]string{v.(string)}, r.lists[key]...)
+	}
+	return int64(len(r.lists[key])), nil
+}
+
+func (r *mockRedis) LTrim(key string, start int64, stop int64) (string, error) {
+	if l := r.lists[key]; int64(len(l)) > stop+1 {
+		r.lists[key] = l[start : stop+1]
+	}
+	return "OK", nil
+}
+
+var (
+	now      = time.Date(2016, 6, 10, 12, 0, 0, 0, time.UTC)
+	lastWeek = now.AddDate(0, 0, -7)
+)
+
+func completedOrder(id prosper.OrderID, placed time.Time, bids ...prosper.BidStatus) redis.OrderRecord {
+	return redis.OrderRecord{
+		Order: prosper.OrderResponse{
+			OrderID:     id,
+			OrderStatus: prosper.OrderCompleted,
+			OrderDate:   placed,
+			BidStatus:   bids,
+		},
+		Timestamp: placed,
+	}
+}
+
+func succeeded(listing prosper.ListingNumber, amount float64) prosper.BidStatus {
+	return prosper.BidStatus{
+		BidRequest:      prosper.BidRequest{ListingID: listing, BidAmount: amount},
+		Result:          prosper.BidSucceeded,
+		BidAmountPlaced: amount,
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	var tests = []struct {
+		orders []redis.OrderRecord
+		notes  []prosper.Note
+		want   []Discrepancy
+		msg    string
+	}{
+		{
+			orders: []redis.OrderRecord{completedOrder("order-a", lastWeek, succeeded(123, 25))},
+			notes:  []prosper.Note{{LoanNoteID: "123-1", ListingNumber: 123, NoteOwnershipAmount: 25}},
+			want:   []Discrepancy{},
+			msg:    "a bid with a matching note should reconcile",
+		},
+		{
+			orders: []redis.OrderRecord{completedOrder("order-a", lastWeek, succeeded(123, 25))},
+			want: []Discrepancy{
+				{Kind: MissingNote, ListingNumber: 123, OrderIDs: []prosper.OrderID{"order-a"}, BidAmount: 25},
+			},
+			msg: "a settled bid with no note should be a missing note",
+		},
+		{
+			orders: []redis.OrderRecord{completedOrder("order-a", now.Add(-time.Hour), succeeded(123, 25))},
+			want:   []Discrepancy{},
+			msg:    "a recent bid with no note should not be flagged yet",
+		},
+		{
+			notes: []prosper.Note{{LoanNoteID: "456-1", ListingNumber: 456, NoteOwnershipAmount: 50}},
+			want: []Discrepancy{
+				{Kind: UnexpectedNote, ListingNumber: 456, LoanNoteIDs: []string{"456-1"}, NoteAmount: 50},
+			},
+			msg: "a note with no bid should be unexpected",
+		},
+		{
+			orders: []redis.OrderRecord{completedOrder("order-a", lastWeek, succeeded(123, 25))},
+			notes:  []prosper.Note{{LoanNoteID: "123-1", ListingNumber: 123, NoteOwnershipAmount: 20}},
+			want: []Discrepancy{
+				{Kind: AmountMismatch, ListingNumber: 123, OrderIDs: []prosper.OrderID{"order-a"}, LoanNoteIDs: []string{"123-1"}, BidAmount: 25, NoteAmount: 20},
+			},
+			msg: "a note for less than the bid should be an amount mismatch",
+		},
+		{
+			orders: []redis.OrderRecord{
+				completedOrder("order-a", lastWeek, prosper.BidStatus{
+					BidRequest: prosper.BidRequest{ListingID: 123, BidAmount: 25},
+					Result:     prosper.BidFailed,
+				}),
+				{Order: prosper.OrderResponse{OrderID: "order-b", OrderStatus: prosper.OrderInProgress, BidStatus: []prosper.BidStatus{succeeded(789, 25)}}},
+			},
+			want: []Discrepancy{},
+			msg:  "failed bids and orders in progress should be ignored",
+		},
+	}
+	for _, tt := range tests {
+		got := Reconcile(tt.orders, tt.notes, now, 72*time.Hour)
+		if !reflect.DeepEqual(got.Discrepancies, tt.want) {
+			t.Errorf("%s: unexpected discrepancies. got: %+v, want: %+v", tt.msg, got.Discrepancies, tt.want)
+		}
+	}
+}
+
+func TestRunOnceSavesReport(t *testing.T) {
+	r := &mockRedis{
+		values: map[string]string{
+			"order:order-a": `{"Order":{"OrderID":"order-a","OrderStatus":1,"OrderDate":"2016-06-03T12:00:00Z","BidStatus":[{"ListingID":123,"BidAmount":25,"Result":4,"BidAmountPlaced":25}]},"Timestamp":"2016-06-03T12:00:00Z","Version":1}`,
+		},
+		lists: map[string][]string{
+			"note:456-1": {`{"Note":{"LoanNoteID":"456-1","ListingNumber":456,"NoteOwnershipAmount":50},"Timestamp":"2016-06-03T12:00:00Z","Version":1}`},
+		},
+	}
+	j := job{redis: r, gracePeriod: 72 * time.Hour, clock: clock.NewVirtual(now)}
+	report, err := j.RunOnce()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.BidsChecked != 1 || report.NotesChecked != 1 || len(report.Discrepancies) != 2 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	saved, ok, err := LatestReport(r)
+	if err != nil || !ok {
+		t.Fatalf("expected saved report, got ok: %v, err: %v", ok, err)
+	}
+	if !reflect.DeepEqual(saved, report) {
+		t.Errorf("unexpected saved report. got: %+v, want: %+v", saved, report)
+	}
+}
@@ -0,0 +1,135 @@
+// Package record saves the bot's traffic with the Prosper API to a log and
+// plays it back, so that what the bot saw and decided can be reproduced
+// offline.
+package record
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/clock"
+)
+
+// Names of the recorded calls, one per method of prosper.Client.
+const (
+	CallSearch      = "Search"
+	CallPlaceBid    = "PlaceBid"
+	CallOrderStatus = "OrderStatus"
+	CallAccount     = "Account"
+	CallNotes       = "Notes"
+)
+
+const redacted = "[REDACTED]"
+
+// tokenPatterns match credentials and tokens that the Prosper client may
+// include in its error messages.
+var tokenPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)(bearer\s+)[^\s"',]+`),
+	regexp.MustCompile(`(?i)("?(?:access_token|refresh_token|client_secret|password)"?\s*[:=]\s*"?)[^\s"&,}]+`),
+}
+
+// Entry is one call to the Prosper API and its outcome.
+type Entry struct {
+	Time     time.Time
+	Call     string
+	Request  json.RawMessage
+	Response json.RawMessage `json:",omitempty"`
+	// Error is the text of the error the call returned, if any.
+	Error string `json:",omitempty"`
+}
+
+// Recorder is a prosper.Client that passes calls to another client and writes
+// each one to a log as a line of JSON.
+type Recorder struct {
+	c       prosper.Client
+	clock   clock.Clock
+	secrets []string
+	mu      sync.Mutex
+	enc     *json.Encoder
+}
+
+// NewRecorder creates a Recorder that logs the calls made through c to w.
+// Any of secrets that appear in an error are redacted, along with anything
+// that looks like an OAuth token.
+func NewRecorder(c prosper.Client, w io.Writer, clk clock.Clock, secrets ...string) *Recorder {
+	nonEmpty := []string{}
+	for _, s := range secrets {
+		if s != "" {
+			nonEmpty = append(nonEmpty, s)
+		}
+	}
+	return &Recorder{
+		c:       c,
+		clock:   clk,
+		secrets: nonEmpty,
+		enc:     json.NewEncoder(w),
+	}
+}
+
+// Redact removes the secrets and anything that looks like a token from s.
+func (r *Recorder) Redact(s string) string {
+	for _, secret := range r.secrets {
+		s = strings.Replace(s, secret, redacted, -1)
+	}
+	for _, p := range tokenPatterns {
+		s = p.ReplaceAllString(s, "${1}"+redacted)
+	}
+	return s
+}
+
+func (r *Recorder) record(call string, request, response interface{}, err error) {
+	e := Entry{Time: r.clock.Now(), Call: call}
+	var marshalErr error
+	if e.Request, marshalErr = json.Marshal(request); marshalErr != nil {
+		log.Printf("failed to serialize %s request for the API log: %v", call, marshalErr)
+		return
+	}
+	if err != nil {
+		e.Error = r.Redact(err.Error())
+	} else if e.Response, marshalErr = json.Marshal(response); marshalErr != nil {
+		log.Printf("failed to serialize %s response for the API log: %v", call, marshalErr)
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.enc.Encode(e); err != nil {
+		log.Printf("failed to write %s call to the API log: %v", call, err)
+	}
+}
+
+func (r *Recorder) Search(p prosper.SearchParams) (prosper.SearchResponse, error) {
+	response, err := r.c.Search(p)
+	r.record(CallSearch, p, response, err)
+	return response, err
+}
+
+func (r *Recorder) PlaceBid(b prosper.BidRequest) (prosper.OrderResponse, error) {
+	response, err := r.c.PlaceBid(b)
+	r.record(CallPlaceBid, b, response, err)
+	return response, err
+}
+
+func (r *Recorder) OrderStatus(id prosper.OrderID) (prosper.OrderResponse, error) {
+	response, err := r.c.OrderStatus(id)
+	r.record(CallOrderStatus, id, response, err)
+	return response, err
+}
+
+func (r *Recorder) Account(p prosper.AccountParams) (prosper.AccountInformation, error) {
+	response, err := r.c.Account(p)
+	r.record(CallAccount, p, response, err)
+	return response, err
+}
+
+func (r *Recorder) Notes(p prosper.NotesParams) (prosper.NotesResponse, error) {
+	response, err := r.c.Notes(p)
+	r.record(CallNotes, p, response, err)
+	return response, err
+}
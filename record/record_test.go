@@ -0,0 +1,259 @@
+package record
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/interval"
+	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/buyer"
+	"github.com/mtlynch/prosperbot/clock"
+)
+
+var mockCurrentTime = time.Date(2016, 6, 1, 9, 0, 0, 0, time.UTC)
+
+type mockClient struct {
+	search  prosper.SearchResponse
+	order   prosper.OrderResponse
+	account prosper.AccountInformation
+	notes   prosper.NotesResponse
+	err     error
+}
+
+func (c mockClient) Search(prosper.SearchParams) (prosper.SearchResponse, error) {
+	return c.search, nil
+}
+
+func (c mockClient) PlaceBid(prosper.BidRequest) (prosper.OrderResponse, error) {
+	return c.order, nil
+}
+
+func (c mockClient) OrderStatus(prosper.OrderID) (prosper.OrderResponse, error) {
+	return c.order, nil
+}
+
+func (c mockClient) Account(prosper.AccountParams) (prosper.AccountInformation, error) {
+	return c.account, nil
+}
+
+func (c mockClient) Notes(prosper.NotesParams) (prosper.NotesResponse, error) {
+	return prosper.NotesResponse{}, c.err
+}
+
+func TestRedact(t *testing.T) {
+	var tests = []struct {
+		s    string
+		want string
+		msg  string
+	}{
+		{
+			s:    "failed to get new listings: 500 Internal Server Error",
+			want: "failed to get new listings: 500 Internal Server Error",
+			msg:  "messages without secrets should be unchanged",
+		},
+		{
+			s:    "bad login for user jdoe@example.com with password hunter2",
+			want: "bad login for user [REDACTED] with password [REDACTED]",
+			msg:  "credentials should be redacted wherever they appear",
+		},
+		{
+			s:    `token request failed: {"access_token":"abc123","refresh_token":"def456"}`,
+			want: `token request failed: {"access_token":"[REDACTED]","refresh_token":"[REDACTED]"}`,
+			msg:  "tokens in JSON should be redacted",
+		},
+		{
+			s:    "request with Authorization: bearer abc123 rejected",
+			want: "request with Authorization: bearer [REDACTED] rejected",
+			msg:  "bearer tokens should be redacted",
+		},
+		{
+			s:    "POST grant_type=password&client_secret=s3cret&password=pw failed",
+			want: "POST grant_type=password&client_secret=[REDACTED]&password=[REDACTED] failed",
+			msg:  "tokens in form values should be redacted",
+		},
+	}
+	r := NewRecorder(mockClient{}, ioutil.Discard, clock.NewVirtual(mockCurrentTime), "jdoe@example.com", "hunter2", "")
+	for _, tt := range tests {
+		if got := r.Redact(tt.s); got != tt.want {
+			t.Errorf("%s: unexpected redaction. got = %q, want = %q", tt.msg, got, tt.want)
+		}
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	c := mockClient{
+		search: prosper.SearchResponse{
+			Results:     []prosper.Listing{{ListingNumber: 123}},
+			ResultCount: 1,
+			TotalCount:  1,
+		},
+		order: prosper.OrderResponse{
+			OrderID:     "order-a",
+			OrderStatus: prosper.OrderCompleted,
+			BidStatus:   []prosper.BidStatus{{BidRequest: prosper.BidRequest{ListingID: 123, BidAmount: 25.0}, Result: prosper.BidSucceeded}},
+		},
+		account: prosper.AccountInformation{AvailableCashBalance: 100.0},
+		err:     errors.New("token expired: access_token=abc123"),
+	}
+	var buf bytes.Buffer
+	r := NewRecorder(c, &buf, clock.NewVirtual(mockCurrentTime))
+	wantSearch, _ := r.Search(prosper.SearchParams{Limit: 50})
+	wantBid, _ := r.PlaceBid(prosper.BidRequest{ListingID: 123, BidAmount: 25.0})
+	wantOrder, _ := r.OrderStatus("order-a")
+	wantAccount, _ := r.Account(prosper.AccountParams{})
+	if _, err := r.Notes(prosper.NotesParams{}); err != c.err {
+		t.Fatalf("recorder should pass along errors. got = %v, want = %v", err, c.err)
+	}
+	if strings.Contains(buf.String(), "abc123") {
+		t.Fatalf("log contains a token: %s", buf.String())
+	}
+
+	entries, err := ReadEntries(&buf)
+	if err != nil {
+		t.Fatalf("failed to read log: %v", err)
+	}
+	if len(entries) != 5 {
+		t.Fatalf("unexpected number of entries. got = %d, want = 5", len(entries))
+	}
+	if !entries[0].Time.Equal(mockCurrentTime) || entries[0].Call != CallSearch {
+		t.Errorf("unexpected first entry: %+v", entries[0])
+	}
+
+	rp := NewReplayer(entries)
+	if got, err := rp.OrderStatus("order-b"); err != ErrNotRecorded {
+		t.Errorf("unrecorded order should not be served. got = %+v, %v", got, err)
+	}
+	if got, err := rp.Search(prosper.SearchParams{Limit: 50}); err != nil || !reflect.DeepEqual(got, wantSearch) {
+		t.Errorf("unexpected search response. got = %+v, %v, want = %+v", got, err, wantSearch)
+	}
+	if got, err := rp.PlaceBid(prosper.BidRequest{ListingID: 123, BidAmount: 25.0}); err != nil || !reflect.DeepEqual(got, wantBid) {
+		t.Errorf("unexpected bid response. got = %+v, %v, want = %+v", got, err, wantBid)
+	}
+	if got, err := rp.OrderStatus("order-a"); err != nil || !reflect.DeepEqual(got, wantOrder) {
+		t.Errorf("unexpected order status. got = %+v, %v, want = %+v", got, err, wantOrder)
+	}
+	if got, err := rp.Account(prosper.AccountParams{}); err != nil || !reflect.DeepEqual(got, wantAccount) {
+		t.Errorf("unexpected account. got = %+v, %v, want = %+v", got, err, wantAccount)
+	}
+	if _, err := rp.Notes(prosper.NotesParams{}); err == nil || err.Error() != "token expired: access_token=[REDACTED]" {
+		t.Errorf("unexpected notes error. got = %v", err)
+	}
+	if _, err := rp.Search(prosper.SearchParams{Limit: 50}); err != ErrNotRecorded {
+		t.Errorf("each entry should be served once. got = %v", err)
+	}
+	if made, failed := rp.Calls(CallSearch); made != 2 || failed != 1 {
+		t.Errorf("unexpected search calls. got = %d made, %d failed, want = 2 made, 1 failed", made, failed)
+	}
+}
+
+func TestRecordEmptySearches(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewRecorder(mockClient{}, &buf, clock.NewVirtual(mockCurrentTime))
+	if _, err := r.Search(prosper.SearchParams{Limit: 50}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	entries, err := ReadEntries(&buf)
+	if err != nil {
+		t.Fatalf("failed to read recording: %v", err)
+	}
+	got, err := NewReplayer(entries).Search(prosper.SearchParams{Limit: 50})
+	if err != nil {
+		t.Fatalf("a search that found nothing should be replayed. got error: %v", err)
+	}
+	if len(got.Results) != 0 {
+		t.Errorf("unexpected replayed search. got: %+v", got)
+	}
+}
+
+func TestReplaySearchesForKnownListings(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2016, 6, d, 9, 0, 0, 0, time.UTC) }
+	search := func(start time.Time, exclude bool) prosper.SearchParams {
+		return prosper.SearchParams{
+			Limit:                   50,
+			ExcludeListingsInvested: exclude,
+			Filter:                  prosper.SearchFilter{ListingStartDate: interval.TimeRange{Min: &start, Max: &start}},
+		}
+	}
+	entry := func(p prosper.SearchParams, n prosper.ListingNumber) Entry {
+		request, _ := json.Marshal(p)
+		response, _ := json.Marshal(prosper.SearchResponse{Results: []prosper.Listing{{ListingNumber: n}}, ResultCount: 1, TotalCount: 1})
+		return Entry{Call: CallSearch, Request: request, Response: response}
+	}
+	rp := NewReplayer([]Entry{
+		entry(search(day(1), true), 1),
+		entry(search(day(1), false), 2),
+		entry(search(day(2), false), 3),
+	})
+	var tests = []struct {
+		p    prosper.SearchParams
+		want prosper.ListingNumber
+		msg  string
+	}{
+		{search(day(2), false), 3, "a search for a known listing should be served by its filter"},
+		{search(day(3), true), 1, "a search for new listings should be served by its offset"},
+		{search(day(1), false), 2, "the remaining search for a known listing should still be served"},
+	}
+	for _, tt := range tests {
+		got, err := rp.Search(tt.p)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.msg, err)
+		}
+		if len(got.Results) != 1 || got.Results[0].ListingNumber != tt.want {
+			t.Errorf("%s: unexpected response. got = %+v, want listing %v", tt.msg, got, tt.want)
+		}
+	}
+}
+
+func TestRerun(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	employed := prosper.Listing{ListingNumber: 123, EmploymentStatusDescription: "Employed"}
+	unemployed := prosper.Listing{ListingNumber: 456, EmploymentStatusDescription: "Unemployed"}
+	bid := prosper.BidRequest{ListingID: 123, BidAmount: 25.0}
+	c := mockClient{
+		search: prosper.SearchResponse{
+			Results:     []prosper.Listing{employed, unemployed},
+			ResultCount: 2,
+			TotalCount:  2,
+		},
+		order: prosper.OrderResponse{
+			OrderID:     "order-a",
+			OrderStatus: prosper.OrderCompleted,
+			BidStatus:   []prosper.BidStatus{{BidRequest: bid, Result: prosper.BidSucceeded}},
+		},
+	}
+	var buf bytes.Buffer
+	r := NewRecorder(c, &buf, clock.NewVirtual(mockCurrentTime))
+	r.Search(prosper.SearchParams{Limit: 50, ExcludeListingsInvested: true})
+	r.PlaceBid(bid)
+	r.OrderStatus("order-a")
+	entries, err := ReadEntries(&buf)
+	if err != nil {
+		t.Fatalf("failed to read log: %v", err)
+	}
+
+	got, err := Rerun(entries, buyer.Policy{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []Outcome{
+		{ListingNumber: 123, Passed: true, OrderID: "order-a", BidRecorded: true},
+		{ListingNumber: 456, Reason: "EmploymentStatusDescription is blacklisted: Unemployed"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected outcomes. got = %+v, want = %+v", got, want)
+	}
+	if _, err := Rerun([]Entry{}, buyer.Policy{}); err != ErrEmptyRecording {
+		t.Errorf("empty recording should fail. got = %v, want = %v", err, ErrEmptyRecording)
+	}
+}
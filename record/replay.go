@@ -0,0 +1,173 @@
+package record
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+)
+
+// ErrNotRecorded is returned by a Replayer for a call that has no matching
+// entry left in the log.
+var ErrNotRecorded = errors.New("call not found in recording")
+
+// ReadEntries parses a log written by a Recorder.
+func ReadEntries(r io.Reader) ([]Entry, error) {
+	entries := []Entry{}
+	scanner := bufio.NewScanner(r)
+	// Search responses hold up to a page of full listings, so lines can be far
+	// longer than the scanner's default limit.
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("failed to parse line %d of API log: %v", line, err)
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Replayer is a prosper.Client that answers calls with the responses in a
+// recording instead of contacting Prosper. Each entry is served once. Calls
+// are matched to entries in the order they were recorded, searches and note
+// fetches by page offset, bids by listing and order status queries by order
+// ID, so that the bot's concurrent calls still get the responses it saw.
+type Replayer struct {
+	mu      sync.Mutex
+	entries []Entry
+	used    []bool
+	// calls and failed count the calls made and those that returned an error.
+	calls  map[string]int
+	failed map[string]int
+}
+
+func NewReplayer(entries []Entry) *Replayer {
+	return &Replayer{
+		entries: entries,
+		used:    make([]bool, len(entries)),
+		calls:   map[string]int{},
+		failed:  map[string]int{},
+	}
+}
+
+// Calls returns how many times call has been made, and how many of those
+// calls returned an error.
+func (r *Replayer) Calls(call string) (made, failed int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.calls[call], r.failed[call]
+}
+
+// Recorded returns how many entries for call are in the recording.
+func (r *Replayer) Recorded(call string) int {
+	count := 0
+	for _, e := range r.entries {
+		if e.Call == call {
+			count++
+		}
+	}
+	return count
+}
+
+// next finds the first unused entry for call whose request matches, marks it
+// used and decodes its response into response.
+func (r *Replayer) next(call string, matches func(request json.RawMessage) bool, response interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls[call]++
+	err := r.serve(call, matches, response)
+	if err != nil {
+		r.failed[call]++
+	}
+	return err
+}
+
+func (r *Replayer) serve(call string, matches func(request json.RawMessage) bool, response interface{}) error {
+	for i, e := range r.entries {
+		if r.used[i] || e.Call != call || !matches(e.Request) {
+			continue
+		}
+		r.used[i] = true
+		if e.Error != "" {
+			return errors.New(e.Error)
+		}
+		if err := json.Unmarshal(e.Response, response); err != nil {
+			return fmt.Errorf("failed to parse recorded %s response: %v", call, err)
+		}
+		return nil
+	}
+	return ErrNotRecorded
+}
+
+func matchAny(json.RawMessage) bool { return true }
+
+// Search serves searches for new listings by page offset, since the time
+// window they search depends on when they ran. Searches for a known listing,
+// which include listings already invested in, are served by their whole
+// filter.
+func (r *Replayer) Search(p prosper.SearchParams) (prosper.SearchResponse, error) {
+	var response prosper.SearchResponse
+	filter, err := json.Marshal(p.Filter)
+	if err != nil {
+		return response, err
+	}
+	err = r.next(CallSearch, func(request json.RawMessage) bool {
+		var recorded prosper.SearchParams
+		if json.Unmarshal(request, &recorded) != nil || recorded.Offset != p.Offset || recorded.ExcludeListingsInvested != p.ExcludeListingsInvested {
+			return false
+		}
+		if p.ExcludeListingsInvested {
+			return true
+		}
+		recordedFilter, err := json.Marshal(recorded.Filter)
+		return err == nil && bytes.Equal(recordedFilter, filter)
+	}, &response)
+	return response, err
+}
+
+func (r *Replayer) PlaceBid(b prosper.BidRequest) (prosper.OrderResponse, error) {
+	var response prosper.OrderResponse
+	err := r.next(CallPlaceBid, func(request json.RawMessage) bool {
+		var recorded prosper.BidRequest
+		return json.Unmarshal(request, &recorded) == nil && recorded.ListingID == b.ListingID
+	}, &response)
+	return response, err
+}
+
+func (r *Replayer) OrderStatus(id prosper.OrderID) (prosper.OrderResponse, error) {
+	var response prosper.OrderResponse
+	err := r.next(CallOrderStatus, func(request json.RawMessage) bool {
+		var recorded prosper.OrderID
+		return json.Unmarshal(request, &recorded) == nil && recorded == id
+	}, &response)
+	return response, err
+}
+
+func (r *Replayer) Account(prosper.AccountParams) (prosper.AccountInformation, error) {
+	var response prosper.AccountInformation
+	err := r.next(CallAccount, matchAny, &response)
+	return response, err
+}
+
+func (r *Replayer) Notes(p prosper.NotesParams) (prosper.NotesResponse, error) {
+	var response prosper.NotesResponse
+	err := r.next(CallNotes, func(request json.RawMessage) bool {
+		var recorded prosper.NotesParams
+		return json.Unmarshal(request, &recorded) == nil && recorded.Offset == p.Offset
+	}, &response)
+	return response, err
+}
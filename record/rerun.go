@@ -0,0 +1,231 @@
+package record
+
+import (
+	"encoding/json"
+	"errors"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/buyer"
+	"github.com/mtlynch/prosperbot/clock"
+	"github.com/mtlynch/prosperbot/redis"
+)
+
+const (
+	// rerunPollInterval is how far the virtual clock moves between listing
+	// polls. It only has to be short enough that order status queries keep up.
+	rerunPollInterval = time.Second
+	// rerunTimeout bounds the real time a rerun may take.
+	rerunTimeout = 30 * time.Second
+)
+
+var (
+	ErrEmptyRecording = errors.New("recording has no API calls")
+	ErrRerunTimedOut  = errors.New("bot did not finish handling the recorded listings in time")
+)
+
+// Outcome is what the bot decides about a listing when the recording is
+// replayed, alongside what it did when the recording was made.
+type Outcome struct {
+	ListingNumber prosper.ListingNumber
+	Passed        bool
+	Reason        string `json:",omitempty"`
+	// OrderID is the order that the replayed bid was answered with.
+	OrderID prosper.OrderID `json:",omitempty"`
+	// BidRecorded is whether the recording has a bid on the listing.
+	BidRecorded bool
+}
+
+// Diverged reports whether the replayed bot bid differently than the
+// recorded one.
+func (o Outcome) Diverged() bool {
+	return o.Passed != o.BidRecorded
+}
+
+type byListingNumber []Outcome
+
+func (o byListingNumber) Len() int           { return len(o) }
+func (o byListingNumber) Swap(i, j int)      { o[i], o[j] = o[j], o[i] }
+func (o byListingNumber) Less(i, j int) bool { return o[i].ListingNumber < o[j].ListingNumber }
+
+// Rerun feeds the recorded searches through the bot's buying pipeline with
+// buying enabled, answering every API call from the recording, and returns
+// the bot's decision on each listing it was served under policy p. Because
+// account information is not replayed, ranked bids are not limited by cash.
+// It replaces the Redis store with an in-memory one while it runs, so a rerun
+// waits for any other rerun or simulation to finish first. The bot's
+// goroutines are left blocked on the virtual clock when Rerun returns.
+func Rerun(entries []Entry, p buyer.Policy) ([]Outcome, error) {
+	if len(entries) == 0 {
+		return nil, ErrEmptyRecording
+	}
+	v := clock.NewVirtual(entries[0].Time)
+	store := redis.NewMemoryStore(v)
+	defer redis.UseMemoryStore(store)()
+
+	listings, bids, err := recordedListingsAndBids(entries)
+	if err != nil {
+		return nil, err
+	}
+	rp := NewReplayer(entries)
+	if err = buyer.Poll(rerunPollInterval, buyer.DefaultSearchFilter(), true, rp, v, p); err != nil {
+		return nil, err
+	}
+	searches, err := recordedPolls(entries)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(rerunTimeout)
+	for {
+		made, failed := rp.Calls(CallSearch)
+		if made-failed >= searches {
+			break
+		}
+		if time.Now().After(deadline) {
+			return nil, ErrRerunTimedOut
+		}
+		v.Advance(rerunPollInterval)
+		waitForSearch(rp, made)
+	}
+
+	r := store.Client()
+	for {
+		outcomes, settled, err := collectOutcomes(r, rp, listings, bids)
+		if err != nil {
+			return nil, err
+		}
+		if settled {
+			sort.Sort(byListingNumber(outcomes))
+			return outcomes, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, ErrRerunTimedOut
+		}
+		// Keep time moving so that a batch of ranked listings is bid on.
+		v.Advance(rerunPollInterval)
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// waitForSearch waits up to a millisecond for the bot to make another search
+// after the first made, so that a recording with a search every second
+// replays as fast as the bot handles it.
+func waitForSearch(rp *Replayer, made int) {
+	deadline := time.Now().Add(time.Millisecond)
+	for time.Now().Before(deadline) {
+		if m, _ := rp.Calls(CallSearch); m > made {
+			return
+		}
+		runtime.Gosched()
+	}
+}
+
+// isPoll reports whether a recorded search was a search for new listings,
+// rather than for the latest state of a listing the bot already knew.
+func isPoll(e Entry) (bool, error) {
+	var p prosper.SearchParams
+	if err := json.Unmarshal(e.Request, &p); err != nil {
+		return false, err
+	}
+	return p.ExcludeListingsInvested, nil
+}
+
+// recordedPolls returns the number of recorded searches for new listings.
+func recordedPolls(entries []Entry) (int, error) {
+	count := 0
+	for _, e := range entries {
+		if e.Call != CallSearch {
+			continue
+		}
+		poll, err := isPoll(e)
+		if err != nil {
+			return 0, err
+		}
+		if poll {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// recordedListingsAndBids returns the distinct listings in the results of the
+// recorded searches for new listings and the listings that the recorded bot
+// bid on.
+func recordedListingsAndBids(entries []Entry) (map[prosper.ListingNumber]bool, map[prosper.ListingNumber]bool, error) {
+	listings := map[prosper.ListingNumber]bool{}
+	bids := map[prosper.ListingNumber]bool{}
+	for _, e := range entries {
+		switch e.Call {
+		case CallSearch:
+			if e.Error != "" {
+				continue
+			}
+			poll, err := isPoll(e)
+			if err != nil {
+				return nil, nil, err
+			}
+			if !poll {
+				continue
+			}
+			var response prosper.SearchResponse
+			if err := json.Unmarshal(e.Response, &response); err != nil {
+				return nil, nil, err
+			}
+			for _, l := range response.Results {
+				listings[l.ListingNumber] = true
+			}
+		case CallPlaceBid:
+			var b prosper.BidRequest
+			if err := json.Unmarshal(e.Request, &b); err != nil {
+				return nil, nil, err
+			}
+			bids[b.ListingID] = true
+		}
+	}
+	return listings, bids, nil
+}
+
+// collectOutcomes reads the bot's decisions from the lineage records. The bot
+// has settled once it has decided on every listing and finished every bid it
+// started.
+func collectOutcomes(r redis.Client, rp *Replayer, listings, bids map[prosper.ListingNumber]bool) ([]Outcome, bool, error) {
+	outcomes := []Outcome{}
+	passed, ordered := 0, 0
+	for n := range listings {
+		fields, err := r.HGetAll(redis.LineageKey(n))
+		if err != nil {
+			return nil, false, err
+		}
+		record := map[string]string{}
+		for i := 0; i+1 < len(fields); i += 2 {
+			record[fields[i]] = fields[i+1]
+		}
+		serialized, ok := record[redis.LineageFieldDecision]
+		if !ok {
+			return nil, false, nil
+		}
+		var d redis.Decision
+		if err = json.Unmarshal([]byte(serialized), &d); err != nil {
+			return nil, false, err
+		}
+		o := Outcome{
+			ListingNumber: n,
+			Passed:        d.Passed,
+			Reason:        d.Reason,
+			OrderID:       prosper.OrderID(record[redis.LineageFieldOrder]),
+			BidRecorded:   bids[n],
+		}
+		if o.Passed {
+			passed++
+		}
+		if o.OrderID != "" {
+			ordered++
+		}
+		outcomes = append(outcomes, o)
+	}
+	made, failed := rp.Calls(CallPlaceBid)
+	return outcomes, made == passed && ordered == made-failed, nil
+}
@@ -0,0 +1,170 @@
+package redis
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+)
+
+// BorrowerID identifies the borrower behind a listing by the listing's member
+// key. It reports false if Prosper did not provide one, in which case the
+// borrower cannot be told apart from others and the limit on exposure to one
+// borrower does not apply.
+func BorrowerID(l prosper.Listing) (string, bool) {
+	if l.MemberKey == "" {
+		return "", false
+	}
+	return "member:" + l.MemberKey, true
+}
+
+func BorrowerKey(id string) string {
+	return KeyPrefixBorrower + id
+}
+
+// RecordBorrowerBid adds a bid on listing n to the borrower's exposure.
+func RecordBorrowerBid(r RedisHashSetter, id string, n prosper.ListingNumber, amount float64) error {
+	_, err := r.HSet(BorrowerKey(id), strconv.FormatInt(int64(n), 10), strconv.FormatFloat(amount, 'f', 2, 64))
+	return err
+}
+
+// BorrowerExposure is the amount the bot has at stake with a borrower. For
+// each listing the bot bid on, that is the outstanding principal of the notes
+// it became, or the amount of the bid if it has not become a note yet. Failed
+// bids count for nothing.
+func BorrowerExposure(r RedisLineageReader, id string) (float64, error) {
+	fields, err := r.HGetAll(BorrowerKey(id))
+	if err != nil {
+		return 0, err
+	}
+	total := 0.0
+	for field, value := range fieldsToMap(fields) {
+		n, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid listing number %q for borrower %s: %v", field, id, err)
+		}
+		bid, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid bid amount %q for borrower %s: %v", value, id, err)
+		}
+		lineage, err := LineageForListing(r, prosper.ListingNumber(n))
+		if err == ErrLineageNotFound {
+			total += bid
+			continue
+		} else if err != nil {
+			return 0, err
+		}
+		total += listingExposure(lineage, bid)
+	}
+	return total, nil
+}
+
+func listingExposure(l Lineage, bid float64) float64 {
+	if len(l.Notes) > 0 {
+		principal := 0.0
+		for _, n := range l.Notes {
+			principal += n.PrincipalBalanceProRataShare
+		}
+		return principal
+	}
+	if l.Bid == nil {
+		// The order has not been saved yet.
+		return bid
+	}
+	switch l.Bid.Result {
+	case prosper.BidFailed:
+		return 0
+	case prosper.BidSucceeded:
+		if l.Bid.BidAmountPlaced > 0 {
+			return l.Bid.BidAmountPlaced
+		}
+	}
+	return l.Bid.BidAmount
+}
+
+// IndexBorrowers adds every listing that the bot has an order or note for to
+// the exposure of its borrower, so that bids placed before borrowers were
+// tracked still count. Listings are found from both their lineage and the
+// saved notes, so notes bought before lineage was recorded count too. Only
+// the saved snapshot of a listing identifies its borrower, so listings whose
+// snapshot has expired or has no member key cannot be counted. It returns the
+// number of listings indexed and the listings that were skipped.
+func IndexBorrowers(r RedisBorrowerIndexer) (int, []prosper.ListingNumber, error) {
+	skipped := []prosper.ListingNumber{}
+	// amounts holds the amount bid on each listing, or the amount of the notes
+	// it became if the bid is unknown.
+	amounts := map[prosper.ListingNumber]float64{}
+	bids := map[prosper.ListingNumber]bool{}
+	keys, err := r.Keys(KeyPrefixLineage + "*")
+	if err != nil {
+		return 0, skipped, err
+	}
+	for _, k := range keys {
+		n, err := strconv.ParseInt(strings.TrimPrefix(k, KeyPrefixLineage), 10, 64)
+		if err != nil {
+			continue
+		}
+		lineage, err := LineageForListing(r, prosper.ListingNumber(n))
+		if err == ErrLineageNotFound {
+			continue
+		} else if err != nil {
+			return 0, skipped, err
+		}
+		if lineage.Order == nil {
+			continue
+		}
+		amounts[lineage.ListingNumber] = 0
+		if lineage.Bid != nil {
+			amounts[lineage.ListingNumber] = lineage.Bid.BidAmount
+			bids[lineage.ListingNumber] = true
+		}
+	}
+	ids, err := NoteIDs(r)
+	if err != nil {
+		return 0, skipped, err
+	}
+	for _, id := range ids {
+		latest, _, err := LatestNoteRecord(r, id)
+		if err == ErrNoteNotFound {
+			continue
+		} else if err != nil {
+			return 0, skipped, fmt.Errorf("failed to read history for note %s: %v", id, err)
+		}
+		if n := latest.Note.ListingNumber; !bids[n] {
+			amounts[n] += latest.Note.NoteOwnershipAmount
+		}
+	}
+	listings := []prosper.ListingNumber{}
+	for n := range amounts {
+		listings = append(listings, n)
+	}
+	sort.Sort(byListingNumber(listings))
+	indexed := 0
+	for _, n := range listings {
+		l, err := Listing(r, n)
+		if err == ErrListingNotFound {
+			skipped = append(skipped, n)
+			continue
+		} else if err != nil {
+			return indexed, skipped, err
+		}
+		id, ok := BorrowerID(l)
+		if !ok {
+			skipped = append(skipped, n)
+			continue
+		}
+		if err = RecordBorrowerBid(r, id, n, amounts[n]); err != nil {
+			return indexed, skipped, err
+		}
+		indexed++
+	}
+	return indexed, skipped, nil
+}
+
+type byListingNumber []prosper.ListingNumber
+
+func (b byListingNumber) Len() int           { return len(b) }
+func (b byListingNumber) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b byListingNumber) Less(i, j int) bool { return b[i] < b[j] }
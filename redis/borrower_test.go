@@ -0,0 +1,124 @@
+package redis
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/clock"
+)
+
+func TestBorrowerID(t *testing.T) {
+	var tests = []struct {
+		l      prosper.Listing
+		wantID string
+		wantOK bool
+		msg    string
+	}{
+		{
+			l:      prosper.Listing{MemberKey: "ABC123", PriorProsperLoans: 2},
+			wantID: "member:ABC123",
+			wantOK: true,
+			msg:    "member key should identify the borrower",
+		},
+		{
+			l:      prosper.Listing{PriorProsperLoans: 2, PriorProsperLoansPrincipalBorrowed: 8500},
+			wantID: "",
+			wantOK: false,
+			msg:    "prior loans should not identify a borrower, since other borrowers may share them",
+		},
+		{
+			l:      prosper.Listing{},
+			wantID: "",
+			wantOK: false,
+			msg:    "new borrower without a member key cannot be identified",
+		},
+	}
+	for _, tt := range tests {
+		id, ok := BorrowerID(tt.l)
+		if id != tt.wantID || ok != tt.wantOK {
+			t.Errorf("%s: unexpected borrower. got = %q, %v, want = %q, %v", tt.msg, id, ok, tt.wantID, tt.wantOK)
+		}
+	}
+}
+
+// newBorrowerStore saves a borrower with a note, a pending bid, a failed bid
+// and a bid whose order is not saved yet.
+func newBorrowerStore(t *testing.T) Client {
+	r := NewMemoryStore(clock.DefaultClock{}).Client()
+	for _, n := range []string{"1", "2", "3", "4"} {
+		r.Set(KeyPrefixListing+n, `{"ListingNumber":`+n+`,"MemberKey":"ABC123"}`)
+	}
+	note, _ := EncodeNoteRecord(NoteRecord{Note: prosper.Note{LoanNoteID: "1-1", ListingNumber: 1, PrincipalBalanceProRataShare: 20.0}})
+	r.LPush(KeyPrefixNote+"1-1", note)
+	r.HSet(LineageKey(1), LineageFieldNotePrefix+"1-1", "1-1")
+	pending, _ := EncodeOrderRecord(OrderRecord{Order: prosper.OrderResponse{
+		OrderID:   "order-2",
+		BidStatus: []prosper.BidStatus{{BidRequest: prosper.BidRequest{ListingID: 2, BidAmount: 25.0}}},
+	}})
+	r.Set(KeyPrefixOrders+"order-2", pending)
+	r.HSet(LineageKey(2), LineageFieldOrder, "order-2")
+	failed, _ := EncodeOrderRecord(OrderRecord{Order: prosper.OrderResponse{
+		OrderID:   "order-3",
+		BidStatus: []prosper.BidStatus{{BidRequest: prosper.BidRequest{ListingID: 3, BidAmount: 25.0}, Result: prosper.BidFailed}},
+	}})
+	r.Set(KeyPrefixOrders+"order-3", failed)
+	r.HSet(LineageKey(3), LineageFieldOrder, "order-3")
+	for _, n := range []prosper.ListingNumber{1, 2, 3, 4} {
+		if err := RecordBorrowerBid(r, "member:ABC123", n, 25.0); err != nil {
+			t.Fatalf("failed to record bid: %v", err)
+		}
+	}
+	return r
+}
+
+func TestBorrowerExposure(t *testing.T) {
+	r := newBorrowerStore(t)
+	got, err := BorrowerExposure(r, "member:ABC123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Outstanding principal of the note, plus the pending bid and the bid
+	// without an order.
+	if want := 20.0 + 25.0 + 25.0; math.Abs(got-want) > 0.001 {
+		t.Errorf("unexpected exposure. got = %v, want = %v", got, want)
+	}
+	if got, err = BorrowerExposure(r, "member:XYZ"); err != nil || got != 0 {
+		t.Errorf("unknown borrower should have no exposure. got = %v, %v", got, err)
+	}
+}
+
+func TestIndexBorrowers(t *testing.T) {
+	r := newBorrowerStore(t)
+	r.Del(BorrowerKey("member:ABC123"))
+	// Notes bought before lineage was recorded, one of them for a listing
+	// whose snapshot has expired.
+	r.Set(KeyPrefixListing+"5", `{"ListingNumber":5,"MemberKey":"ABC123"}`)
+	for _, n := range []prosper.Note{
+		{LoanNoteID: "5-1", ListingNumber: 5, NoteOwnershipAmount: 25.0},
+		{LoanNoteID: "6-1", ListingNumber: 6, NoteOwnershipAmount: 25.0},
+	} {
+		note, _ := EncodeNoteRecord(NoteRecord{Note: n})
+		r.LPush(KeyPrefixNote+n.LoanNoteID, note)
+	}
+	indexed, skipped, err := IndexBorrowers(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Listing 4 has no order or note, so it cannot be found.
+	if indexed != 4 {
+		t.Errorf("unexpected number of listings indexed. got = %d, want = 4", indexed)
+	}
+	if want := []prosper.ListingNumber{6}; !reflect.DeepEqual(skipped, want) {
+		t.Errorf("unexpected skipped listings. got = %v, want = %v", skipped, want)
+	}
+	got, err := BorrowerExposure(r, "member:ABC123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := 20.0 + 25.0 + 25.0; math.Abs(got-want) > 0.001 {
+		t.Errorf("unexpected exposure after indexing. got = %v, want = %v", got, want)
+	}
+}
@@ -0,0 +1,235 @@
+package redis
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+)
+
+// RedisReader is the set of commands needed to read the bot's history.
+type RedisReader interface {
+	Get(key string) (string, error)
+	Keys(pattern string) ([]string, error)
+	LRange(key string, start int64, stop int64) ([]string, error)
+}
+
+var (
+	ErrListingNotFound = errors.New("listing not found")
+	ErrAccountNotFound = errors.New("no account information saved")
+)
+
+// AccountHistory returns every saved account record, oldest first.
+func AccountHistory(r RedisReader) ([]AccountRecord, error) {
+	serialized, err := r.LRange(KeyAccountInformation, 0, -1)
+	if err != nil {
+		return []AccountRecord{}, err
+	}
+	records := make([]AccountRecord, len(serialized))
+	for i, s := range serialized {
+		// Records are prepended, so reverse them into chronological order.
+		if records[len(serialized)-1-i], err = DecodeAccountRecord(s); err != nil {
+			return []AccountRecord{}, err
+		}
+	}
+	return records, nil
+}
+
+// LatestAccount returns the most recent account record.
+func LatestAccount(r RedisReader) (AccountRecord, error) {
+	serialized, err := r.LRange(KeyAccountInformation, 0, 0)
+	if err != nil {
+		return AccountRecord{}, err
+	}
+	if len(serialized) == 0 {
+		return AccountRecord{}, ErrAccountNotFound
+	}
+	return DecodeAccountRecord(serialized[0])
+}
+
+// NoteIDs returns the IDs of every note with saved history.
+func NoteIDs(r RedisReader) ([]string, error) {
+	keys, err := r.Keys(KeyPrefixNote + "*")
+	if err != nil {
+		return []string{}, err
+	}
+	ids := []string{}
+	for _, k := range keys {
+		ids = append(ids, strings.TrimPrefix(k, KeyPrefixNote))
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// NoteHistory returns every saved record for a note, oldest first. Entries
+// compacted into diffs are expanded back into full records.
+func NoteHistory(r RedisReader, loanNoteID string) ([]NoteRecord, error) {
+	serialized, err := r.LRange(KeyPrefixNote+loanNoteID, 0, -1)
+	if err != nil {
+		return []NoteRecord{}, err
+	}
+	return DecodeNoteHistory(serialized)
+}
+
+// AllNoteHistories returns the history of every saved note, keyed by note ID.
+func AllNoteHistories(r RedisReader) (map[string][]NoteRecord, error) {
+	ids, err := NoteIDs(r)
+	if err != nil {
+		return map[string][]NoteRecord{}, err
+	}
+	histories := map[string][]NoteRecord{}
+	for _, id := range ids {
+		h, err := NoteHistory(r, id)
+		if err != nil {
+			return map[string][]NoteRecord{}, fmt.Errorf("failed to read history for note %s: %v", id, err)
+		}
+		histories[id] = h
+	}
+	return histories, nil
+}
+
+// Orders returns the latest saved record of every order, ordered by order
+// date.
+func Orders(r RedisReader) ([]OrderRecord, error) {
+	keys, err := r.Keys(KeyPrefixOrders + "*")
+	if err != nil {
+		return []OrderRecord{}, err
+	}
+	records := []OrderRecord{}
+	for _, k := range keys {
+		serialized, err := r.Get(k)
+		if err != nil {
+			return []OrderRecord{}, err
+		}
+		record, err := DecodeOrderRecord(serialized)
+		if err != nil {
+			return []OrderRecord{}, fmt.Errorf("failed to parse %s: %v", k, err)
+		}
+		records = append(records, record)
+	}
+	sort.Sort(byOrderDate(records))
+	return records, nil
+}
+
+type byOrderDate []OrderRecord
+
+func (b byOrderDate) Len() int      { return len(b) }
+func (b byOrderDate) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b byOrderDate) Less(i, j int) bool {
+	return b[i].Order.OrderDate.Before(b[j].Order.OrderDate)
+}
+
+// Listing returns the saved snapshot of a listing.
+func Listing(r RedisGetter, n prosper.ListingNumber) (prosper.Listing, error) {
+	serialized, err := r.Get(fmt.Sprintf("%s%d", KeyPrefixListing, n))
+	if err != nil {
+		return prosper.Listing{}, err
+	}
+	if serialized == "" {
+		return prosper.Listing{}, ErrListingNotFound
+	}
+	var l prosper.Listing
+	if err = json.Unmarshal([]byte(serialized), &l); err != nil {
+		return prosper.Listing{}, err
+	}
+	return l, nil
+}
+
+func ListingHistoryKey(n prosper.ListingNumber) string {
+	return fmt.Sprintf("%s%d", KeyPrefixListingHistory, n)
+}
+
+// ListingHistory returns every distinct observation of a listing, oldest
+// first.
+func ListingHistory(r RedisReader, n prosper.ListingNumber) ([]ListingRecord, error) {
+	serialized, err := r.LRange(ListingHistoryKey(n), 0, -1)
+	if err != nil {
+		return []ListingRecord{}, err
+	}
+	records := make([]ListingRecord, len(serialized))
+	for i, s := range serialized {
+		// Records are prepended, so reverse them into chronological order.
+		if records[len(serialized)-1-i], err = DecodeListingRecord(s); err != nil {
+			return []ListingRecord{}, err
+		}
+	}
+	return records, nil
+}
+
+// scanBatchSize is how many keys to ask Redis to check in each SCAN call.
+const scanBatchSize = 1000
+
+// ForEachListing calls fn with every saved listing, in no particular order,
+// stopping at the first error. Keys are scanned in batches and listings are
+// read one at a time, so that the listings never have to fit in memory at once
+// and Redis is never blocked for long. A scan can return a key more than once,
+// so the keys already visited are remembered, and memory still grows with the
+// number of saved listings, by one key each.
+func ForEachListing(r RedisScanner, fn func(prosper.Listing) error) error {
+	visited := map[string]bool{}
+	return ForEachKey(r, KeyPrefixListing+"*", func(k string) error {
+		if visited[k] {
+			return nil
+		}
+		visited[k] = true
+		serialized, err := r.Get(k)
+		if err != nil {
+			return err
+		}
+		if serialized == "" {
+			// The listing expired since the key was scanned.
+			return nil
+		}
+		var l prosper.Listing
+		if err = json.Unmarshal([]byte(serialized), &l); err != nil {
+			return fmt.Errorf("failed to parse %s: %v", k, err)
+		}
+		return fn(l)
+	})
+}
+
+// ForEachKey calls fn with every key matching pattern, stopping at the first
+// error. Keys are scanned in batches so that Redis is never blocked for long,
+// and a key can be passed to fn more than once.
+func ForEachKey(r RedisScanner, pattern string, fn func(string) error) error {
+	cursor := int64(0)
+	for {
+		next, keys, err := r.Scan(cursor, "MATCH", pattern, "COUNT", scanBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if err = fn(k); err != nil {
+				return err
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// AccountAt returns the latest account record saved at or before t from a
+// chronological history.
+func AccountAt(history []AccountRecord, t time.Time) (AccountRecord, bool) {
+	i := sort.Search(len(history), func(i int) bool { return history[i].Timestamp.After(t) })
+	if i == 0 {
+		return AccountRecord{}, false
+	}
+	return history[i-1], true
+}
+
+// NoteAt returns the latest note record saved at or before t from a
+// chronological history.
+func NoteAt(history []NoteRecord, t time.Time) (NoteRecord, bool) {
+	i := sort.Search(len(history), func(i int) bool { return history[i].Timestamp.After(t) })
+	if i == 0 {
+		return NoteRecord{}, false
+	}
+	return history[i-1], true
+}
@@ -0,0 +1,259 @@
+package redis
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+)
+
+type mockRedisReader struct {
+	values map[string]string
+	lists  map[string][]string
+	hashes map[string]map[string]string
+	err    error
+}
+
+func (r mockRedisReader) HGetAll(key string) ([]string, error) {
+	fields := []string{}
+	for f, v := range r.hashes[key] {
+		fields = append(fields, f, v)
+	}
+	return fields, r.err
+}
+
+func (r mockRedisReader) Get(key string) (string, error) {
+	return r.values[key], r.err
+}
+
+func (r mockRedisReader) Keys(pattern string) ([]string, error) {
+	prefix := pattern[:len(pattern)-1]
+	keys := []string{}
+	for k := range r.values {
+		if len(k) >= len(prefix) && k[:len(prefix)] == prefix {
+			keys = append(keys, k)
+		}
+	}
+	for k := range r.lists {
+		if len(k) >= len(prefix) && k[:len(prefix)] == prefix {
+			keys = append(keys, k)
+		}
+	}
+	return keys, r.err
+}
+
+// Scan returns one key per call, along with the key from the previous call,
+// since Redis may return a key more than once.
+func (r mockRedisReader) Scan(cursor int64, arguments ...interface{}) (int64, []string, error) {
+	keys, err := r.Keys(arguments[1].(string))
+	sort.Strings(keys)
+	if cursor >= int64(len(keys)) {
+		return 0, []string{}, err
+	}
+	page := keys[cursor : cursor+1]
+	if cursor > 0 {
+		page = keys[cursor-1 : cursor+1]
+	}
+	if cursor+1 == int64(len(keys)) {
+		return 0, page, err
+	}
+	return cursor + 1, page, err
+}
+
+func (r mockRedisReader) LRange(key string, start int64, stop int64) ([]string, error) {
+	return r.lists[key], r.err
+}
+
+var (
+	t1 = time.Date(2016, 5, 1, 12, 0, 0, 0, time.UTC)
+	t2 = time.Date(2016, 5, 2, 12, 0, 0, 0, time.UTC)
+	t3 = time.Date(2016, 5, 3, 12, 0, 0, 0, time.UTC)
+)
+
+func TestNoteHistory(t *testing.T) {
+	r := mockRedisReader{
+		lists: map[string][]string{
+			"note:1619-2": {
+				`{"Note":{"LoanNoteID":"1619-2","AgeInMonths":2},"Timestamp":"2016-05-02T12:00:00Z"}`,
+				`{"Note":{"LoanNoteID":"1619-2","AgeInMonths":1},"Timestamp":"2016-05-01T12:00:00Z"}`,
+			},
+		},
+	}
+	got, err := NoteHistory(r, "1619-2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []NoteRecord{
+		{Note: prosper.Note{LoanNoteID: "1619-2", AgeInMonths: 1}, Timestamp: t1, Version: SchemaVersion},
+		{Note: prosper.Note{LoanNoteID: "1619-2", AgeInMonths: 2}, Timestamp: t2, Version: SchemaVersion},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected note history. got: %+v, want: %+v", got, want)
+	}
+}
+
+func TestNoteHistoryErrors(t *testing.T) {
+	if _, err := NoteHistory(mockRedisReader{err: errors.New("mock redis error")}, "1619-2"); err == nil {
+		t.Errorf("expected redis error to be returned")
+	}
+	r := mockRedisReader{
+		lists: map[string][]string{"note:1619-2": {"{{mock bad JSON"}},
+	}
+	if _, err := NoteHistory(r, "1619-2"); err == nil {
+		t.Errorf("expected bad JSON to return an error")
+	}
+}
+
+func TestNoteIDs(t *testing.T) {
+	r := mockRedisReader{
+		values: map[string]string{"listing:123": "{}"},
+		lists: map[string][]string{
+			"note:2000-1":        {},
+			"note:1619-2":        {},
+			"accountInformation": {},
+		},
+	}
+	got, err := NoteIDs(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"1619-2", "2000-1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected note IDs. got: %v, want: %v", got, want)
+	}
+}
+
+func TestOrders(t *testing.T) {
+	r := mockRedisReader{
+		values: map[string]string{
+			"order:id-b":  `{"Order":{"OrderID":"id-b","OrderDate":"2016-05-02T12:00:00Z"},"Timestamp":"2016-05-02T12:00:00Z"}`,
+			"order:id-a":  `{"Order":{"OrderID":"id-a","OrderDate":"2016-05-01T12:00:00Z"},"Timestamp":"2016-05-01T12:00:00Z"}`,
+			"listing:123": "{}",
+		},
+	}
+	got, err := Orders(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []OrderRecord{
+		{Order: prosper.OrderResponse{OrderID: "id-a", OrderDate: t1}, Timestamp: t1, Version: SchemaVersion},
+		{Order: prosper.OrderResponse{OrderID: "id-b", OrderDate: t2}, Timestamp: t2, Version: SchemaVersion},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected orders. got: %+v, want: %+v", got, want)
+	}
+}
+
+func TestLatestAccount(t *testing.T) {
+	older, _ := EncodeAccountRecord(AccountRecord{Value: prosper.AccountInformation{AvailableCashBalance: 10.0}, Timestamp: t1})
+	newer, _ := EncodeAccountRecord(AccountRecord{Value: prosper.AccountInformation{AvailableCashBalance: 20.0}, Timestamp: t2})
+	r := mockRedisReader{
+		lists: map[string][]string{KeyAccountInformation: {newer, older}},
+	}
+	got, err := LatestAccount(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Value.AvailableCashBalance != 20.0 || !got.Timestamp.Equal(t2) {
+		t.Errorf("unexpected account. got: %+v, want the record from %v", got, t2)
+	}
+	if _, err = LatestAccount(mockRedisReader{}); err != ErrAccountNotFound {
+		t.Errorf("unexpected error for missing account. got: %v, want: %v", err, ErrAccountNotFound)
+	}
+}
+
+func TestListing(t *testing.T) {
+	r := mockRedisReader{
+		values: map[string]string{
+			"listing:123": `{"ListingNumber":123}`,
+		},
+	}
+	got, err := Listing(r, 123)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.ListingNumber != 123 {
+		t.Errorf("unexpected listing. got: %+v, want listing number 123", got)
+	}
+	if _, err = Listing(r, 456); err != ErrListingNotFound {
+		t.Errorf("unexpected error for missing listing. got: %v, want: %v", err, ErrListingNotFound)
+	}
+}
+
+func TestNoteAt(t *testing.T) {
+	history := []NoteRecord{
+		{Note: prosper.Note{AgeInMonths: 1}, Timestamp: t1, Version: SchemaVersion},
+		{Note: prosper.Note{AgeInMonths: 2}, Timestamp: t2, Version: SchemaVersion},
+		{Note: prosper.Note{AgeInMonths: 3}, Timestamp: t3},
+	}
+	var tests = []struct {
+		t       time.Time
+		wantAge int
+		wantOK  bool
+	}{
+		{t1.Add(-time.Second), 0, false},
+		{t1, 1, true},
+		{t2.Add(-time.Second), 1, true},
+		{t2, 2, true},
+		{t3.Add(time.Hour), 3, true},
+	}
+	for _, tt := range tests {
+		got, ok := NoteAt(history, tt.t)
+		if ok != tt.wantOK {
+			t.Errorf("unexpected result for %v. got: %v, want: %v", tt.t, ok, tt.wantOK)
+			continue
+		}
+		if int(got.Note.AgeInMonths) != tt.wantAge {
+			t.Errorf("unexpected note for %v. got age: %d, want: %d", tt.t, got.Note.AgeInMonths, tt.wantAge)
+		}
+	}
+}
+
+func TestAccountAt(t *testing.T) {
+	history := []AccountRecord{
+		{Value: prosper.AccountInformation{TotalAccountValue: 100}, Timestamp: t1},
+		{Value: prosper.AccountInformation{TotalAccountValue: 200}, Timestamp: t2},
+	}
+	if _, ok := AccountAt(history, t1.Add(-time.Second)); ok {
+		t.Errorf("expected no account record before the first one")
+	}
+	got, ok := AccountAt(history, t2.Add(time.Second))
+	if !ok || got.Value.TotalAccountValue != 200 {
+		t.Errorf("unexpected account record. got: %+v, want value 200", got)
+	}
+}
+
+func TestForEachListing(t *testing.T) {
+	r := mockRedisReader{
+		values: map[string]string{
+			"listing:456": `{"ListingNumber":456}`,
+			"listing:123": `{"ListingNumber":123}`,
+			"listing:789": `{"ListingNumber":789}`,
+			"order:id-a":  "{}",
+		},
+	}
+	got := []prosper.ListingNumber{}
+	err := ForEachListing(r, func(l prosper.Listing) error {
+		got = append(got, l.ListingNumber)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []prosper.ListingNumber{123, 456, 789}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected each listing exactly once. got: %v, want: %v", got, want)
+	}
+	stopErr := errors.New("mock stop error")
+	calls := 0
+	err = ForEachListing(r, func(l prosper.Listing) error {
+		calls++
+		return stopErr
+	})
+	if err != stopErr || calls != 1 {
+		t.Errorf("expected iteration to stop at the first error. got err: %v after %d calls", err, calls)
+	}
+}
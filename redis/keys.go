@@ -2,7 +2,61 @@ package redis
 
 const (
 	KeyAccountInformation = "accountInformation"
-	KeyPrefixListing      = "listing:"
-	KeyPrefixNote         = "note:"
-	KeyPrefixOrders       = "order:"
+	// KeySeenListings is a set of the numbers of every listing whose snapshot
+	// has been expired, kept so that the bot never treats them as new.
+	KeySeenListings = "seenListings"
+	// KeyReconciliationReports is a list of reconciliation reports, newest
+	// first.
+	KeyReconciliationReports = "reconciliationReports"
+	KeyPrefixListing         = "listing:"
+	KeyPrefixNote            = "note:"
+	KeyPrefixOrders          = "order:"
+	KeyPrefixNotification    = "notification:"
+	KeyPrefixNoteIndex       = "noteIndex:"
+	KeyPrefixLineage         = "lineage:"
+	// KeyPrefixBorrower is the prefix of a hash per borrower, from the number
+	// of each listing the bot bid on to the amount of the bid.
+	KeyPrefixBorrower = "borrower:"
+	// KeyPrefixListingHistory is the prefix of a list per listing of every
+	// distinct observation of it, newest first. The listing: key keeps the
+	// first observation.
+	KeyPrefixListingHistory = "listingHistory:"
+	// KeyPrefixReevaluation is the prefix of a set per day of the numbers of
+	// listings that started that day and that the bot declined, so that they
+	// can be reconsidered while they are still active.
+	KeyPrefixReevaluation = "reevaluate:"
+	// KeyReevaluationPolicy is a fingerprint of the buying rules that declined
+	// listings were last evaluated under.
+	KeyReevaluationPolicy = "reevaluationPolicy"
+	// KeyPendingNotifications is a list of the alerts waiting to be sent in
+	// the current batch, oldest first.
+	KeyPendingNotifications = "pendingNotifications"
+	// KeyWatchedListings is a set of the numbers of listings that may still
+	// be active, which the bot searches for again to follow their funding.
+	KeyWatchedListings = "watchedListings"
+	// KeyPrefixBidClaim is the prefix of a key per listing that the bot is
+	// bidding on, set before the bid is placed so that no listing is bid on
+	// twice, even if a bid fails.
+	KeyPrefixBidClaim = "bid:"
 )
+
+// KeyPatterns match every key that the bot saves. Backups only include keys
+// that match, so every new key or prefix must be added here.
+var KeyPatterns = []string{
+	KeyAccountInformation,
+	KeySeenListings,
+	KeyReconciliationReports,
+	KeyReevaluationPolicy,
+	KeyPendingNotifications,
+	KeyWatchedListings,
+	KeyPrefixListing + "*",
+	KeyPrefixNote + "*",
+	KeyPrefixOrders + "*",
+	KeyPrefixNotification + "*",
+	KeyPrefixNoteIndex + "*",
+	KeyPrefixLineage + "*",
+	KeyPrefixBorrower + "*",
+	KeyPrefixListingHistory + "*",
+	KeyPrefixReevaluation + "*",
+	KeyPrefixBidClaim + "*",
+}
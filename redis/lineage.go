@@ -0,0 +1,174 @@
+package redis
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+)
+
+// A lineage record is a hash per listing number. Each component that handles
+// the listing sets its own fields, so no component has to read the record
+// before writing to it.
+const (
+	LineageFieldDecision   = "decision"
+	LineageFieldOrder      = "order"
+	LineageFieldNotePrefix = "note:"
+)
+
+var (
+	ErrLineageNotFound  = errors.New("no lineage found")
+	ErrDecisionNotFound = errors.New("no decision found")
+)
+
+// Decision is the bot's verdict on whether to bid on a listing.
+type Decision struct {
+	Passed bool
+	// Reason explains why the listing was rejected.
+	Reason string `json:",omitempty"`
+	// Score is the listing's score, if the bot ranks listings.
+	Score     *float64 `json:",omitempty"`
+	Timestamp time.Time
+}
+
+// Lineage ties together everything the bot saved about one listing.
+type Lineage struct {
+	ListingNumber prosper.ListingNumber
+	Listing       *prosper.Listing `json:",omitempty"`
+	Decision      *Decision        `json:",omitempty"`
+	Order         *OrderRecord     `json:",omitempty"`
+	// Bid is the status of the bid on this listing within Order.
+	Bid   *prosper.BidStatus `json:",omitempty"`
+	Notes []prosper.Note
+}
+
+func LineageKey(n prosper.ListingNumber) string {
+	return fmt.Sprintf("%s%d", KeyPrefixLineage, n)
+}
+
+func fieldsToMap(fields []string) map[string]string {
+	m := map[string]string{}
+	for i := 0; i+1 < len(fields); i += 2 {
+		m[fields[i]] = fields[i+1]
+	}
+	return m
+}
+
+// DecisionForListing returns the bot's most recent decision on a listing.
+func DecisionForListing(r RedisHashGetter, n prosper.ListingNumber) (Decision, error) {
+	fields, err := r.HGetAll(LineageKey(n))
+	if err != nil {
+		return Decision{}, err
+	}
+	s, ok := fieldsToMap(fields)[LineageFieldDecision]
+	if !ok {
+		return Decision{}, ErrDecisionNotFound
+	}
+	var d Decision
+	if err = json.Unmarshal([]byte(s), &d); err != nil {
+		return Decision{}, fmt.Errorf("failed to parse decision for listing %d: %v", n, err)
+	}
+	return d, nil
+}
+
+// LineageForListing gathers the lineage of a listing.
+func LineageForListing(r RedisLineageReader, n prosper.ListingNumber) (Lineage, error) {
+	fields, err := r.HGetAll(LineageKey(n))
+	if err != nil {
+		return Lineage{}, err
+	}
+	lineage := Lineage{ListingNumber: n, Notes: []prosper.Note{}}
+	if l, err := Listing(r, n); err == nil {
+		lineage.Listing = &l
+	} else if err != ErrListingNotFound {
+		return Lineage{}, err
+	}
+	record := fieldsToMap(fields)
+	if s, ok := record[LineageFieldDecision]; ok {
+		var d Decision
+		if err = json.Unmarshal([]byte(s), &d); err != nil {
+			return Lineage{}, fmt.Errorf("failed to parse decision for listing %d: %v", n, err)
+		}
+		lineage.Decision = &d
+	}
+	if id, ok := record[LineageFieldOrder]; ok {
+		serialized, err := r.Get(KeyPrefixOrders + id)
+		if err != nil {
+			return Lineage{}, err
+		}
+		if serialized != "" {
+			o, err := DecodeOrderRecord(serialized)
+			if err != nil {
+				return Lineage{}, fmt.Errorf("failed to parse order %s: %v", id, err)
+			}
+			lineage.Order = &o
+			for i, b := range o.Order.BidStatus {
+				if b.ListingID == n {
+					lineage.Bid = &o.Order.BidStatus[i]
+				}
+			}
+		}
+	}
+	noteIDs := []string{}
+	for field := range record {
+		if strings.HasPrefix(field, LineageFieldNotePrefix) {
+			noteIDs = append(noteIDs, strings.TrimPrefix(field, LineageFieldNotePrefix))
+		}
+	}
+	sort.Strings(noteIDs)
+	for _, id := range noteIDs {
+		latest, _, err := LatestNoteRecord(r, id)
+		if err == ErrNoteNotFound {
+			continue
+		} else if err != nil {
+			return Lineage{}, err
+		}
+		lineage.Notes = append(lineage.Notes, latest.Note)
+	}
+	if lineage.Listing == nil && len(record) == 0 {
+		return Lineage{}, ErrLineageNotFound
+	}
+	return lineage, nil
+}
+
+// LineageForOrder gathers the lineage of every listing bid on in an order.
+func LineageForOrder(r RedisLineageReader, id prosper.OrderID) ([]Lineage, error) {
+	serialized, err := r.Get(KeyPrefixOrders + string(id))
+	if err != nil {
+		return []Lineage{}, err
+	}
+	if serialized == "" {
+		return []Lineage{}, ErrLineageNotFound
+	}
+	o, err := DecodeOrderRecord(serialized)
+	if err != nil {
+		return []Lineage{}, err
+	}
+	lineages := []Lineage{}
+	for _, b := range o.Order.BidStatus {
+		l, err := LineageForListing(r, b.ListingID)
+		if err == ErrLineageNotFound {
+			l = Lineage{ListingNumber: b.ListingID, Notes: []prosper.Note{}}
+		} else if err != nil {
+			return []Lineage{}, err
+		}
+		lineages = append(lineages, l)
+	}
+	return lineages, nil
+}
+
+// LineageForNote gathers the lineage of the listing a note came from.
+func LineageForNote(r RedisLineageReader, loanNoteID string) (Lineage, error) {
+	history, err := NoteHistory(r, loanNoteID)
+	if err != nil {
+		return Lineage{}, err
+	}
+	if len(history) == 0 {
+		return Lineage{}, ErrLineageNotFound
+	}
+	return LineageForListing(r, history[len(history)-1].Note.ListingNumber)
+}
@@ -0,0 +1,116 @@
+package redis
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+)
+
+func newLineageReader() mockRedisReader {
+	return mockRedisReader{
+		values: map[string]string{
+			"listing:123": `{"ListingNumber":123}`,
+			"order:id-a":  `{"Order":{"OrderID":"id-a","BidStatus":[{"ListingID":123,"BidAmount":25},{"ListingID":456,"BidAmount":25}]},"Timestamp":"2016-05-01T12:00:00Z","Version":1}`,
+			"listing:456": `{"ListingNumber":456}`,
+			"listing:789": `{"ListingNumber":789}`,
+		},
+		lists: map[string][]string{
+			"note:123-1": {`{"Note":{"LoanNoteID":"123-1","ListingNumber":123},"Timestamp":"2016-05-02T12:00:00Z","Version":1}`},
+		},
+		hashes: map[string]map[string]string{
+			"lineage:123": {
+				"decision":   `{"Passed":true,"Timestamp":"2016-05-01T12:00:00Z"}`,
+				"order":      "id-a",
+				"note:123-1": "123-1",
+			},
+			"lineage:789": {
+				"decision": `{"Passed":false,"Reason":"CurrentDelinquencies out of range: 2","Timestamp":"2016-05-01T12:00:00Z"}`,
+			},
+		},
+	}
+}
+
+func TestLineageForListing(t *testing.T) {
+	r := newLineageReader()
+	got, err := LineageForListing(r, 123)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Listing == nil || got.Listing.ListingNumber != 123 {
+		t.Errorf("unexpected listing: %+v", got.Listing)
+	}
+	if got.Decision == nil || !got.Decision.Passed || !got.Decision.Timestamp.Equal(t1) {
+		t.Errorf("unexpected decision: %+v", got.Decision)
+	}
+	if got.Order == nil || got.Order.Order.OrderID != "id-a" {
+		t.Errorf("unexpected order: %+v", got.Order)
+	}
+	if got.Bid == nil || got.Bid.ListingID != 123 {
+		t.Errorf("unexpected bid: %+v", got.Bid)
+	}
+	wantNotes := []prosper.Note{{LoanNoteID: "123-1", ListingNumber: 123}}
+	if !reflect.DeepEqual(got.Notes, wantNotes) {
+		t.Errorf("unexpected notes. got: %+v, want: %+v", got.Notes, wantNotes)
+	}
+
+	rejected, err := LineageForListing(r, 789)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rejected.Decision == nil || rejected.Decision.Passed || rejected.Decision.Reason != "CurrentDelinquencies out of range: 2" {
+		t.Errorf("unexpected decision: %+v", rejected.Decision)
+	}
+	if rejected.Order != nil {
+		t.Errorf("expected no order for a rejected listing, got: %+v", rejected.Order)
+	}
+
+	if _, err = LineageForListing(r, 999); err != ErrLineageNotFound {
+		t.Errorf("unexpected error for unknown listing. got: %v, want: %v", err, ErrLineageNotFound)
+	}
+}
+
+func TestDecisionForListing(t *testing.T) {
+	r := newLineageReader()
+	got, err := DecisionForListing(r, 789)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Passed || got.Reason != "CurrentDelinquencies out of range: 2" {
+		t.Errorf("unexpected decision: %+v", got)
+	}
+	if _, err = DecisionForListing(r, 456); err != ErrDecisionNotFound {
+		t.Errorf("unexpected error for listing without a decision. got: %v, want: %v", err, ErrDecisionNotFound)
+	}
+}
+
+func TestLineageForOrder(t *testing.T) {
+	r := newLineageReader()
+	got, err := LineageForOrder(r, "id-a")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 || got[0].ListingNumber != 123 || got[1].ListingNumber != 456 {
+		t.Fatalf("unexpected lineages: %+v", got)
+	}
+	if got[1].Decision != nil || got[1].Listing == nil {
+		t.Errorf("listing without a lineage record should still include its snapshot. got: %+v", got[1])
+	}
+	if _, err = LineageForOrder(r, "id-missing"); err != ErrLineageNotFound {
+		t.Errorf("unexpected error for unknown order. got: %v, want: %v", err, ErrLineageNotFound)
+	}
+}
+
+func TestLineageForNote(t *testing.T) {
+	r := newLineageReader()
+	got, err := LineageForNote(r, "123-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.ListingNumber != 123 || got.Order == nil {
+		t.Errorf("unexpected lineage: %+v", got)
+	}
+	if _, err = LineageForNote(r, "999-1"); err != ErrLineageNotFound {
+		t.Errorf("unexpected error for unknown note. got: %v, want: %v", err, ErrLineageNotFound)
+	}
+}
@@ -0,0 +1,574 @@
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mtlynch/prosperbot/clock"
+)
+
+const (
+	typeString = "string"
+	typeList   = "list"
+	typeSet    = "set"
+	typeHash   = "hash"
+	typeNone   = "none"
+)
+
+var (
+	errWrongType   = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+	errNestedMulti = errors.New("ERR MULTI calls can not be nested")
+	errExecNoMulti = errors.New("ERR EXEC without MULTI")
+	errHMSetArgs   = errors.New("ERR wrong number of arguments for 'hmset' command")
+	errSyntax      = errors.New("ERR syntax error")
+)
+
+// MemoryStore is an in-memory stand-in for a Redis server. It implements the
+// commands in Client with Redis semantics, including expiry, which follows the
+// store's clock.
+type MemoryStore struct {
+	mu     sync.Mutex
+	clock  clock.Clock
+	values map[string]*memoryValue
+}
+
+type memoryValue struct {
+	kind string
+	str  string
+	list []string
+	set  map[string]bool
+	hash map[string]string
+	// expires is when the key expires, or zero if it never does.
+	expires time.Time
+}
+
+// NewMemoryStore creates an empty store that tells time with c.
+func NewMemoryStore(c clock.Clock) *MemoryStore {
+	return &MemoryStore{clock: c, values: map[string]*memoryValue{}}
+}
+
+// Client returns a new connection to the store. Like a connection to a Redis
+// server, each client has its own transaction state, so each goroutine should
+// use its own client.
+func (s *MemoryStore) Client() Client {
+	return &memoryClient{store: s}
+}
+
+// lookup returns the value at key, removing it first if it has expired. The
+// caller must hold s.mu.
+func (s *MemoryStore) lookup(key string) *memoryValue {
+	v, ok := s.values[key]
+	if !ok {
+		return nil
+	}
+	if !v.expires.IsZero() && !s.clock.Now().Before(v.expires) {
+		delete(s.values, key)
+		return nil
+	}
+	return v
+}
+
+// lookupKind returns the value at key if it has the given type, or nil if the
+// key doesn't exist. The caller must hold s.mu.
+func (s *MemoryStore) lookupKind(key, kind string) (*memoryValue, error) {
+	v := s.lookup(key)
+	if v != nil && v.kind != kind {
+		return nil, errWrongType
+	}
+	return v, nil
+}
+
+// create returns the value at key, creating an empty one of the given type if
+// the key doesn't exist. The caller must hold s.mu.
+func (s *MemoryStore) create(key, kind string) (*memoryValue, error) {
+	v, err := s.lookupKind(key, kind)
+	if err != nil || v != nil {
+		return v, err
+	}
+	v = &memoryValue{kind: kind, set: map[string]bool{}, hash: map[string]string{}}
+	s.values[key] = v
+	return v, nil
+}
+
+// removeIfEmpty deletes a list, set or hash with no elements, as Redis does.
+// The caller must hold s.mu.
+func (s *MemoryStore) removeIfEmpty(key string, v *memoryValue) {
+	switch {
+	case v.kind == typeList && len(v.list) == 0,
+		v.kind == typeSet && len(v.set) == 0,
+		v.kind == typeHash && len(v.hash) == 0:
+		delete(s.values, key)
+	}
+}
+
+// toString converts a command argument the way the Redis client sends it.
+func toString(v interface{}) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case []byte:
+		return string(s)
+	}
+	return fmt.Sprint(v)
+}
+
+// listRange converts Redis start and stop indexes, which may count back from
+// the end, to slice bounds. ok is false if the range is empty.
+func listRange(length, start, stop int64) (int64, int64, bool) {
+	if start < 0 {
+		start += length
+	}
+	if stop < 0 {
+		stop += length
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= length {
+		stop = length - 1
+	}
+	if start > stop || start >= length {
+		return 0, 0, false
+	}
+	return start, stop + 1, true
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+type memoryClient struct {
+	store *MemoryStore
+	// queue holds the commands of an open transaction, or is nil outside one.
+	queue []func() (interface{}, error)
+}
+
+// do runs a command, or queues it if a transaction is open. Queued commands
+// return zero values; their results come from Exec.
+func (c *memoryClient) do(command func() (interface{}, error)) (interface{}, error) {
+	if c.queue != nil {
+		c.queue = append(c.queue, command)
+		return nil, nil
+	}
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+	return command()
+}
+
+func (c *memoryClient) Multi() (string, error) {
+	if c.queue != nil {
+		return "", errNestedMulti
+	}
+	c.queue = []func() (interface{}, error){}
+	return "OK", nil
+}
+
+// Exec runs the queued commands atomically. As with Redis, a failing command
+// doesn't stop the rest; its error is returned in its place among the replies.
+func (c *memoryClient) Exec() ([]interface{}, error) {
+	if c.queue == nil {
+		return nil, errExecNoMulti
+	}
+	queue := c.queue
+	c.queue = nil
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+	replies := []interface{}{}
+	for _, command := range queue {
+		reply, err := command()
+		if err != nil {
+			replies = append(replies, err)
+		} else {
+			replies = append(replies, reply)
+		}
+	}
+	return replies, nil
+}
+
+func (c *memoryClient) Quit() (string, error) {
+	return "OK", nil
+}
+
+func (c *memoryClient) Get(key string) (string, error) {
+	reply, err := c.do(func() (interface{}, error) {
+		v, err := c.store.lookupKind(key, typeString)
+		if err != nil || v == nil {
+			return "", err
+		}
+		return v.str, nil
+	})
+	s, _ := reply.(string)
+	return s, err
+}
+
+func (c *memoryClient) Set(key string, value interface{}) (string, error) {
+	reply, err := c.do(func() (interface{}, error) {
+		c.store.values[key] = &memoryValue{kind: typeString, str: toString(value)}
+		return "OK", nil
+	})
+	s, _ := reply.(string)
+	return s, err
+}
+
+func (c *memoryClient) SetNX(key string, value interface{}) (bool, error) {
+	reply, err := c.do(func() (interface{}, error) {
+		if c.store.lookup(key) != nil {
+			return false, nil
+		}
+		c.store.values[key] = &memoryValue{kind: typeString, str: toString(value)}
+		return true, nil
+	})
+	b, _ := reply.(bool)
+	return b, err
+}
+
+func (c *memoryClient) Del(keys ...string) (int64, error) {
+	reply, err := c.do(func() (interface{}, error) {
+		var removed int64
+		for _, k := range keys {
+			if c.store.lookup(k) != nil {
+				delete(c.store.values, k)
+				removed++
+			}
+		}
+		return removed, nil
+	})
+	n, _ := reply.(int64)
+	return n, err
+}
+
+func (c *memoryClient) Keys(pattern string) ([]string, error) {
+	reply, err := c.do(func() (interface{}, error) {
+		keys := []string{}
+		for k := range c.store.values {
+			if c.store.lookup(k) == nil {
+				continue
+			}
+			if matched, _ := path.Match(pattern, k); matched {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		return keys, nil
+	})
+	keys, _ := reply.([]string)
+	return keys, err
+}
+
+// Scan pages through the keys in order. The cursor is the number of keys
+// already returned, so, as with Redis, keys added or removed during a scan
+// may be missed or returned twice. MATCH and COUNT are supported.
+func (c *memoryClient) Scan(cursor int64, arguments ...interface{}) (int64, []string, error) {
+	pattern := "*"
+	count := int64(10)
+	if len(arguments)%2 != 0 {
+		return 0, nil, errSyntax
+	}
+	for i := 0; i < len(arguments); i += 2 {
+		switch fmt.Sprint(arguments[i]) {
+		case "MATCH":
+			pattern = fmt.Sprint(arguments[i+1])
+		case "COUNT":
+			n, ok := arguments[i+1].(int)
+			if !ok || n <= 0 {
+				return 0, nil, errSyntax
+			}
+			count = int64(n)
+		default:
+			return 0, nil, errSyntax
+		}
+	}
+	all, err := c.Keys(pattern)
+	if err != nil {
+		return 0, nil, err
+	}
+	if cursor < 0 || cursor > int64(len(all)) {
+		cursor = int64(len(all))
+	}
+	end := cursor + count
+	if end >= int64(len(all)) {
+		return 0, all[cursor:], nil
+	}
+	return end, all[cursor:end], nil
+}
+
+func (c *memoryClient) Type(key string) (string, error) {
+	reply, err := c.do(func() (interface{}, error) {
+		v := c.store.lookup(key)
+		if v == nil {
+			return typeNone, nil
+		}
+		return v.kind, nil
+	})
+	s, _ := reply.(string)
+	return s, err
+}
+
+func (c *memoryClient) Exists(key string) (bool, error) {
+	reply, err := c.do(func() (interface{}, error) {
+		return c.store.lookup(key) != nil, nil
+	})
+	b, _ := reply.(bool)
+	return b, err
+}
+
+// TTL returns the seconds until key expires, -1 if it never expires or -2 if
+// it doesn't exist.
+func (c *memoryClient) TTL(key string) (int64, error) {
+	reply, err := c.do(func() (interface{}, error) {
+		v := c.store.lookup(key)
+		if v == nil {
+			return int64(-2), nil
+		}
+		if v.expires.IsZero() {
+			return int64(-1), nil
+		}
+		remaining := v.expires.Sub(c.store.clock.Now())
+		return int64((remaining + time.Second/2) / time.Second), nil
+	})
+	n, _ := reply.(int64)
+	return n, err
+}
+
+func (c *memoryClient) Expire(key string, seconds uint64) (bool, error) {
+	reply, err := c.do(func() (interface{}, error) {
+		v := c.store.lookup(key)
+		if v == nil {
+			return false, nil
+		}
+		v.expires = c.store.clock.Now().Add(time.Duration(seconds) * time.Second)
+		return true, nil
+	})
+	b, _ := reply.(bool)
+	return b, err
+}
+
+func (c *memoryClient) LRange(key string, start int64, stop int64) ([]string, error) {
+	reply, err := c.do(func() (interface{}, error) {
+		v, err := c.store.lookupKind(key, typeList)
+		if err != nil || v == nil {
+			return []string{}, err
+		}
+		from, to, ok := listRange(int64(len(v.list)), start, stop)
+		if !ok {
+			return []string{}, nil
+		}
+		return append([]string{}, v.list[from:to]...), nil
+	})
+	values, _ := reply.([]string)
+	return values, err
+}
+
+// LPush inserts values at the head of the list one after another, so the last
+// value ends up first.
+func (c *memoryClient) LPush(key string, values ...interface{}) (int64, error) {
+	reply, err := c.do(func() (interface{}, error) {
+		v, err := c.store.create(key, typeList)
+		if err != nil {
+			return int64(0), err
+		}
+		for _, value := range values {
+			v.list = append([]string{toString(value)}, v.list...)
+		}
+		return int64(len(v.list)), nil
+	})
+	n, _ := reply.(int64)
+	return n, err
+}
+
+func (c *memoryClient) RPush(key string, values ...interface{}) (int64, error) {
+	reply, err := c.do(func() (interface{}, error) {
+		v, err := c.store.create(key, typeList)
+		if err != nil {
+			return int64(0), err
+		}
+		for _, value := range values {
+			v.list = append(v.list, toString(value))
+		}
+		return int64(len(v.list)), nil
+	})
+	n, _ := reply.(int64)
+	return n, err
+}
+
+func (c *memoryClient) LTrim(key string, start int64, stop int64) (string, error) {
+	reply, err := c.do(func() (interface{}, error) {
+		v, err := c.store.lookupKind(key, typeList)
+		if err != nil || v == nil {
+			return "OK", err
+		}
+		from, to, ok := listRange(int64(len(v.list)), start, stop)
+		if ok {
+			v.list = append([]string{}, v.list[from:to]...)
+		} else {
+			v.list = []string{}
+		}
+		c.store.removeIfEmpty(key, v)
+		return "OK", nil
+	})
+	s, _ := reply.(string)
+	return s, err
+}
+
+func (c *memoryClient) SAdd(key string, members ...interface{}) (int64, error) {
+	reply, err := c.do(func() (interface{}, error) {
+		v, err := c.store.create(key, typeSet)
+		if err != nil {
+			return int64(0), err
+		}
+		var added int64
+		for _, m := range members {
+			if s := toString(m); !v.set[s] {
+				v.set[s] = true
+				added++
+			}
+		}
+		return added, nil
+	})
+	n, _ := reply.(int64)
+	return n, err
+}
+
+func (c *memoryClient) SRem(key string, members ...interface{}) (int64, error) {
+	reply, err := c.do(func() (interface{}, error) {
+		v, err := c.store.lookupKind(key, typeSet)
+		if err != nil || v == nil {
+			return int64(0), err
+		}
+		var removed int64
+		for _, m := range members {
+			if s := toString(m); v.set[s] {
+				delete(v.set, s)
+				removed++
+			}
+		}
+		c.store.removeIfEmpty(key, v)
+		return removed, nil
+	})
+	n, _ := reply.(int64)
+	return n, err
+}
+
+func (c *memoryClient) SIsMember(key string, member interface{}) (bool, error) {
+	reply, err := c.do(func() (interface{}, error) {
+		v, err := c.store.lookupKind(key, typeSet)
+		if err != nil || v == nil {
+			return false, err
+		}
+		return v.set[toString(member)], nil
+	})
+	b, _ := reply.(bool)
+	return b, err
+}
+
+// SMembers returns the members of a set in sorted order.
+func (c *memoryClient) SMembers(key string) ([]string, error) {
+	reply, err := c.do(func() (interface{}, error) {
+		v, err := c.store.lookupKind(key, typeSet)
+		if err != nil || v == nil {
+			return []string{}, err
+		}
+		return sortedKeys(v.set), nil
+	})
+	members, _ := reply.([]string)
+	return members, err
+}
+
+// SInter returns the members common to every set in sorted order. A key that
+// doesn't exist counts as an empty set.
+func (c *memoryClient) SInter(keys ...string) ([]string, error) {
+	reply, err := c.do(func() (interface{}, error) {
+		if len(keys) == 0 {
+			return []string{}, nil
+		}
+		common := map[string]bool{}
+		for i, k := range keys {
+			v, err := c.store.lookupKind(k, typeSet)
+			if err != nil {
+				return []string{}, err
+			}
+			if v == nil {
+				return []string{}, nil
+			}
+			if i == 0 {
+				for m := range v.set {
+					common[m] = true
+				}
+				continue
+			}
+			for m := range common {
+				if !v.set[m] {
+					delete(common, m)
+				}
+			}
+		}
+		return sortedKeys(common), nil
+	})
+	members, _ := reply.([]string)
+	return members, err
+}
+
+// HSet sets a hash field and reports whether the field is new.
+func (c *memoryClient) HSet(key string, field string, value interface{}) (bool, error) {
+	reply, err := c.do(func() (interface{}, error) {
+		v, err := c.store.create(key, typeHash)
+		if err != nil {
+			return false, err
+		}
+		_, exists := v.hash[field]
+		v.hash[field] = toString(value)
+		return !exists, nil
+	})
+	b, _ := reply.(bool)
+	return b, err
+}
+
+// HMSet sets hash fields from alternating field and value arguments.
+func (c *memoryClient) HMSet(key string, values ...interface{}) (string, error) {
+	reply, err := c.do(func() (interface{}, error) {
+		if len(values) == 0 || len(values)%2 != 0 {
+			return "", errHMSetArgs
+		}
+		v, err := c.store.create(key, typeHash)
+		if err != nil {
+			return "", err
+		}
+		for i := 0; i < len(values); i += 2 {
+			v.hash[toString(values[i])] = toString(values[i+1])
+		}
+		return "OK", nil
+	})
+	s, _ := reply.(string)
+	return s, err
+}
+
+// HGetAll returns a hash as alternating fields and values, ordered by field.
+func (c *memoryClient) HGetAll(key string) ([]string, error) {
+	reply, err := c.do(func() (interface{}, error) {
+		v, err := c.store.lookupKind(key, typeHash)
+		if err != nil || v == nil {
+			return []string{}, err
+		}
+		fields := []string{}
+		for f := range v.hash {
+			fields = append(fields, f)
+		}
+		sort.Strings(fields)
+		flattened := []string{}
+		for _, f := range fields {
+			flattened = append(flattened, f, v.hash[f])
+		}
+		return flattened, nil
+	})
+	fields, _ := reply.([]string)
+	return fields, err
+}
@@ -0,0 +1,244 @@
+package redis
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type mockClock struct {
+	now time.Time
+}
+
+func (c *mockClock) Now() time.Time {
+	return c.now
+}
+
+func TestMemoryStoreLists(t *testing.T) {
+	r := NewMemoryStore(&mockClock{t1}).Client()
+	r.LPush("list", "b", "a")
+	r.RPush("list", "c", "d")
+	var tests = []struct {
+		start int64
+		stop  int64
+		want  []string
+	}{
+		{0, -1, []string{"a", "b", "c", "d"}},
+		{0, 0, []string{"a"}},
+		{1, 2, []string{"b", "c"}},
+		{-2, -1, []string{"c", "d"}},
+		{2, 100, []string{"c", "d"}},
+		{3, 1, []string{}},
+		{10, 20, []string{}},
+	}
+	for _, tt := range tests {
+		got, err := r.LRange("list", tt.start, tt.stop)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("unexpected LRANGE %d %d. got: %v, want: %v", tt.start, tt.stop, got, tt.want)
+		}
+	}
+	r.LTrim("list", 0, 1)
+	if got, _ := r.LRange("list", 0, -1); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("unexpected list after LTRIM. got: %v", got)
+	}
+	r.LTrim("list", 5, 10)
+	if exists, _ := r.Exists("list"); exists {
+		t.Errorf("trimming a list to nothing should delete it")
+	}
+	if got, err := r.LRange("missing", 0, -1); err != nil || len(got) != 0 {
+		t.Errorf("a missing list should be empty. got: %v, err: %v", got, err)
+	}
+}
+
+func TestMemoryStoreStrings(t *testing.T) {
+	r := NewMemoryStore(&mockClock{t1}).Client()
+	if got, err := r.Get("missing"); got != "" || err != nil {
+		t.Errorf("a missing key should read as empty. got: %q, err: %v", got, err)
+	}
+	if ok, _ := r.SetNX("key", "first"); !ok {
+		t.Errorf("SETNX should set a new key")
+	}
+	if ok, _ := r.SetNX("key", "second"); ok {
+		t.Errorf("SETNX should not overwrite an existing key")
+	}
+	if got, _ := r.Get("key"); got != "first" {
+		t.Errorf("unexpected value. got: %q, want: %q", got, "first")
+	}
+	r.Set("key", 42)
+	if got, _ := r.Get("key"); got != "42" {
+		t.Errorf("unexpected value. got: %q, want: %q", got, "42")
+	}
+	r.LPush("list", "a")
+	if _, err := r.Get("list"); err != errWrongType {
+		t.Errorf("reading a list as a string should fail. got: %v, want: %v", err, errWrongType)
+	}
+	if n, _ := r.Del("key", "list", "missing"); n != 2 {
+		t.Errorf("unexpected number of deleted keys. got: %d, want: 2", n)
+	}
+}
+
+func TestMemoryStoreExpiry(t *testing.T) {
+	c := &mockClock{t1}
+	r := NewMemoryStore(c).Client()
+	r.Set("key", "value")
+	if ttl, _ := r.TTL("key"); ttl != -1 {
+		t.Errorf("unexpected TTL without expiry. got: %d, want: -1", ttl)
+	}
+	if ttl, _ := r.TTL("missing"); ttl != -2 {
+		t.Errorf("unexpected TTL of missing key. got: %d, want: -2", ttl)
+	}
+	if ok, _ := r.Expire("key", 60); !ok {
+		t.Errorf("EXPIRE should succeed on an existing key")
+	}
+	c.now = t1.Add(20 * time.Second)
+	if ttl, _ := r.TTL("key"); ttl != 40 {
+		t.Errorf("unexpected TTL. got: %d, want: 40", ttl)
+	}
+	c.now = t1.Add(60 * time.Second)
+	if got, _ := r.Get("key"); got != "" {
+		t.Errorf("expired key should be gone, got: %q", got)
+	}
+	if keys, _ := r.Keys("*"); len(keys) != 0 {
+		t.Errorf("expired key should not be listed, got: %v", keys)
+	}
+	if ok, _ := r.SetNX("key", "again"); !ok {
+		t.Errorf("SETNX should succeed once the key expires")
+	}
+}
+
+func TestMemoryStoreSetsAndHashes(t *testing.T) {
+	r := NewMemoryStore(&mockClock{t1}).Client()
+	if n, _ := r.SAdd("a", "1", "2", "3", "1"); n != 3 {
+		t.Errorf("unexpected number of added members. got: %d, want: 3", n)
+	}
+	r.SAdd("b", 2, 3, 4)
+	if got, _ := r.SInter("a", "b"); !reflect.DeepEqual(got, []string{"2", "3"}) {
+		t.Errorf("unexpected intersection. got: %v", got)
+	}
+	if got, _ := r.SInter("a", "missing"); len(got) != 0 {
+		t.Errorf("intersection with a missing set should be empty. got: %v", got)
+	}
+	if ok, _ := r.SIsMember("a", 1); !ok {
+		t.Errorf("expected 1 to be a member")
+	}
+	r.SRem("a", "1", "2", "3")
+	if typ, _ := r.Type("a"); typ != "none" {
+		t.Errorf("removing every member should delete the set, got type: %s", typ)
+	}
+	if isNew, _ := r.HSet("h", "f1", "v1"); !isNew {
+		t.Errorf("HSET of a new field should report it as new")
+	}
+	if isNew, _ := r.HSet("h", "f1", "v2"); isNew {
+		t.Errorf("HSET of an existing field should not report it as new")
+	}
+	r.HMSet("h", "f0", "v0")
+	if got, _ := r.HGetAll("h"); !reflect.DeepEqual(got, []string{"f0", "v0", "f1", "v2"}) {
+		t.Errorf("unexpected hash. got: %v", got)
+	}
+	if _, err := r.HMSet("h", "odd"); err == nil {
+		t.Errorf("HMSET with an odd number of arguments should fail")
+	}
+}
+
+func TestMemoryStoreTransactions(t *testing.T) {
+	s := NewMemoryStore(&mockClock{t1})
+	r, other := s.Client(), s.Client()
+	r.Multi()
+	r.LPush("list", "a")
+	r.Set("list", "overwritten")
+	r.SAdd("list", "wrong type")
+	if exists, _ := other.Exists("list"); exists {
+		t.Errorf("queued commands should not run before EXEC")
+	}
+	replies, err := r.Exec()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(replies) != 3 || replies[0] != int64(1) || replies[1] != "OK" || replies[2] != errWrongType {
+		t.Errorf("unexpected replies: %v", replies)
+	}
+	if got, _ := other.Get("list"); got != "overwritten" {
+		t.Errorf("EXEC should run the queued commands. got: %q", got)
+	}
+	if _, err = r.Exec(); err != errExecNoMulti {
+		t.Errorf("unexpected error for EXEC without MULTI. got: %v, want: %v", err, errExecNoMulti)
+	}
+}
+
+func TestMemoryStoreScan(t *testing.T) {
+	r := NewMemoryStore(&mockClock{}).Client()
+	for _, k := range []string{"listing:1", "listing:2", "listing:3", "order:a"} {
+		r.Set(k, "{}")
+	}
+	got := []string{}
+	cursor := int64(0)
+	for calls := 0; ; calls++ {
+		if calls > 3 {
+			t.Fatalf("scan did not finish")
+		}
+		next, keys, err := r.Scan(cursor, "MATCH", "listing:*", "COUNT", 2)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got = append(got, keys...)
+		if next == 0 {
+			break
+		}
+		cursor = next
+	}
+	want := []string{"listing:1", "listing:2", "listing:3"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected keys. got: %v, want: %v", got, want)
+	}
+	for _, arguments := range [][]interface{}{{"MATCH"}, {"TYPE", "string"}} {
+		if _, _, err := r.Scan(0, arguments...); err != errSyntax {
+			t.Errorf("unexpected error for arguments %v. got: %v, want: %v", arguments, err, errSyntax)
+		}
+	}
+}
+
+func TestUseMemoryStore(t *testing.T) {
+	s := NewMemoryStore(&mockClock{t1})
+	restore := UseMemoryStore(s)
+	defer restore()
+	r, err := New()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r.Set("key", "value")
+	if got, _ := s.Client().Get("key"); got != "value" {
+		t.Errorf("New should return a client of the memory store. got: %q", got)
+	}
+}
+
+func TestUseMemoryStoreWaitsForRestore(t *testing.T) {
+	first := NewMemoryStore(&mockClock{t1})
+	second := NewMemoryStore(&mockClock{t1})
+	restoreFirst := UseMemoryStore(first)
+	swapped := make(chan func())
+	go func() {
+		swapped <- UseMemoryStore(second)
+	}()
+	r, err := New()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r.Set("key", "first")
+	restoreFirst()
+	restoreSecond := <-swapped
+	defer restoreSecond()
+	if got, _ := first.Client().Get("key"); got != "first" {
+		t.Errorf("a memory store in use should not be replaced. got: %q, want: %q", got, "first")
+	}
+	r, err = New()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r.Set("key", "second")
+	if got, _ := second.Client().Get("key"); got != "second" {
+		t.Errorf("New should return a client of the second store once the first is restored. got: %q", got)
+	}
+}
@@ -0,0 +1,200 @@
+package redis
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+)
+
+type (
+	// FieldChange records a single note field that changed, with its JSON
+	// values before and after.
+	FieldChange struct {
+		Field string
+		Old   json.RawMessage
+		New   json.RawMessage
+	}
+	// NoteDiff is a compact entry in a note's history holding only the fields
+	// that changed since the previous entry.
+	NoteDiff struct {
+		Changes   []FieldChange
+		Timestamp time.Time
+		Version   int
+	}
+)
+
+var noteDiffMigrations = []migration{fromUnversioned}
+
+// NoteCheckpointInterval is the most diffs saved in a row in a note's history.
+// The next change is saved as a full record, so the latest state of a note can
+// be rebuilt from a bounded number of entries.
+const NoteCheckpointInterval = 16
+
+var ErrNoteNotFound = errors.New("note not found")
+
+func noteFields(n prosper.Note) (map[string]json.RawMessage, error) {
+	serialized, err := json.Marshal(n)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(serialized, &fields)
+	return fields, err
+}
+
+// DiffNotes returns the fields that differ between two states of a note,
+// sorted by field name.
+func DiffNotes(previous, current prosper.Note) ([]FieldChange, error) {
+	before, err := noteFields(previous)
+	if err != nil {
+		return nil, err
+	}
+	after, err := noteFields(current)
+	if err != nil {
+		return nil, err
+	}
+	changes := []FieldChange{}
+	for field, v := range after {
+		if !bytes.Equal(before[field], v) {
+			changes = append(changes, FieldChange{field, before[field], v})
+		}
+	}
+	sort.Sort(byField(changes))
+	return changes, nil
+}
+
+type byField []FieldChange
+
+func (b byField) Len() int           { return len(b) }
+func (b byField) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b byField) Less(i, j int) bool { return b[i].Field < b[j].Field }
+
+// ApplyNoteDiff returns the note with the new value of each change applied.
+func ApplyNoteDiff(n prosper.Note, changes []FieldChange) (prosper.Note, error) {
+	fields, err := noteFields(n)
+	if err != nil {
+		return prosper.Note{}, err
+	}
+	for _, c := range changes {
+		fields[c.Field] = c.New
+	}
+	serialized, err := json.Marshal(fields)
+	if err != nil {
+		return prosper.Note{}, err
+	}
+	var applied prosper.Note
+	err = json.Unmarshal(serialized, &applied)
+	return applied, err
+}
+
+func EncodeNoteDiff(d NoteDiff) (string, error) {
+	d.Version = SchemaVersion
+	serialized, err := json.Marshal(d)
+	return string(serialized), err
+}
+
+// IsNoteDiff reports whether a serialized note history entry is a NoteDiff
+// rather than a full NoteRecord.
+func IsNoteDiff(serialized string) bool {
+	var probe struct {
+		Changes []FieldChange
+	}
+	return json.Unmarshal([]byte(serialized), &probe) == nil && len(probe.Changes) > 0
+}
+
+// DecodeNoteHistory rebuilds the full state of a note at every entry of its
+// history, given newest first as stored. The oldest entry must be a full
+// record. Records are returned oldest first.
+func DecodeNoteHistory(serialized []string) ([]NoteRecord, error) {
+	records := make([]NoteRecord, len(serialized))
+	for i := len(serialized) - 1; i >= 0; i-- {
+		s := serialized[i]
+		pos := len(serialized) - 1 - i
+		if !IsNoteDiff(s) {
+			r, err := DecodeNoteRecord(s)
+			if err != nil {
+				return []NoteRecord{}, err
+			}
+			records[pos] = r
+			continue
+		}
+		if pos == 0 {
+			return []NoteRecord{}, fmt.Errorf("note history starts with a diff")
+		}
+		var d NoteDiff
+		if err := decodeRecord(s, noteDiffMigrations, &d); err != nil {
+			return []NoteRecord{}, err
+		}
+		n, err := ApplyNoteDiff(records[pos-1].Note, d.Changes)
+		if err != nil {
+			return []NoteRecord{}, err
+		}
+		records[pos] = NoteRecord{Note: n, Timestamp: d.Timestamp, Version: d.Version}
+	}
+	return records, nil
+}
+
+// LatestNoteRecord rebuilds the latest state of a note from the entries of its
+// history since the last full record. It also returns how many diffs were
+// saved since that record. Histories saved before checkpoints were written may
+// have to be read in full.
+func LatestNoteRecord(r RedisListReader, loanNoteID string) (NoteRecord, int, error) {
+	key := KeyPrefixNote + loanNoteID
+	serialized, err := r.LRange(key, 0, NoteCheckpointInterval)
+	if err != nil {
+		return NoteRecord{}, 0, err
+	}
+	if len(serialized) == 0 {
+		return NoteRecord{}, 0, ErrNoteNotFound
+	}
+	checkpoint := lastFullRecord(serialized)
+	if checkpoint < 0 {
+		if serialized, err = r.LRange(key, 0, -1); err != nil {
+			return NoteRecord{}, 0, err
+		}
+		checkpoint = lastFullRecord(serialized)
+	}
+	if checkpoint < 0 {
+		return NoteRecord{}, 0, fmt.Errorf("history of note %s has no full record", loanNoteID)
+	}
+	history, err := DecodeNoteHistory(serialized[:checkpoint+1])
+	if err != nil {
+		return NoteRecord{}, 0, err
+	}
+	return history[len(history)-1], checkpoint, nil
+}
+
+// lastFullRecord returns the index of the newest full record among history
+// entries given newest first, or -1 if they are all diffs.
+func lastFullRecord(serialized []string) int {
+	for i, s := range serialized {
+		if !IsNoteDiff(s) {
+			return i
+		}
+	}
+	return -1
+}
+
+// NoteChanges lists what changed in a note at each entry of its history after
+// the first, given oldest first as returned by NoteHistory. Use NoteAt to get
+// the full state of the note at a given time.
+func NoteChanges(history []NoteRecord) ([]NoteDiff, error) {
+	diffs := []NoteDiff{}
+	for i := 1; i < len(history); i++ {
+		changes, err := DiffNotes(history[i-1].Note, history[i].Note)
+		if err != nil {
+			return []NoteDiff{}, err
+		}
+		diffs = append(diffs, NoteDiff{
+			Changes:   changes,
+			Timestamp: history[i].Timestamp,
+			Version:   SchemaVersion,
+		})
+	}
+	return diffs, nil
+}
@@ -0,0 +1,92 @@
+package redis
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+)
+
+func TestDiffAndApplyNotes(t *testing.T) {
+	previous := prosper.Note{LoanNoteID: "1619-2", DaysPastDue: 0, NoteStatusDescription: "CURRENT", PrincipalBalanceProRataShare: 25}
+	current := prosper.Note{LoanNoteID: "1619-2", DaysPastDue: 16, NoteStatusDescription: "LATE", PrincipalBalanceProRataShare: 25}
+	changes, err := DiffNotes(previous, current)
+	if err != nil {
+		t.Fatalf("diff failed: %v", err)
+	}
+	want := []FieldChange{
+		{"DaysPastDue", []byte("0"), []byte("16")},
+		{"NoteStatusDescription", []byte(`"CURRENT"`), []byte(`"LATE"`)},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("unexpected changes. got: %s, want: %s", changes, want)
+	}
+	applied, err := ApplyNoteDiff(previous, changes)
+	if err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	if !reflect.DeepEqual(applied, current) {
+		t.Errorf("applying diff should give current note. got: %+v, want: %+v", applied, current)
+	}
+}
+
+func TestDecodeNoteHistoryWithDiffs(t *testing.T) {
+	history := []string{
+		`{"Note":{"LoanNoteID":"1619-2","AgeInMonths":3,"DaysPastDue":0},"Timestamp":"2016-05-03T12:00:00Z","Version":1}`,
+		`{"Changes":[{"Field":"DaysPastDue","Old":0,"New":16}],"Timestamp":"2016-05-02T12:00:00Z","Version":1}`,
+		`{"Note":{"LoanNoteID":"1619-2","AgeInMonths":2},"Timestamp":"2016-05-01T12:00:00Z"}`,
+	}
+	got, err := DecodeNoteHistory(history)
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	want := []NoteRecord{
+		{Note: prosper.Note{LoanNoteID: "1619-2", AgeInMonths: 2}, Timestamp: t1, Version: SchemaVersion},
+		{Note: prosper.Note{LoanNoteID: "1619-2", AgeInMonths: 2, DaysPastDue: 16}, Timestamp: t2, Version: SchemaVersion},
+		{Note: prosper.Note{LoanNoteID: "1619-2", AgeInMonths: 3}, Timestamp: t3, Version: SchemaVersion},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected history. got: %+v, want: %+v", got, want)
+	}
+
+	changes, err := NoteChanges(got)
+	if err != nil {
+		t.Fatalf("failed to list changes: %v", err)
+	}
+	wantChanges := []NoteDiff{
+		{Changes: []FieldChange{{"DaysPastDue", []byte("0"), []byte("16")}}, Timestamp: t2, Version: SchemaVersion},
+		{Changes: []FieldChange{{"AgeInMonths", []byte("2"), []byte("3")}, {"DaysPastDue", []byte("16"), []byte("0")}}, Timestamp: t3, Version: SchemaVersion},
+	}
+	if !reflect.DeepEqual(changes, wantChanges) {
+		t.Errorf("unexpected changes. got: %+v, want: %+v", changes, wantChanges)
+	}
+
+	if _, err = DecodeNoteHistory(history[:2]); err == nil {
+		t.Error("expected error for history starting with a diff")
+	}
+}
+
+func TestLatestNoteRecord(t *testing.T) {
+	r := mockRedisReader{
+		lists: map[string][]string{
+			"note:1619-2": {
+				`{"Changes":[{"Field":"DaysPastDue","Old":0,"New":16}],"Timestamp":"2016-05-03T12:00:00Z","Version":1}`,
+				`{"Note":{"LoanNoteID":"1619-2","AgeInMonths":3},"Timestamp":"2016-05-02T12:00:00Z","Version":1}`,
+				`{"Changes":[{"Field":"AgeInMonths","Old":2,"New":3}],"Timestamp":"2016-05-01T12:00:00Z","Version":1}`,
+			},
+		},
+	}
+	got, diffs, err := LatestNoteRecord(r, "1619-2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Note.AgeInMonths != 3 || got.Note.DaysPastDue != 16 || !got.Timestamp.Equal(t3) {
+		t.Errorf("unexpected latest state: %+v", got)
+	}
+	if diffs != 1 {
+		t.Errorf("unexpected diffs since the last full record. got: %d, want: 1", diffs)
+	}
+	if _, _, err = LatestNoteRecord(r, "2000-1"); err != ErrNoteNotFound {
+		t.Errorf("unexpected error for unknown note. got: %v, want: %v", err, ErrNoteNotFound)
+	}
+}
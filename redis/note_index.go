@@ -0,0 +1,114 @@
+package redis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+)
+
+// Each note index is a set of the IDs of the notes whose latest state has a
+// given value of a field.
+
+func NoteStatusIndex(s prosper.NoteStatus) string {
+	return fmt.Sprintf("%sstatus:%d", KeyPrefixNoteIndex, s)
+}
+
+// NoteStatusDescriptionIndex returns the index of notes with a status
+// description, such as CHARGEOFF, ignoring case.
+func NoteStatusDescriptionIndex(description string) string {
+	return KeyPrefixNoteIndex + "statusDescription:" + strings.ToUpper(description)
+}
+
+// NoteLateIndex returns the index of notes that are past due.
+func NoteLateIndex() string {
+	return KeyPrefixNoteIndex + "late"
+}
+
+func NoteRatingIndex(r prosper.Rating) string {
+	return fmt.Sprintf("%srating:%d", KeyPrefixNoteIndex, r)
+}
+
+func NoteTermIndex(term int) string {
+	return fmt.Sprintf("%sterm:%d", KeyPrefixNoteIndex, term)
+}
+
+func NoteListingIndex(l prosper.ListingNumber) string {
+	return fmt.Sprintf("%slisting:%d", KeyPrefixNoteIndex, l)
+}
+
+// NoteOriginationIndex returns the index of notes originated in the same month
+// as t, in UTC.
+func NoteOriginationIndex(t time.Time) string {
+	return KeyPrefixNoteIndex + "origination:" + t.UTC().Format("2006-01")
+}
+
+// NoteIndexes returns every index that should contain the note.
+func NoteIndexes(n prosper.Note) []string {
+	indexes := []string{
+		NoteStatusIndex(n.NoteStatus),
+		NoteRatingIndex(n.Rating),
+		NoteTermIndex(n.Term),
+		NoteListingIndex(n.ListingNumber),
+		NoteOriginationIndex(n.OriginationDate),
+	}
+	if n.NoteStatusDescription != "" {
+		indexes = append(indexes, NoteStatusDescriptionIndex(n.NoteStatusDescription))
+	}
+	if n.DaysPastDue > 0 {
+		indexes = append(indexes, NoteLateIndex())
+	}
+	return indexes
+}
+
+// StaleNoteIndexes returns the indexes that contained the previous state of a
+// note but not its current state.
+func StaleNoteIndexes(previous, current prosper.Note) []string {
+	keep := map[string]bool{}
+	for _, k := range NoteIndexes(current) {
+		keep[k] = true
+	}
+	stale := []string{}
+	for _, k := range NoteIndexes(previous) {
+		if !keep[k] {
+			stale = append(stale, k)
+		}
+	}
+	return stale
+}
+
+// FindNoteIDs returns the IDs of the notes in every one of the given indexes,
+// sorted.
+func FindNoteIDs(r RedisNoteFinder, indexes ...string) ([]string, error) {
+	if len(indexes) == 0 {
+		return []string{}, nil
+	}
+	ids, err := r.SInter(indexes...)
+	if err != nil {
+		return []string{}, err
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// FindNotes returns the latest state of the notes in every one of the given
+// indexes, ordered by note ID.
+func FindNotes(r RedisNoteFinder, indexes ...string) ([]prosper.Note, error) {
+	ids, err := FindNoteIDs(r, indexes...)
+	if err != nil {
+		return []prosper.Note{}, err
+	}
+	notes := []prosper.Note{}
+	for _, id := range ids {
+		latest, _, err := LatestNoteRecord(r, id)
+		if err == ErrNoteNotFound {
+			continue
+		} else if err != nil {
+			return []prosper.Note{}, fmt.Errorf("failed to read history for note %s: %v", id, err)
+		}
+		notes = append(notes, latest.Note)
+	}
+	return notes, nil
+}
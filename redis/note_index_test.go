@@ -0,0 +1,135 @@
+package redis
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+)
+
+type mockRedisNoteFinder struct {
+	sets  map[string][]string
+	lists map[string][]string
+}
+
+func (r mockRedisNoteFinder) SInter(keys ...string) ([]string, error) {
+	counts := map[string]int{}
+	for _, k := range keys {
+		for _, m := range r.sets[k] {
+			counts[m]++
+		}
+	}
+	members := []string{}
+	for m, c := range counts {
+		if c == len(keys) {
+			members = append(members, m)
+		}
+	}
+	return members, nil
+}
+
+func (r mockRedisNoteFinder) LRange(key string, start int64, stop int64) ([]string, error) {
+	return r.lists[key], nil
+}
+
+func TestStaleNoteIndexes(t *testing.T) {
+	previous := prosper.Note{LoanNoteID: "1619-2", NoteStatus: 1, Term: 36, OriginationDate: t1}
+	current := prosper.Note{LoanNoteID: "1619-2", NoteStatus: 2, Term: 36, OriginationDate: t1}
+	got := StaleNoteIndexes(previous, current)
+	want := []string{"noteIndex:status:1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected stale indexes. got: %v, want: %v", got, want)
+	}
+}
+
+func TestNoteIndexesStatusDescriptionAndLate(t *testing.T) {
+	var tests = []struct {
+		note prosper.Note
+		want []string
+		msg  string
+	}{
+		{
+			prosper.Note{NoteStatusDescription: "Current", Term: 36, OriginationDate: t1},
+			[]string{"noteIndex:statusDescription:CURRENT"},
+			"a current note should be indexed by its upper-case status description",
+		},
+		{
+			prosper.Note{NoteStatusDescription: "CURRENT", DaysPastDue: 16, Term: 36, OriginationDate: t1},
+			[]string{"noteIndex:statusDescription:CURRENT", "noteIndex:late"},
+			"a past due note should be in the late index",
+		},
+		{
+			prosper.Note{Term: 36, OriginationDate: t1},
+			[]string{},
+			"a note without a status description should not be indexed by it",
+		},
+	}
+	for _, tt := range tests {
+		// The first five indexes are the ones every note is in.
+		got := NoteIndexes(tt.note)[5:]
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: unexpected indexes. got: %v, want: %v", tt.msg, got, tt.want)
+		}
+	}
+}
+
+func TestStaleNoteIndexesLate(t *testing.T) {
+	previous := prosper.Note{LoanNoteID: "1619-2", NoteStatusDescription: "CURRENT", DaysPastDue: 16, Term: 36, OriginationDate: t1}
+	current := previous
+	current.DaysPastDue = 0
+	got := StaleNoteIndexes(previous, current)
+	want := []string{NoteLateIndex()}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected stale indexes. got: %v, want: %v", got, want)
+	}
+}
+
+func TestFindNotes(t *testing.T) {
+	r := mockRedisNoteFinder{
+		sets: map[string][]string{
+			NoteRatingIndex(prosper.RatingA):                                  {"2000-1", "1619-2"},
+			NoteOriginationIndex(time.Date(2016, 5, 1, 0, 0, 0, 0, time.UTC)): {"1619-2", "2000-1", "3000-1"},
+			NoteTermIndex(36): {"1619-2"},
+		},
+		lists: map[string][]string{
+			"note:1619-2": {
+				`{"Changes":[{"Field":"DaysPastDue","Old":0,"New":16}],"Timestamp":"2016-05-02T12:00:00Z","Version":1}`,
+				`{"Note":{"LoanNoteID":"1619-2","Term":36},"Timestamp":"2016-05-01T12:00:00Z","Version":1}`,
+			},
+			"note:2000-1": {
+				`{"Note":{"LoanNoteID":"2000-1","Term":60},"Timestamp":"2016-05-01T12:00:00Z","Version":1}`,
+			},
+		},
+	}
+	var tests = []struct {
+		indexes []string
+		want    []prosper.Note
+		msg     string
+	}{
+		{
+			[]string{NoteRatingIndex(prosper.RatingA)},
+			[]prosper.Note{{LoanNoteID: "1619-2", Term: 36, DaysPastDue: 16}, {LoanNoteID: "2000-1", Term: 60}},
+			"notes in one index should be found in order",
+		},
+		{
+			[]string{NoteRatingIndex(prosper.RatingA), NoteTermIndex(36)},
+			[]prosper.Note{{LoanNoteID: "1619-2", Term: 36, DaysPastDue: 16}},
+			"only notes in every index should be found",
+		},
+		{
+			[]string{},
+			[]prosper.Note{},
+			"no indexes should find no notes",
+		},
+	}
+	for _, tt := range tests {
+		got, err := FindNotes(r, tt.indexes...)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.msg, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: unexpected notes. got: %+v, want: %+v", tt.msg, got, tt.want)
+		}
+	}
+}
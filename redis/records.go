@@ -10,13 +10,21 @@ type (
 	AccountRecord struct {
 		Value     prosper.AccountInformation
 		Timestamp time.Time
+		Version   int
 	}
 	NoteRecord struct {
 		Note      prosper.Note
 		Timestamp time.Time
+		Version   int
 	}
 	OrderRecord struct {
 		Order     prosper.OrderResponse
 		Timestamp time.Time
+		Version   int
+	}
+	ListingRecord struct {
+		Listing   prosper.Listing
+		Timestamp time.Time
+		Version   int
 	}
 )
@@ -1,6 +1,8 @@
 package redis
 
 import (
+	"sync"
+
 	"menteslibres.net/gosexy/redis"
 )
 
@@ -9,7 +11,54 @@ const (
 	port     = 6379
 )
 
-func New() (*redis.Client, error) {
+// Client is every command the bot uses. Components take the narrower
+// interfaces in types.go; Client is what New returns to satisfy all of them.
+type Client interface {
+	Quit() (string, error)
+	Get(key string) (string, error)
+	Set(key string, value interface{}) (string, error)
+	SetNX(key string, value interface{}) (bool, error)
+	Del(keys ...string) (int64, error)
+	Keys(pattern string) ([]string, error)
+	Scan(cursor int64, arguments ...interface{}) (int64, []string, error)
+	Type(key string) (string, error)
+	Exists(key string) (bool, error)
+	TTL(key string) (int64, error)
+	Expire(key string, seconds uint64) (bool, error)
+	LRange(key string, start int64, stop int64) ([]string, error)
+	LPush(key string, values ...interface{}) (int64, error)
+	RPush(key string, values ...interface{}) (int64, error)
+	LTrim(key string, start int64, stop int64) (string, error)
+	SAdd(key string, members ...interface{}) (int64, error)
+	SRem(key string, members ...interface{}) (int64, error)
+	SIsMember(key string, member interface{}) (bool, error)
+	SMembers(key string) ([]string, error)
+	SInter(keys ...string) ([]string, error)
+	HSet(key string, field string, value interface{}) (bool, error)
+	HMSet(key string, values ...interface{}) (string, error)
+	HGetAll(key string) ([]string, error)
+	Multi() (string, error)
+	Exec() ([]interface{}, error)
+}
+
+var (
+	// connect opens the client that New returns. It is guarded by connectMu.
+	connect   = connectServer
+	connectMu sync.RWMutex
+	// memoryStoreMu is held for as long as a memory store replaces the Redis
+	// server, so that callers of UseMemoryStore take turns.
+	memoryStoreMu sync.Mutex
+)
+
+// New connects to the bot's Redis store.
+func New() (Client, error) {
+	connectMu.RLock()
+	c := connect
+	connectMu.RUnlock()
+	return c()
+}
+
+func connectServer() (Client, error) {
 	r := redis.New()
 	err := r.Connect(hostname, port)
 	if err != nil {
@@ -17,3 +66,24 @@ func New() (*redis.Client, error) {
 	}
 	return r, nil
 }
+
+// UseMemoryStore makes New return clients of s instead of connecting to the
+// Redis server, so that components can run in tests without one. The returned
+// function restores the Redis server. If another memory store is in use,
+// UseMemoryStore waits until it is restored.
+func UseMemoryStore(s *MemoryStore) func() {
+	memoryStoreMu.Lock()
+	setConnect(func() (Client, error) {
+		return s.Client(), nil
+	})
+	return func() {
+		setConnect(connectServer)
+		memoryStoreMu.Unlock()
+	}
+}
+
+func setConnect(c func() (Client, error)) {
+	connectMu.Lock()
+	connect = c
+	connectMu.Unlock()
+}
@@ -0,0 +1,66 @@
+package redis
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+)
+
+const reevaluationDayFormat = "2006-01-02"
+
+// ReevaluationKey is the key of the set of declined listings that started on
+// the same day as start.
+func ReevaluationKey(start time.Time) string {
+	return KeyPrefixReevaluation + start.UTC().Format(reevaluationDayFormat)
+}
+
+// ReevaluationDay parses the day out of a key made by ReevaluationKey.
+func ReevaluationDay(key string) (time.Time, error) {
+	return time.Parse(reevaluationDayFormat, strings.TrimPrefix(key, KeyPrefixReevaluation))
+}
+
+// QueueReevaluation adds a declined listing to the queue of listings to
+// reconsider.
+func QueueReevaluation(r RedisReevaluationQueuer, l prosper.Listing) error {
+	_, err := r.SAdd(ReevaluationKey(l.ListingStartDate), int64(l.ListingNumber))
+	return err
+}
+
+// HasOrder reports whether the bot has placed an order for listing n.
+func HasOrder(r RedisHashGetter, n prosper.ListingNumber) (bool, error) {
+	fields, err := r.HGetAll(LineageKey(n))
+	if err != nil {
+		return false, err
+	}
+	_, ok := fieldsToMap(fields)[LineageFieldOrder]
+	return ok, nil
+}
+
+func BidClaimKey(n prosper.ListingNumber) string {
+	return fmt.Sprintf("%s%d", KeyPrefixBidClaim, n)
+}
+
+// ClaimBid records that the bot is about to bid on listing n, and reports
+// false if the listing was already claimed. A claim is kept once the bid is
+// placed, and released with ReleaseBid if the bid fails.
+func ClaimBid(r RedisSetNXer, n prosper.ListingNumber, t time.Time) (bool, error) {
+	return r.SetNX(BidClaimKey(n), t.UTC().Format(time.RFC3339))
+}
+
+// ReleaseBid removes the claim on listing n.
+func ReleaseBid(r RedisBidClaimer, n prosper.ListingNumber) error {
+	_, err := r.Del(BidClaimKey(n))
+	return err
+}
+
+// HasBid reports whether the bot has claimed listing n to bid on it, or has
+// an order for it from before claims were saved.
+func HasBid(r RedisBidChecker, n prosper.ListingNumber) (bool, error) {
+	claimed, err := r.Exists(BidClaimKey(n))
+	if err != nil || claimed {
+		return claimed, err
+	}
+	return HasOrder(r, n)
+}
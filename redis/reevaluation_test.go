@@ -0,0 +1,66 @@
+package redis
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/clock"
+)
+
+func TestQueueReevaluation(t *testing.T) {
+	r := NewMemoryStore(clock.DefaultClock{}).Client()
+	start := time.Date(2016, 5, 1, 23, 30, 0, 0, time.UTC)
+	if err := QueueReevaluation(r, prosper.Listing{ListingNumber: 123, ListingStartDate: start}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	key := ReevaluationKey(start)
+	if key != "reevaluate:2016-05-01" {
+		t.Errorf("unexpected key. got = %s, want = reevaluate:2016-05-01", key)
+	}
+	if members, _ := r.SMembers(key); !reflect.DeepEqual(members, []string{"123"}) {
+		t.Errorf("unexpected queue. got = %v, want = [123]", members)
+	}
+	day, err := ReevaluationDay(key)
+	if err != nil || !day.Equal(time.Date(2016, 5, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected day. got = %v, %v", day, err)
+	}
+}
+
+func TestHasOrder(t *testing.T) {
+	r := newLineageReader()
+	if ordered, err := HasOrder(r, 123); err != nil || !ordered {
+		t.Errorf("listing with an order should have one. got = %v, %v", ordered, err)
+	}
+	if ordered, err := HasOrder(r, 789); err != nil || ordered {
+		t.Errorf("rejected listing should have no order. got = %v, %v", ordered, err)
+	}
+}
+
+func TestClaimBid(t *testing.T) {
+	r := NewMemoryStore(clock.DefaultClock{}).Client()
+	r.HSet(LineageKey(456), LineageFieldOrder, "order-b")
+	now := time.Date(2016, 5, 1, 12, 0, 0, 0, time.UTC)
+	if claimed, err := ClaimBid(r, 123, now); err != nil || !claimed {
+		t.Errorf("first claim should succeed. got = %v, %v", claimed, err)
+	}
+	if claimed, err := ClaimBid(r, 123, now); err != nil || claimed {
+		t.Errorf("second claim should fail. got = %v, %v", claimed, err)
+	}
+	var tests = []struct {
+		n    prosper.ListingNumber
+		want bool
+		msg  string
+	}{
+		{123, true, "claimed listing should have a bid"},
+		{456, true, "listing ordered before claims were saved should have a bid"},
+		{789, false, "listing never bid on should have no bid"},
+	}
+	for _, tt := range tests {
+		if got, err := HasBid(r, tt.n); err != nil || got != tt.want {
+			t.Errorf("%s. got = %v, %v, want = %v", tt.msg, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package redis
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// SchemaVersion is the layout version stamped into every record the bot
+// saves. Records saved before versioning have no stamp and are treated as
+// version 0.
+const SchemaVersion = 1
+
+// A migration upgrades a decoded record from one schema version to the next.
+type migration func(record map[string]interface{}) error
+
+// Migrations for each record type, indexed by the version they upgrade from.
+// When a record layout changes, bump SchemaVersion and append a migration to
+// each list.
+var (
+	accountMigrations = []migration{fromUnversioned}
+	noteMigrations    = []migration{fromUnversioned}
+	orderMigrations   = []migration{fromUnversioned}
+	listingMigrations = []migration{fromUnversioned}
+)
+
+// Unversioned records have the same layout as version 1 records, only the
+// version stamp is new.
+func fromUnversioned(record map[string]interface{}) error {
+	return nil
+}
+
+func recordVersion(record map[string]interface{}) (int, error) {
+	stamp, ok := record["Version"]
+	if !ok {
+		return 0, nil
+	}
+	n, ok := stamp.(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("invalid schema version: %v", stamp)
+	}
+	version, err := n.Int64()
+	if err != nil {
+		return 0, fmt.Errorf("invalid schema version: %v", stamp)
+	}
+	return int(version), nil
+}
+
+// decodeRecord parses a serialized record into v, first upgrading it to the
+// current schema if it was saved by an older version of the bot.
+func decodeRecord(serialized string, migrations []migration, v interface{}) error {
+	dec := json.NewDecoder(strings.NewReader(serialized))
+	// Keep numbers exact through the round trip below.
+	dec.UseNumber()
+	var record map[string]interface{}
+	if err := dec.Decode(&record); err != nil {
+		return err
+	}
+	version, err := recordVersion(record)
+	if err != nil {
+		return err
+	}
+	if version > SchemaVersion {
+		return fmt.Errorf("record has schema version %d, newer than supported version %d", version, SchemaVersion)
+	}
+	if version == SchemaVersion {
+		return json.Unmarshal([]byte(serialized), v)
+	}
+	for ; version < SchemaVersion; version++ {
+		if err = migrations[version](record); err != nil {
+			return fmt.Errorf("failed to migrate record from schema version %d: %v", version, err)
+		}
+	}
+	record["Version"] = SchemaVersion
+	migrated, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(migrated, v)
+}
+
+func EncodeAccountRecord(r AccountRecord) (string, error) {
+	r.Version = SchemaVersion
+	serialized, err := json.Marshal(r)
+	return string(serialized), err
+}
+
+func DecodeAccountRecord(serialized string) (AccountRecord, error) {
+	var r AccountRecord
+	err := decodeRecord(serialized, accountMigrations, &r)
+	return r, err
+}
+
+func EncodeNoteRecord(r NoteRecord) (string, error) {
+	r.Version = SchemaVersion
+	serialized, err := json.Marshal(r)
+	return string(serialized), err
+}
+
+func DecodeNoteRecord(serialized string) (NoteRecord, error) {
+	var r NoteRecord
+	err := decodeRecord(serialized, noteMigrations, &r)
+	return r, err
+}
+
+func EncodeOrderRecord(r OrderRecord) (string, error) {
+	r.Version = SchemaVersion
+	serialized, err := json.Marshal(r)
+	return string(serialized), err
+}
+
+func DecodeOrderRecord(serialized string) (OrderRecord, error) {
+	var r OrderRecord
+	err := decodeRecord(serialized, orderMigrations, &r)
+	return r, err
+}
+
+func EncodeListingRecord(r ListingRecord) (string, error) {
+	r.Version = SchemaVersion
+	serialized, err := json.Marshal(r)
+	return string(serialized), err
+}
+
+func DecodeListingRecord(serialized string) (ListingRecord, error) {
+	var r ListingRecord
+	err := decodeRecord(serialized, listingMigrations, &r)
+	return r, err
+}
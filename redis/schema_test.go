@@ -0,0 +1,123 @@
+package redis
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+)
+
+// Fixtures of records as saved by each schema version.
+const (
+	accountRecordV0 = `{"Value":{"AvailableCashBalance":125.5,"TotalAccountValue":1000,"LastDepositDate":"2016-01-04T00:00:00Z"},"Timestamp":"2016-02-14T12:28:15Z"}`
+	accountRecordV1 = `{"Value":{"AvailableCashBalance":125.5,"TotalAccountValue":1000,"LastDepositDate":"2016-01-04T00:00:00Z"},"Timestamp":"2016-02-14T12:28:15Z","Version":1}`
+	noteRecordV0    = `{"Note":{"LoanNoteID":"1619-2","ListingNumber":1619,"LoanNumber":9007199254740993,"NoteDefaultReason":2,"PrincipalBalanceProRataShare":24.16},"Timestamp":"2016-02-14T12:28:15Z"}`
+	noteRecordV1    = `{"Note":{"LoanNoteID":"1619-2","ListingNumber":1619,"LoanNumber":9007199254740993,"NoteDefaultReason":2,"PrincipalBalanceProRataShare":24.16},"Timestamp":"2016-02-14T12:28:15Z","Version":1}`
+	orderRecordV0   = `{"Order":{"OrderID":"id-a","BidStatus":[{"ListingID":54321,"BidAmount":25,"Result":4,"BidAmountPlaced":25}],"OrderDate":"2016-04-23T11:54:29Z"},"Timestamp":"2016-02-14T12:28:15Z"}`
+	orderRecordV1   = `{"Order":{"OrderID":"id-a","BidStatus":[{"ListingID":54321,"BidAmount":25,"Result":4,"BidAmountPlaced":25}],"OrderDate":"2016-04-23T11:54:29Z"},"Timestamp":"2016-02-14T12:28:15Z","Version":1}`
+)
+
+var fixtureTime = time.Date(2016, 2, 14, 12, 28, 15, 0, time.UTC)
+
+func TestDecodeAccountRecord(t *testing.T) {
+	want := AccountRecord{
+		Value: prosper.AccountInformation{
+			AvailableCashBalance: 125.5,
+			TotalAccountValue:    1000,
+			LastDepositDate:      time.Date(2016, 1, 4, 0, 0, 0, 0, time.UTC),
+		},
+		Timestamp: fixtureTime,
+		Version:   SchemaVersion,
+	}
+	for _, serialized := range []string{accountRecordV0, accountRecordV1} {
+		got, err := DecodeAccountRecord(serialized)
+		if err != nil {
+			t.Fatalf("failed to decode %s: %v", serialized, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("unexpected record for %s. got: %+v, want: %+v", serialized, got, want)
+		}
+	}
+}
+
+func TestDecodeNoteRecord(t *testing.T) {
+	reason := prosper.DefaultReason(2)
+	want := NoteRecord{
+		Note: prosper.Note{
+			LoanNoteID:                   "1619-2",
+			ListingNumber:                1619,
+			LoanNumber:                   9007199254740993,
+			NoteDefaultReason:            &reason,
+			PrincipalBalanceProRataShare: 24.16,
+		},
+		Timestamp: fixtureTime,
+		Version:   SchemaVersion,
+	}
+	for _, serialized := range []string{noteRecordV0, noteRecordV1} {
+		got, err := DecodeNoteRecord(serialized)
+		if err != nil {
+			t.Fatalf("failed to decode %s: %v", serialized, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("unexpected record for %s. got: %+v, want: %+v", serialized, got, want)
+		}
+	}
+}
+
+func TestDecodeOrderRecord(t *testing.T) {
+	want := OrderRecord{
+		Order: prosper.OrderResponse{
+			OrderID: "id-a",
+			BidStatus: []prosper.BidStatus{
+				{
+					BidRequest:      prosper.BidRequest{ListingID: 54321, BidAmount: 25},
+					Result:          prosper.BidSucceeded,
+					BidAmountPlaced: 25,
+				},
+			},
+			OrderDate: time.Date(2016, 4, 23, 11, 54, 29, 0, time.UTC),
+		},
+		Timestamp: fixtureTime,
+		Version:   SchemaVersion,
+	}
+	for _, serialized := range []string{orderRecordV0, orderRecordV1} {
+		got, err := DecodeOrderRecord(serialized)
+		if err != nil {
+			t.Fatalf("failed to decode %s: %v", serialized, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("unexpected record for %s. got: %+v, want: %+v", serialized, got, want)
+		}
+	}
+}
+
+func TestDecodeRecordErrors(t *testing.T) {
+	var tests = []struct {
+		serialized string
+		msg        string
+	}{
+		{`{"Note":{},"Version":2}`, "records from a newer schema should be rejected"},
+		{`{"Note":{},"Version":"1"}`, "non-numeric versions should be rejected"},
+		{`{"Note":`, "malformed records should be rejected"},
+	}
+	for _, tt := range tests {
+		if _, err := DecodeNoteRecord(tt.serialized); err == nil {
+			t.Errorf("%s: expected error", tt.msg)
+		}
+	}
+}
+
+func TestEncodeStampsVersion(t *testing.T) {
+	serialized, err := EncodeOrderRecord(OrderRecord{Timestamp: fixtureTime})
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	got, err := DecodeOrderRecord(serialized)
+	if err != nil {
+		t.Fatalf("failed to decode %s: %v", serialized, err)
+	}
+	if got.Version != SchemaVersion {
+		t.Errorf("unexpected version. got: %d, want: %d", got.Version, SchemaVersion)
+	}
+}
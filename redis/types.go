@@ -8,16 +8,217 @@ type RedisSetNXer interface {
 	SetNX(key string, value interface{}) (bool, error)
 }
 
+type RedisHashSetter interface {
+	HSet(key string, field string, value interface{}) (bool, error)
+}
+
+// RedisListingSaver is the set of commands needed to save observations of
+// listings.
+type RedisListingSaver interface {
+	SetNX(key string, value interface{}) (bool, error)
+	SAdd(key string, members ...interface{}) (int64, error)
+	SIsMember(key string, member interface{}) (bool, error)
+	LRange(key string, start int64, stop int64) ([]string, error)
+	LPush(key string, values ...interface{}) (int64, error)
+}
+
+// RedisListingObserver is the set of commands needed to save new observations
+// of the listings that are being watched.
+type RedisListingObserver interface {
+	Get(key string) (string, error)
+	Keys(pattern string) ([]string, error)
+	SMembers(key string) ([]string, error)
+	SRem(key string, members ...interface{}) (int64, error)
+	LRange(key string, start int64, stop int64) ([]string, error)
+	LPush(key string, values ...interface{}) (int64, error)
+}
+
+// RedisNotificationQueue is the set of commands needed to save alerts until
+// they are sent and to record which alerts were sent.
+type RedisNotificationQueue interface {
+	Exists(key string) (bool, error)
+	SetNX(key string, value interface{}) (bool, error)
+	Del(keys ...string) (int64, error)
+	LRange(key string, start int64, stop int64) ([]string, error)
+	RPush(key string, values ...interface{}) (int64, error)
+}
+
+type RedisHashGetter interface {
+	HGetAll(key string) ([]string, error)
+}
+
 type RedisSetter interface {
 	Set(key string, value interface{}) (string, error)
 }
 
+type RedisGetter interface {
+	Get(key string) (string, error)
+}
+
 type RedisGetterSetter interface {
 	Get(string) (string, error)
 	Set(key string, value interface{}) (string, error)
 }
 
+type RedisListReader interface {
+	LRange(key string, start int64, stop int64) ([]string, error)
+}
+
 type RedisListPrepender interface {
 	LRange(key string, start int64, stop int64) ([]string, error)
 	LPush(key string, values ...interface{}) (int64, error)
 }
+
+// RedisListIndexer is the set of commands needed to prepend to a list while
+// atomically updating index sets that refer to it.
+type RedisListIndexer interface {
+	Get(key string) (string, error)
+	LRange(key string, start int64, stop int64) ([]string, error)
+	LPush(key string, values ...interface{}) (int64, error)
+	SAdd(key string, members ...interface{}) (int64, error)
+	SRem(key string, members ...interface{}) (int64, error)
+	HSet(key string, field string, value interface{}) (bool, error)
+	Multi() (string, error)
+	Exec() ([]interface{}, error)
+}
+
+// RedisLineageReader is the set of commands needed to follow a listing to the
+// orders and notes that came from it.
+type RedisLineageReader interface {
+	Get(key string) (string, error)
+	Keys(pattern string) ([]string, error)
+	LRange(key string, start int64, stop int64) ([]string, error)
+	HGetAll(key string) ([]string, error)
+}
+
+// RedisBorrowerIndexer is the set of commands needed to keep track of the
+// bot's exposure to each borrower.
+type RedisBorrowerIndexer interface {
+	Get(key string) (string, error)
+	Keys(pattern string) ([]string, error)
+	LRange(key string, start int64, stop int64) ([]string, error)
+	HGetAll(key string) ([]string, error)
+	HSet(key string, field string, value interface{}) (bool, error)
+}
+
+// RedisReevaluationQueuer is the set of commands needed to queue a declined
+// listing for re-evaluation.
+type RedisReevaluationQueuer interface {
+	SAdd(key string, members ...interface{}) (int64, error)
+}
+
+// RedisBidChecker is the set of commands needed to check whether a listing
+// was already bid on.
+type RedisBidChecker interface {
+	Exists(key string) (bool, error)
+	HGetAll(key string) ([]string, error)
+}
+
+// RedisBidClaimer is the set of commands needed to claim a listing before
+// bidding on it, release the claim if the bid fails and check whether it was
+// already bid on.
+type RedisBidClaimer interface {
+	SetNX(key string, value interface{}) (bool, error)
+	Del(keys ...string) (int64, error)
+	Exists(key string) (bool, error)
+	HGetAll(key string) ([]string, error)
+}
+
+// RedisReevaluator is the set of commands needed to work through the queue of
+// listings to re-evaluate.
+type RedisReevaluator interface {
+	Get(key string) (string, error)
+	Set(key string, value interface{}) (string, error)
+	Keys(pattern string) ([]string, error)
+	LRange(key string, start int64, stop int64) ([]string, error)
+	SMembers(key string) ([]string, error)
+	SRem(key string, members ...interface{}) (int64, error)
+	Del(keys ...string) (int64, error)
+	Exists(key string) (bool, error)
+	HGetAll(key string) ([]string, error)
+}
+
+// RedisScanner is the set of commands needed to read every key with a prefix
+// without blocking Redis.
+type RedisScanner interface {
+	Get(key string) (string, error)
+	Scan(cursor int64, arguments ...interface{}) (int64, []string, error)
+}
+
+// RedisHistoryScanner is the set of commands needed to read saved history,
+// including every saved listing.
+type RedisHistoryScanner interface {
+	Get(key string) (string, error)
+	Keys(pattern string) ([]string, error)
+	Scan(cursor int64, arguments ...interface{}) (int64, []string, error)
+	LRange(key string, start int64, stop int64) ([]string, error)
+}
+
+// RedisInspector is the set of read-only commands that the command-line tools
+// use to inspect the bot's state.
+type RedisInspector interface {
+	Get(key string) (string, error)
+	Keys(pattern string) ([]string, error)
+	Scan(cursor int64, arguments ...interface{}) (int64, []string, error)
+	Type(key string) (string, error)
+	TTL(key string) (int64, error)
+	LRange(key string, start int64, stop int64) ([]string, error)
+	SMembers(key string) ([]string, error)
+	SInter(keys ...string) ([]string, error)
+	HGetAll(key string) ([]string, error)
+}
+
+// RedisNoteFinder is the set of commands needed to look up notes through their
+// indexes.
+type RedisNoteFinder interface {
+	LRange(key string, start int64, stop int64) ([]string, error)
+	SInter(keys ...string) ([]string, error)
+}
+
+// RedisDumper is the set of commands needed to read every key the bot owns,
+// whatever its type.
+type RedisDumper interface {
+	Keys(pattern string) ([]string, error)
+	Type(key string) (string, error)
+	Get(key string) (string, error)
+	LRange(key string, start int64, stop int64) ([]string, error)
+	SMembers(key string) ([]string, error)
+	HGetAll(key string) ([]string, error)
+	TTL(key string) (int64, error)
+}
+
+// RedisLoader is the set of commands needed to write keys of any type.
+type RedisLoader interface {
+	Keys(pattern string) ([]string, error)
+	Del(keys ...string) (int64, error)
+	Expire(key string, seconds uint64) (bool, error)
+	Set(key string, value interface{}) (string, error)
+	RPush(key string, values ...interface{}) (int64, error)
+	SAdd(key string, members ...interface{}) (int64, error)
+	HMSet(key string, values ...interface{}) (string, error)
+}
+
+// RedisReconciler is the set of commands needed to reconcile orders against
+// notes and save the report.
+type RedisReconciler interface {
+	Keys(pattern string) ([]string, error)
+	Get(key string) (string, error)
+	LRange(key string, start int64, stop int64) ([]string, error)
+	LPush(key string, values ...interface{}) (int64, error)
+	LTrim(key string, start int64, stop int64) (string, error)
+}
+
+// RedisMaintainer is the set of commands needed to expire and compact
+// history.
+type RedisMaintainer interface {
+	Scan(cursor int64, arguments ...interface{}) (int64, []string, error)
+	Get(key string) (string, error)
+	LRange(key string, start int64, stop int64) ([]string, error)
+	LTrim(key string, start int64, stop int64) (string, error)
+	RPush(key string, values ...interface{}) (int64, error)
+	SAdd(key string, members ...interface{}) (int64, error)
+	TTL(key string) (int64, error)
+	Expire(key string, seconds uint64) (bool, error)
+	Multi() (string, error)
+	Exec() ([]interface{}, error)
+}
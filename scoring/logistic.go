@@ -0,0 +1,214 @@
+package scoring
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+)
+
+const (
+	defaultIterations   = 2000
+	defaultLearningRate = 0.5
+	defaultL2           = 0.001
+)
+
+var (
+	ErrNoExamples = errors.New("no training examples")
+	ErrOneClass   = errors.New("training examples must include both defaulted and repaid loans")
+)
+
+// Predictor estimates the probability that a listing's loan is lost.
+type Predictor interface {
+	Probability(l prosper.Listing) float64
+}
+
+// LogisticModel predicts the probability that a listing's loan charges off or
+// defaults, from a weighted sum of its features.
+type LogisticModel struct {
+	Intercept float64
+	// Weights maps feature names to their weight in the log-odds of default.
+	Weights map[string]float64
+}
+
+// Probability returns the predicted probability that the listing's loan is
+// lost.
+func (m LogisticModel) Probability(l prosper.Listing) float64 {
+	return sigmoid(LinearModel(m).Score(l))
+}
+
+// Score rates listings by their chance of being repaid, so that a
+// LogisticModel can also rank listings.
+func (m LogisticModel) Score(l prosper.Listing) float64 {
+	return 1 - m.Probability(l)
+}
+
+// Validate checks that the model only weighs known features.
+func (m LogisticModel) Validate() error {
+	return LinearModel(m).Validate()
+}
+
+func sigmoid(x float64) float64 {
+	return 1 / (1 + math.Exp(-x))
+}
+
+// Example is a listing whose outcome is known.
+type Example struct {
+	Listing   prosper.Listing
+	Defaulted bool
+}
+
+// TrainOptions tunes logistic regression. Zero values are replaced with
+// defaults.
+type TrainOptions struct {
+	// Features are the names of the features to fit. Defaults to all of them.
+	Features     []string
+	Iterations   int
+	LearningRate float64
+	// L2 is the strength of the penalty on large weights.
+	L2 float64
+}
+
+// Train fits a logistic regression to the examples by gradient descent.
+// Features are standardized while fitting, and the weights are scaled back so
+// that the model applies to raw listing fields.
+func Train(examples []Example, opts TrainOptions) (LogisticModel, error) {
+	if len(examples) == 0 {
+		return LogisticModel{}, ErrNoExamples
+	}
+	if len(opts.Features) == 0 {
+		opts.Features = FeatureNames()
+	}
+	if opts.Iterations == 0 {
+		opts.Iterations = defaultIterations
+	}
+	if opts.LearningRate == 0 {
+		opts.LearningRate = defaultLearningRate
+	}
+	if opts.L2 == 0 {
+		opts.L2 = defaultL2
+	}
+	features := make([]func(prosper.Listing) float64, len(opts.Features))
+	for i, name := range opts.Features {
+		f, ok := Features[name]
+		if !ok {
+			return LogisticModel{}, fmt.Errorf("unknown scoring feature %q", name)
+		}
+		features[i] = f
+	}
+
+	n := float64(len(examples))
+	x := make([][]float64, len(examples))
+	y := make([]float64, len(examples))
+	defaults := 0
+	means := make([]float64, len(features))
+	for i, e := range examples {
+		x[i] = make([]float64, len(features))
+		for j, f := range features {
+			x[i][j] = f(e.Listing)
+			means[j] += x[i][j] / n
+		}
+		if e.Defaulted {
+			y[i] = 1
+			defaults++
+		}
+	}
+	if defaults == 0 || defaults == len(examples) {
+		return LogisticModel{}, ErrOneClass
+	}
+	scales := make([]float64, len(features))
+	for j := range features {
+		for i := range x {
+			scales[j] += (x[i][j] - means[j]) * (x[i][j] - means[j]) / n
+		}
+		scales[j] = math.Sqrt(scales[j])
+		// Rounding leaves a constant feature with a tiny spread rather than
+		// none, which would blow up its weight.
+		if scales[j] <= 1e-9*math.Max(1, math.Abs(means[j])) {
+			scales[j] = 0
+		}
+		for i := range x {
+			if scales[j] > 0 {
+				x[i][j] = (x[i][j] - means[j]) / scales[j]
+			} else {
+				x[i][j] = 0
+			}
+		}
+	}
+
+	w := make([]float64, len(features))
+	b := 0.0
+	for iter := 0; iter < opts.Iterations; iter++ {
+		gradW := make([]float64, len(features))
+		gradB := 0.0
+		for i := range x {
+			z := b
+			for j := range w {
+				z += w[j] * x[i][j]
+			}
+			residual := sigmoid(z) - y[i]
+			gradB += residual / n
+			for j := range w {
+				gradW[j] += residual * x[i][j] / n
+			}
+		}
+		b -= opts.LearningRate * gradB
+		for j := range w {
+			w[j] -= opts.LearningRate * (gradW[j] + opts.L2*w[j])
+		}
+	}
+
+	m := LogisticModel{Intercept: b, Weights: map[string]float64{}}
+	for j, name := range opts.Features {
+		if scales[j] == 0 {
+			continue
+		}
+		m.Weights[name] = w[j] / scales[j]
+		m.Intercept -= w[j] * means[j] / scales[j]
+	}
+	return m, nil
+}
+
+// LogLoss is the mean negative log-likelihood of the examples under the
+// model. Lower is better.
+func LogLoss(m LogisticModel, examples []Example) float64 {
+	const epsilon = 1e-15
+	total := 0.0
+	for _, e := range examples {
+		p := math.Min(math.Max(m.Probability(e.Listing), epsilon), 1-epsilon)
+		if e.Defaulted {
+			total -= math.Log(p)
+		} else {
+			total -= math.Log(1 - p)
+		}
+	}
+	return total / float64(len(examples))
+}
+
+// SaveModel writes the model to path as JSON.
+func SaveModel(m LogisticModel, path string) error {
+	serialized, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(serialized, '\n'), 0644)
+}
+
+// LoadModel reads a model written by SaveModel.
+func LoadModel(path string) (LogisticModel, error) {
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return LogisticModel{}, err
+	}
+	var m LogisticModel
+	if err = json.Unmarshal(file, &m); err != nil {
+		return LogisticModel{}, fmt.Errorf("failed to parse model %s: %v", path, err)
+	}
+	if err = m.Validate(); err != nil {
+		return LogisticModel{}, fmt.Errorf("invalid model %s: %v", path, err)
+	}
+	return m, nil
+}
@@ -0,0 +1,109 @@
+package scoring
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+)
+
+// syntheticExamples gives loans with high debt-to-income ratios a high chance
+// of default, and loans with low ratios a low one.
+func syntheticExamples() []Example {
+	examples := []Example{}
+	for i := 0; i < 100; i++ {
+		dti := float64(i) / 100
+		examples = append(examples, Example{
+			Listing: prosper.Listing{
+				ListingNumber:   prosper.ListingNumber(i),
+				DtiWprosperLoan: dti,
+				ListingTerm:     36,
+			},
+			// Every fifth loan goes against the trend, so the classes overlap.
+			Defaulted: (dti > 0.5) != (i%5 == 0),
+		})
+	}
+	return examples
+}
+
+func TestTrain(t *testing.T) {
+	examples := syntheticExamples()
+	m, err := Train(examples, TrainOptions{Features: []string{"DtiWprosperLoan", "ListingTerm"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.Weights["DtiWprosperLoan"] <= 0 {
+		t.Errorf("higher debt-to-income should predict default. got weight %v", m.Weights["DtiWprosperLoan"])
+	}
+	if _, ok := m.Weights["ListingTerm"]; ok {
+		t.Errorf("constant feature should get no weight. got weights %+v", m.Weights)
+	}
+	low := m.Probability(prosper.Listing{DtiWprosperLoan: 0.1})
+	high := m.Probability(prosper.Listing{DtiWprosperLoan: 0.9})
+	if !(low < 0.5 && high > 0.5) {
+		t.Errorf("unexpected predictions. got %v for low DTI and %v for high DTI", low, high)
+	}
+	baseline := LogLoss(LogisticModel{}, examples)
+	if loss := LogLoss(m, examples); loss >= baseline {
+		t.Errorf("trained model should beat a coin flip. got log loss %v, baseline %v", loss, baseline)
+	}
+	if got := m.Score(prosper.Listing{DtiWprosperLoan: 0.1}); math.Abs(got-(1-low)) > 1e-12 {
+		t.Errorf("score should be the probability of repayment. got %v, want %v", got, 1-low)
+	}
+}
+
+func TestTrainErrors(t *testing.T) {
+	var tests = []struct {
+		examples []Example
+		opts     TrainOptions
+		want     error
+		msg      string
+	}{
+		{
+			examples: []Example{},
+			want:     ErrNoExamples,
+			msg:      "training without examples should fail",
+		},
+		{
+			examples: []Example{{Defaulted: true}, {Defaulted: true}},
+			want:     ErrOneClass,
+			msg:      "training on one outcome should fail",
+		},
+	}
+	for _, tt := range tests {
+		if _, err := Train(tt.examples, tt.opts); err != tt.want {
+			t.Errorf("%s: unexpected error. got = %v, want = %v", tt.msg, err, tt.want)
+		}
+	}
+	if _, err := Train(syntheticExamples(), TrainOptions{Features: []string{"Astrology"}}); err == nil {
+		t.Errorf("training on an unknown feature should fail")
+	}
+}
+
+func TestSaveAndLoadModel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scoring")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "model.json")
+	want := LogisticModel{Intercept: -2.5, Weights: map[string]float64{"DtiWprosperLoan": 3.0}}
+	if err = SaveModel(want, path); err != nil {
+		t.Fatalf("failed to save model: %v", err)
+	}
+	got, err := LoadModel(path)
+	if err != nil {
+		t.Fatalf("failed to load model: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected model. got = %+v, want = %+v", got, want)
+	}
+	ioutil.WriteFile(path, []byte(`{"Weights":{"Astrology":1}}`), 0644)
+	if _, err = LoadModel(path); err == nil {
+		t.Errorf("model with an unknown feature should fail to load")
+	}
+}
@@ -0,0 +1,91 @@
+// Package scoring rates listings so that the bot can bid on the most
+// promising ones first when it cannot afford them all.
+package scoring
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+)
+
+// Scorer rates a listing. Higher scores are better.
+type Scorer interface {
+	Score(l prosper.Listing) float64
+}
+
+// Features are the listing fields that a model can weigh, by name. Each
+// converts its field to a number.
+var Features = map[string]func(prosper.Listing) float64{
+	"EstimatedReturn":      func(l prosper.Listing) float64 { return l.EstimatedReturn },
+	"EstimatedLossRate":    func(l prosper.Listing) float64 { return l.EstimatedLossRate },
+	"LenderYield":          func(l prosper.Listing) float64 { return l.LenderYield },
+	"BorrowerRate":         func(l prosper.Listing) float64 { return l.BorrowerRate },
+	"ProsperRating":        func(l prosper.Listing) float64 { return float64(l.ProsperRating) },
+	"ListingAmount":        func(l prosper.Listing) float64 { return l.ListingAmount },
+	"ListingTerm":          func(l prosper.Listing) float64 { return float64(l.ListingTerm) },
+	"IncomeRange":          func(l prosper.Listing) float64 { return float64(l.IncomeRange) },
+	"StatedMonthlyIncome":  func(l prosper.Listing) float64 { return l.StatedMonthlyIncome },
+	"DtiWprosperLoan":      func(l prosper.Listing) float64 { return l.DtiWprosperLoan },
+	"PriorProsperLoans":    func(l prosper.Listing) float64 { return float64(l.PriorProsperLoans) },
+	"CurrentDelinquencies": func(l prosper.Listing) float64 { return float64(l.CurrentDelinquencies) },
+	"InquiriesLast6Months": func(l prosper.Listing) float64 { return float64(l.InquiriesLast6Months) },
+	"CreditScore":          creditScore,
+}
+
+// creditScore is the midpoint of the listing's credit score range, such as
+// "700-719", or zero if the range is missing or malformed.
+func creditScore(l prosper.Listing) float64 {
+	bounds := strings.Split(l.FicoScore, "-")
+	if len(bounds) != 2 {
+		return 0
+	}
+	lower, err := strconv.ParseFloat(strings.TrimSpace(bounds[0]), 64)
+	if err != nil {
+		return 0
+	}
+	upper, err := strconv.ParseFloat(strings.TrimSpace(bounds[1]), 64)
+	if err != nil {
+		return 0
+	}
+	return (lower + upper) / 2
+}
+
+// FeatureNames returns the names of the features, sorted.
+func FeatureNames() []string {
+	names := []string{}
+	for name := range Features {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LinearModel scores a listing as a weighted sum of its features.
+type LinearModel struct {
+	Intercept float64
+	// Weights maps feature names to their weight.
+	Weights map[string]float64
+}
+
+func (m LinearModel) Score(l prosper.Listing) float64 {
+	score := m.Intercept
+	for name, weight := range m.Weights {
+		if feature, ok := Features[name]; ok {
+			score += weight * feature(l)
+		}
+	}
+	return score
+}
+
+// Validate checks that the model only weighs known features.
+func (m LinearModel) Validate() error {
+	for name := range m.Weights {
+		if _, ok := Features[name]; !ok {
+			return fmt.Errorf("unknown scoring feature %q, must be one of: %s", name, strings.Join(FeatureNames(), ", "))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package scoring
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+)
+
+func TestLinearModelScore(t *testing.T) {
+	var tests = []struct {
+		model LinearModel
+		l     prosper.Listing
+		want  float64
+		msg   string
+	}{
+		{
+			model: LinearModel{Intercept: 1.5},
+			l:     prosper.Listing{EstimatedReturn: 0.08},
+			want:  1.5,
+			msg:   "a model without weights should score the intercept",
+		},
+		{
+			model: LinearModel{
+				Weights: map[string]float64{
+					"EstimatedReturn":      10.0,
+					"DtiWprosperLoan":      -2.0,
+					"InquiriesLast6Months": -0.1,
+				},
+			},
+			l:    prosper.Listing{EstimatedReturn: 0.08, DtiWprosperLoan: 0.25, InquiriesLast6Months: 2},
+			want: 0.8 - 0.5 - 0.2,
+			msg:  "score should be the weighted sum of features",
+		},
+		{
+			model: LinearModel{Weights: map[string]float64{"CreditScore": 0.01}},
+			l:     prosper.Listing{FicoScore: "700-719"},
+			want:  7.095,
+			msg:   "credit score should be the midpoint of the range",
+		},
+		{
+			model: LinearModel{Weights: map[string]float64{"CreditScore": 0.01}},
+			l:     prosper.Listing{FicoScore: "N/A"},
+			want:  0.0,
+			msg:   "malformed credit score ranges should count as zero",
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.model.Score(tt.l); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: unexpected score. got = %v, want = %v", tt.msg, got, tt.want)
+		}
+	}
+}
+
+func TestLinearModelValidate(t *testing.T) {
+	if err := (LinearModel{Weights: map[string]float64{"EstimatedReturn": 1.0}}).Validate(); err != nil {
+		t.Errorf("known feature should be valid, got: %v", err)
+	}
+	if err := (LinearModel{Weights: map[string]float64{"Astrology": 1.0}}).Validate(); err == nil {
+		t.Errorf("unknown feature should be invalid")
+	}
+}
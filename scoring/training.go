@@ -0,0 +1,54 @@
+package scoring
+
+import (
+	"sort"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/notes"
+	"github.com/mtlynch/prosperbot/redis"
+)
+
+// LoadExamples joins the saved notes to the listing snapshots they came from.
+// A note is an example once its loan has closed or it is at least
+// minAgeMonths old, so that young loans that have not had time to default do
+// not count as repaid. Notes without a saved listing are skipped.
+func LoadExamples(r redis.RedisReader, minAgeMonths int) ([]Example, error) {
+	histories, err := redis.AllNoteHistories(r)
+	if err != nil {
+		return nil, err
+	}
+	// A listing may back more than one note, and counts as defaulted if any of
+	// them did.
+	outcomes := map[prosper.ListingNumber]bool{}
+	for _, h := range histories {
+		if len(h) == 0 {
+			continue
+		}
+		n := h[len(h)-1].Note
+		lost := n.NoteStatusDescription == notes.StatusChargeOff || n.NoteStatusDescription == notes.StatusDefaulted
+		closed := lost || n.NoteStatusDescription == notes.StatusCompleted
+		if !closed && n.AgeInMonths < minAgeMonths {
+			continue
+		}
+		outcomes[n.ListingNumber] = outcomes[n.ListingNumber] || lost
+	}
+	numbers := []int{}
+	for number := range outcomes {
+		numbers = append(numbers, int(number))
+	}
+	// Sort so that training on the same history always gives the same model.
+	sort.Ints(numbers)
+	examples := []Example{}
+	for _, i := range numbers {
+		number := prosper.ListingNumber(i)
+		l, err := redis.Listing(r, number)
+		if err == redis.ErrListingNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		examples = append(examples, Example{Listing: l, Defaulted: outcomes[number]})
+	}
+	return examples, nil
+}
@@ -0,0 +1,51 @@
+package scoring
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/clock"
+	"github.com/mtlynch/prosperbot/redis"
+)
+
+func TestLoadExamples(t *testing.T) {
+	store := redis.NewMemoryStore(clock.DefaultClock{})
+	r := store.Client()
+	for _, n := range []prosper.ListingNumber{1, 2, 3, 4} {
+		serialized, _ := json.Marshal(prosper.Listing{ListingNumber: n})
+		r.Set(fmt.Sprintf("%s%d", redis.KeyPrefixListing, n), string(serialized))
+	}
+	notes := []prosper.Note{
+		{LoanNoteID: "1-1", ListingNumber: 1, AgeInMonths: 3, NoteStatusDescription: "CHARGEOFF"},
+		{LoanNoteID: "2-1", ListingNumber: 2, AgeInMonths: 20, NoteStatusDescription: "CURRENT"},
+		{LoanNoteID: "3-1", ListingNumber: 3, AgeInMonths: 4, NoteStatusDescription: "CURRENT"},
+		{LoanNoteID: "4-1", ListingNumber: 4, AgeInMonths: 30, NoteStatusDescription: "COMPLETED"},
+		{LoanNoteID: "4-2", ListingNumber: 4, AgeInMonths: 30, NoteStatusDescription: "DEFAULTED"},
+		{LoanNoteID: "5-1", ListingNumber: 5, AgeInMonths: 30, NoteStatusDescription: "DEFAULTED"},
+	}
+	for _, n := range notes {
+		serialized, err := redis.EncodeNoteRecord(redis.NoteRecord{Note: n, Timestamp: time.Now()})
+		if err != nil {
+			t.Fatalf("failed to encode note: %v", err)
+		}
+		r.LPush(redis.KeyPrefixNote+n.LoanNoteID, serialized)
+	}
+
+	got, err := LoadExamples(r, 12)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []Example{
+		{Listing: prosper.Listing{ListingNumber: 1}, Defaulted: true},
+		{Listing: prosper.Listing{ListingNumber: 2}, Defaulted: false},
+		{Listing: prosper.Listing{ListingNumber: 4}, Defaulted: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected examples. got = %+v, want = %+v", got, want)
+	}
+}
@@ -0,0 +1,213 @@
+package sim
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/buyer"
+	"github.com/mtlynch/prosperbot/clock"
+)
+
+// market is an in-process stand-in for the Prosper API that serves a fixed
+// set of synthetic listings as their start times arrive on the clock. Every
+// bid succeeds as soon as its order status is queried.
+type market struct {
+	mu       sync.Mutex
+	searched *sync.Cond
+	// searches counts the searches that have started.
+	searches int
+	clock    clock.Clock
+	listings []prosper.Listing
+	served   map[prosper.ListingNumber]bool
+	bidOn    map[prosper.ListingNumber]bool
+	orders   map[prosper.OrderID]prosper.OrderResponse
+	notes    []prosper.Note
+	cash     float64
+	invested float64
+}
+
+type byStartDate []prosper.Listing
+
+func (l byStartDate) Len() int           { return len(l) }
+func (l byStartDate) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+func (l byStartDate) Less(i, j int) bool { return l[i].ListingStartDate.Before(l[j].ListingStartDate) }
+
+func newMarket(c clock.Clock, listings []prosper.Listing, cash float64) *market {
+	m := &market{
+		clock:    c,
+		listings: listings,
+		served:   map[prosper.ListingNumber]bool{},
+		bidOn:    map[prosper.ListingNumber]bool{},
+		orders:   map[prosper.OrderID]prosper.OrderResponse{},
+		cash:     cash,
+	}
+	m.searched = sync.NewCond(&m.mu)
+	return m
+}
+
+// waitForSearches blocks until at least n searches have started.
+func (m *market) waitForSearches(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for m.searches < n {
+		m.searched.Wait()
+	}
+}
+
+// syntheticListings generates n listings that start at random times within d
+// of start. Their attributes vary enough that some fail the bot's search or
+// client-side filters.
+func syntheticListings(r *rand.Rand, start time.Time, d time.Duration, n int) []prosper.Listing {
+	ratings := []prosper.Rating{prosper.RatingAA, prosper.RatingA, prosper.RatingB, prosper.RatingC, prosper.RatingD, prosper.RatingE, prosper.RatingHR}
+	employment := []string{"Employed", "Employed", "Employed", "Self-employed", "Unemployed", "Not Available"}
+	listings := []prosper.Listing{}
+	for i := 0; i < n; i++ {
+		listings = append(listings, prosper.Listing{
+			ListingNumber:               prosper.ListingNumber(1000000 + i),
+			ListingStartDate:            start.Add(time.Duration(r.Int63n(int64(d)))),
+			ListingStatus:               prosper.ListingActive,
+			ListingAmount:               float64(2000 + 1000*r.Intn(34)),
+			ProsperRating:               ratings[r.Intn(len(ratings))],
+			EstimatedReturn:             0.04 + 0.1*r.Float64(),
+			ListingTerm:                 36,
+			IncomeRange:                 prosper.IncomeRange(2 + r.Intn(5)),
+			DtiWprosperLoan:             0.5 * r.Float64(),
+			EmploymentStatusDescription: employment[r.Intn(len(employment))],
+			CurrentDelinquencies:        int64(r.Intn(3) / 2),
+			InquiriesLast6Months:        int64(r.Intn(6)),
+		})
+	}
+	sort.Sort(byStartDate(listings))
+	return listings
+}
+
+func (m *market) Search(p prosper.SearchParams) (prosper.SearchResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if p.Offset == 0 {
+		m.searches++
+		m.searched.Broadcast()
+	}
+	now := m.clock.Now()
+	matching := []prosper.Listing{}
+	for _, l := range m.listings {
+		if l.ListingStartDate.After(now) {
+			break
+		}
+		if min := p.Filter.ListingStartDate.Min; min != nil && l.ListingStartDate.Before(*min) {
+			continue
+		}
+		if p.ExcludeListingsInvested && m.bidOn[l.ListingNumber] {
+			continue
+		}
+		if !buyer.MatchesSearchFilter(p.Filter, l) {
+			continue
+		}
+		matching = append(matching, l)
+	}
+	offset := p.Offset
+	if offset > len(matching) {
+		offset = len(matching)
+	}
+	end := len(matching)
+	if p.Limit > 0 && offset+p.Limit < end {
+		end = offset + p.Limit
+	}
+	results := matching[offset:end]
+	for _, l := range results {
+		m.served[l.ListingNumber] = true
+	}
+	return prosper.SearchResponse{
+		Results:     results,
+		ResultCount: len(results),
+		TotalCount:  len(matching),
+	}, nil
+}
+
+func (m *market) PlaceBid(b prosper.BidRequest) (prosper.OrderResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if b.BidAmount > m.cash {
+		return prosper.OrderResponse{}, fmt.Errorf("insufficient funds for bid of %.2f", b.BidAmount)
+	}
+	m.cash -= b.BidAmount
+	m.bidOn[b.ListingID] = true
+	o := prosper.OrderResponse{
+		OrderID:     prosper.OrderID(fmt.Sprintf("sim-order-%d", len(m.orders)+1)),
+		BidStatus:   []prosper.BidStatus{{BidRequest: b}},
+		OrderStatus: prosper.OrderInProgress,
+		OrderDate:   m.clock.Now(),
+	}
+	m.orders[o.OrderID] = o
+	return o, nil
+}
+
+// OrderStatus completes an order the first time it is queried and issues a
+// note for each of its bids.
+func (m *market) OrderStatus(id prosper.OrderID) (prosper.OrderResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	o, ok := m.orders[id]
+	if !ok {
+		return prosper.OrderResponse{}, fmt.Errorf("unknown order: %s", id)
+	}
+	if o.OrderStatus == prosper.OrderCompleted {
+		return o, nil
+	}
+	bids := []prosper.BidStatus{}
+	for _, b := range o.BidStatus {
+		b.Result = prosper.BidSucceeded
+		b.BidAmountPlaced = b.BidAmount
+		bids = append(bids, b)
+		m.invested += b.BidAmount
+		m.notes = append(m.notes, prosper.Note{
+			LoanNoteID:                   fmt.Sprintf("%d-1", b.ListingID),
+			ListingNumber:                b.ListingID,
+			NoteOwnershipAmount:          b.BidAmount,
+			PrincipalBalanceProRataShare: b.BidAmount,
+			NoteStatus:                   1,
+			NoteStatusDescription:        "CURRENT",
+			OriginationDate:              m.clock.Now(),
+			Term:                         36,
+		})
+	}
+	o.BidStatus = bids
+	o.OrderStatus = prosper.OrderCompleted
+	m.orders[id] = o
+	return o, nil
+}
+
+func (m *market) Account(prosper.AccountParams) (prosper.AccountInformation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return prosper.AccountInformation{
+		AvailableCashBalance:              m.cash,
+		OutstandingPrincipalOnActiveNotes: m.invested,
+		TotalAmountInvestedOnActiveNotes:  m.invested,
+		TotalAccountValue:                 m.cash + m.invested,
+	}, nil
+}
+
+func (m *market) Notes(p prosper.NotesParams) (prosper.NotesResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	offset := p.Offset
+	if offset > len(m.notes) {
+		offset = len(m.notes)
+	}
+	end := len(m.notes)
+	if p.Limit > 0 && offset+p.Limit < end {
+		end = offset + p.Limit
+	}
+	result := append([]prosper.Note{}, m.notes[offset:end]...)
+	return prosper.NotesResponse{
+		Result:      result,
+		ResultCount: len(result),
+		TotalCount:  len(m.notes),
+	}, nil
+}
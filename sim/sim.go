@@ -0,0 +1,185 @@
+// Package sim drives synthetic listings through the bot on a virtual clock, so
+// that a day of polling, bidding and order tracking runs in milliseconds.
+package sim
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/mtlynch/prosperbot/account"
+	"github.com/mtlynch/prosperbot/buyer"
+	"github.com/mtlynch/prosperbot/clock"
+	"github.com/mtlynch/prosperbot/notes"
+	"github.com/mtlynch/prosperbot/redis"
+)
+
+const (
+	defaultDuration       = 24 * time.Hour
+	defaultListingsPerDay = 500
+	// defaultPollInterval matches the minute of listings that each poll looks
+	// back over, so no listing is missed.
+	defaultPollInterval = time.Minute
+	defaultCash         = 100000.0
+	accountInterval     = time.Hour
+	noteInterval        = time.Hour
+	// pollers is how many goroutines sleep on the clock between steps: the
+	// listing, account and note pollers.
+	pollers = 3
+	// settleTimeout bounds the real time allowed for the bot to finish
+	// handling the listings it was served.
+	settleTimeout = 30 * time.Second
+)
+
+var ErrNotSettled = errors.New("bot did not finish handling listings in time")
+
+// Config describes a simulation. Zero values are replaced with defaults.
+type Config struct {
+	Start time.Time
+	// Duration is how much virtual time to simulate. Defaults to a day.
+	Duration time.Duration
+	// ListingsPerDay is how many synthetic listings start per day.
+	ListingsPerDay int
+	// PollInterval is how often the bot searches for listings. It must be at
+	// most a minute, or listings are missed.
+	PollInterval time.Duration
+	// Seed makes the synthetic listings reproducible.
+	Seed int64
+}
+
+// Result summarizes what the bot did during a simulation.
+type Result struct {
+	Start           time.Time
+	End             time.Time
+	ListingsOffered int
+	// ListingsSeen counts the listings the bot found through its search.
+	ListingsSeen int
+	// ListingsPassed counts the listings that passed the client-side filter.
+	ListingsPassed int
+	BidsPlaced     int
+	AmountInvested float64
+}
+
+func (c Config) withDefaults() Config {
+	if c.Start.IsZero() {
+		c.Start = time.Date(2016, 6, 1, 0, 0, 0, 0, time.UTC)
+	}
+	if c.Duration == 0 {
+		c.Duration = defaultDuration
+	}
+	if c.ListingsPerDay == 0 {
+		c.ListingsPerDay = defaultListingsPerDay
+	}
+	if c.PollInterval == 0 {
+		c.PollInterval = defaultPollInterval
+	}
+	return c
+}
+
+// Run simulates the bot with buying enabled against a market of synthetic
+// listings. It replaces the Redis store with an in-memory one while it runs,
+// so a simulation waits for any other simulation or rerun to finish first. The
+// bot's goroutines are left blocked on the virtual clock when Run returns.
+func Run(c Config) (Result, error) {
+	c = c.withDefaults()
+	if c.PollInterval > time.Minute {
+		return Result{}, errors.New("poll interval must be at most a minute")
+	}
+	v := clock.NewVirtual(c.Start)
+	store := redis.NewMemoryStore(v)
+	defer redis.UseMemoryStore(store)()
+
+	count := int(int64(c.ListingsPerDay) * int64(c.Duration) / int64(24*time.Hour))
+	listings := syntheticListings(rand.New(rand.NewSource(c.Seed)), c.Start, c.Duration, count)
+	m := newMarket(v, listings, defaultCash)
+
+	if err := buyer.Poll(c.PollInterval, buyer.DefaultSearchFilter(), true, m, v, buyer.Policy{}); err != nil {
+		return Result{}, err
+	}
+	if err := account.Poll(accountInterval, m, v); err != nil {
+		return Result{}, err
+	}
+	if err := notes.Poll(noteInterval, m, nil, v); err != nil {
+		return Result{}, err
+	}
+	// Each step waits for the listing poller to search, so that every search
+	// looks back from the time of its own step.
+	v.BlockUntil(pollers)
+	m.waitForSearches(1)
+	steps := 1
+	for elapsed := time.Duration(0); elapsed < c.Duration; elapsed += c.PollInterval {
+		v.Advance(c.PollInterval)
+		steps++
+		m.waitForSearches(steps)
+		v.BlockUntil(pollers)
+	}
+
+	r := store.Client()
+	deadline := time.Now().Add(settleTimeout)
+	for {
+		result, settled, err := summarize(r, m)
+		if err != nil {
+			return Result{}, err
+		}
+		if settled {
+			result.Start = c.Start
+			result.End = v.Now()
+			result.ListingsOffered = len(listings)
+			return result, nil
+		}
+		if time.Now().After(deadline) {
+			return result, ErrNotSettled
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// summarize tallies the bot's decisions from its lineage records. The bot has
+// settled once it has decided on every listing it was served and saved an
+// order for every listing it bid on.
+func summarize(r redis.Client, m *market) (Result, bool, error) {
+	m.mu.Lock()
+	served := len(m.served)
+	bids := len(m.bidOn)
+	invested := m.invested
+	m.mu.Unlock()
+	result := Result{ListingsSeen: served, BidsPlaced: bids, AmountInvested: invested}
+
+	keys, err := r.Keys(redis.KeyPrefixLineage + "*")
+	if err != nil {
+		return result, false, err
+	}
+	ordered := 0
+	for _, k := range keys {
+		fields, err := r.HGetAll(k)
+		if err != nil {
+			return result, false, err
+		}
+		record := map[string]string{}
+		for i := 0; i+1 < len(fields); i += 2 {
+			record[fields[i]] = fields[i+1]
+		}
+		serialized, ok := record[redis.LineageFieldDecision]
+		if !ok {
+			return result, false, nil
+		}
+		var decision redis.Decision
+		if err = json.Unmarshal([]byte(serialized), &decision); err != nil {
+			return result, false, err
+		}
+		if !decision.Passed {
+			continue
+		}
+		result.ListingsPassed++
+		if _, ok := record[redis.LineageFieldOrder]; ok {
+			ordered++
+		}
+	}
+	orders, err := r.Keys(redis.KeyPrefixOrders + "*")
+	if err != nil {
+		return result, false, err
+	}
+	settled := len(keys) == served && ordered == result.ListingsPassed && len(orders) == bids && bids == ordered
+	return result, settled, nil
+}
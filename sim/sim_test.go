@@ -0,0 +1,58 @@
+package sim
+
+import (
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mtlynch/prosperbot/buyer"
+)
+
+func TestRunSimulatesADay(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	c := Config{Seed: 42}.withDefaults()
+	got, err := Run(c)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Work out independently what the bot should have done with the same
+	// listings.
+	listings := syntheticListings(rand.New(rand.NewSource(c.Seed)), c.Start, c.Duration, c.ListingsPerDay)
+	wantSeen, wantPassed := 0, 0
+	for _, l := range listings {
+		if !buyer.MatchesSearchFilter(buyer.DefaultSearchFilter(), l) {
+			continue
+		}
+		wantSeen++
+		if buyer.DefaultClientSideFilter().Filter(l) {
+			wantPassed++
+		}
+	}
+	if wantPassed == 0 || wantPassed == wantSeen {
+		t.Fatalf("synthetic listings should exercise both filters. seen: %d, passed: %d", wantSeen, wantPassed)
+	}
+	want := Result{
+		Start:           c.Start,
+		End:             c.Start.Add(24 * time.Hour),
+		ListingsOffered: c.ListingsPerDay,
+		ListingsSeen:    wantSeen,
+		ListingsPassed:  wantPassed,
+		BidsPlaced:      wantPassed,
+		AmountInvested:  25.0 * float64(wantPassed),
+	}
+	if got != want {
+		t.Errorf("unexpected simulation result. got: %+v, want: %+v", got, want)
+	}
+}
+
+func TestRunRejectsLongPollInterval(t *testing.T) {
+	if _, err := Run(Config{PollInterval: 2 * time.Minute}); err == nil {
+		t.Errorf("expected error for a poll interval longer than the listing lookback")
+	}
+}
@@ -0,0 +1,99 @@
+package tax
+
+import (
+	"sort"
+
+	"github.com/mtlynch/prosperbot/notes"
+	"github.com/mtlynch/prosperbot/redis"
+)
+
+// Income is the taxable activity on notes during a year.
+type Income struct {
+	Interest         float64
+	LateFees         float64
+	ServiceFees      float64
+	PrincipalLost    float64
+	DebtSaleProceeds float64
+}
+
+func (i *Income) add(o Income) {
+	i.Interest += o.Interest
+	i.LateFees += o.LateFees
+	i.ServiceFees += o.ServiceFees
+	i.PrincipalLost += o.PrincipalLost
+	i.DebtSaleProceeds += o.DebtSaleProceeds
+}
+
+func (i Income) isZero() bool {
+	return i == Income{}
+}
+
+// NoteIncome is the income from a single note.
+type NoteIncome struct {
+	LoanNoteID string
+	Income
+}
+
+// Report is the income from every note over a calendar year.
+type Report struct {
+	Year  int
+	Notes []NoteIncome
+	Total Income
+}
+
+func isLost(status string) bool {
+	return status == notes.StatusChargeOff || status == notes.StatusDefaulted
+}
+
+// noteIncome totals the changes between successive snapshots of a note that
+// were saved during year. Principal is counted as lost in the year the note
+// was charged off or defaulted. The amounts in a note's first snapshot were
+// paid at unknown times before tracking began, so they are never counted.
+func noteIncome(history []redis.NoteRecord, year int) Income {
+	var income Income
+	for i := 1; i < len(history); i++ {
+		prev, cur := history[i-1].Note, history[i].Note
+		if history[i].Timestamp.Year() != year {
+			continue
+		}
+		income.Interest += cur.InterestPaidProRataShare - prev.InterestPaidProRataShare
+		income.LateFees += cur.LateFeesPaidProRataShare - prev.LateFeesPaidProRataShare
+		income.ServiceFees += cur.ServiceFeesPaidProRataShare - prev.ServiceFeesPaidProRataShare
+		income.DebtSaleProceeds += cur.DebtSaleProceedsReceivedProRataShare - prev.DebtSaleProceedsReceivedProRataShare
+		if isLost(cur.NoteStatusDescription) && !isLost(prev.NoteStatusDescription) {
+			income.PrincipalLost += cur.NoteOwnershipAmount - cur.PrincipalPaidProRataShare
+		}
+	}
+	return income
+}
+
+// Build creates the income report for a year from the history of every note.
+func Build(histories map[string][]redis.NoteRecord, year int) Report {
+	report := Report{
+		Year:  year,
+		Notes: []NoteIncome{},
+	}
+	ids := []string{}
+	for id := range histories {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		income := noteIncome(histories[id], year)
+		if income.isZero() {
+			continue
+		}
+		report.Notes = append(report.Notes, NoteIncome{LoanNoteID: id, Income: income})
+		report.Total.add(income)
+	}
+	return report
+}
+
+// Load creates the income report for a year from saved note history.
+func Load(r redis.RedisReader, year int) (Report, error) {
+	histories, err := redis.AllNoteHistories(r)
+	if err != nil {
+		return Report{}, err
+	}
+	return Build(histories, year), nil
+}
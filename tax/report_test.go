@@ -0,0 +1,91 @@
+package tax
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mtlynch/gofn-prosper/prosper"
+
+	"github.com/mtlynch/prosperbot/redis"
+)
+
+var histories = map[string][]redis.NoteRecord{
+	"1619-2": {
+		{
+			Note:      prosper.Note{LoanNoteID: "1619-2", NoteOwnershipAmount: 100, InterestPaidProRataShare: 5, ServiceFeesPaidProRataShare: 0.5, NoteStatusDescription: "CURRENT"},
+			Timestamp: time.Date(2015, 12, 15, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			Note:      prosper.Note{LoanNoteID: "1619-2", NoteOwnershipAmount: 100, InterestPaidProRataShare: 6, LateFeesPaidProRataShare: 0.25, ServiceFeesPaidProRataShare: 0.75, NoteStatusDescription: "CURRENT"},
+			Timestamp: time.Date(2016, 1, 15, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			Note:      prosper.Note{LoanNoteID: "1619-2", NoteOwnershipAmount: 100, InterestPaidProRataShare: 7, LateFeesPaidProRataShare: 0.25, ServiceFeesPaidProRataShare: 1, NoteStatusDescription: "CURRENT"},
+			Timestamp: time.Date(2016, 2, 15, 0, 0, 0, 0, time.UTC),
+		},
+	},
+	"2000-1": {
+		{
+			Note:      prosper.Note{LoanNoteID: "2000-1", NoteOwnershipAmount: 50, PrincipalPaidProRataShare: 10, InterestPaidProRataShare: 2, NoteStatusDescription: "CURRENT"},
+			Timestamp: time.Date(2016, 3, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			Note:      prosper.Note{LoanNoteID: "2000-1", NoteOwnershipAmount: 50, PrincipalPaidProRataShare: 10, InterestPaidProRataShare: 2, NoteStatusDescription: "CHARGEOFF"},
+			Timestamp: time.Date(2016, 8, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			Note:      prosper.Note{LoanNoteID: "2000-1", NoteOwnershipAmount: 50, PrincipalPaidProRataShare: 10, InterestPaidProRataShare: 2, DebtSaleProceedsReceivedProRataShare: 4, NoteStatusDescription: "CHARGEOFF"},
+			Timestamp: time.Date(2017, 1, 10, 0, 0, 0, 0, time.UTC),
+		},
+	},
+}
+
+func TestBuild(t *testing.T) {
+	var tests = []struct {
+		year int
+		want Report
+	}{
+		{
+			year: 2015,
+			want: Report{
+				Year:  2015,
+				Notes: []NoteIncome{},
+			},
+		},
+		{
+			year: 2016,
+			want: Report{
+				Year: 2016,
+				Notes: []NoteIncome{
+					{LoanNoteID: "1619-2", Income: Income{Interest: 2, LateFees: 0.25, ServiceFees: 0.5}},
+					{LoanNoteID: "2000-1", Income: Income{PrincipalLost: 40}},
+				},
+				Total: Income{Interest: 2, LateFees: 0.25, ServiceFees: 0.5, PrincipalLost: 40},
+			},
+		},
+		{
+			year: 2017,
+			want: Report{
+				Year: 2017,
+				Notes: []NoteIncome{
+					{LoanNoteID: "2000-1", Income: Income{DebtSaleProceeds: 4}},
+				},
+				Total: Income{DebtSaleProceeds: 4},
+			},
+		},
+		{
+			year: 2018,
+			want: Report{
+				Year:  2018,
+				Notes: []NoteIncome{},
+			},
+		},
+	}
+	for _, tt := range tests {
+		got := Build(histories, tt.year)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("unexpected report for %d. got: %+v, want: %+v", tt.year, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package tax
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+var csvHeader = []string{"LoanNoteID", "Interest", "LateFees", "ServiceFees", "PrincipalLost", "DebtSaleProceeds"}
+
+func csvRow(id string, i Income) []string {
+	return []string{
+		id,
+		fmt.Sprintf("%.2f", i.Interest),
+		fmt.Sprintf("%.2f", i.LateFees),
+		fmt.Sprintf("%.2f", i.ServiceFees),
+		fmt.Sprintf("%.2f", i.PrincipalLost),
+		fmt.Sprintf("%.2f", i.DebtSaleProceeds),
+	}
+}
+
+// WriteCSV writes one row per note, followed by a row of totals.
+func WriteCSV(w io.Writer, r Report) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, n := range r.Notes {
+		if err := cw.Write(csvRow(n.LoanNoteID, n.Income)); err != nil {
+			return err
+		}
+	}
+	if err := cw.Write(csvRow("TOTAL", r.Total)); err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func WriteJSON(w io.Writer, r Report) error {
+	serialized, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(serialized, '\n'))
+	return err
+}
+
+// Write writes the report in the given format, either "csv" or "json".
+func Write(w io.Writer, r Report, format string) error {
+	switch format {
+	case "csv":
+		return WriteCSV(w, r)
+	case "json":
+		return WriteJSON(w, r)
+	}
+	return fmt.Errorf("unrecognized report format: %s", format)
+}
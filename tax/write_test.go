@@ -0,0 +1,59 @@
+package tax
+
+import (
+	"bytes"
+	"testing"
+)
+
+var report2016 = Report{
+	Year: 2016,
+	Notes: []NoteIncome{
+		{LoanNoteID: "1619-2", Income: Income{Interest: 2, LateFees: 0.25, ServiceFees: 0.5}},
+		{LoanNoteID: "2000-1", Income: Income{Interest: 2, PrincipalLost: 40}},
+	},
+	Total: Income{Interest: 4, LateFees: 0.25, ServiceFees: 0.5, PrincipalLost: 40},
+}
+
+func TestWriteCSV(t *testing.T) {
+	var b bytes.Buffer
+	if err := Write(&b, report2016, "csv"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `LoanNoteID,Interest,LateFees,ServiceFees,PrincipalLost,DebtSaleProceeds
+1619-2,2.00,0.25,0.50,0.00,0.00
+2000-1,2.00,0.00,0.00,40.00,0.00
+TOTAL,4.00,0.25,0.50,40.00,0.00
+`
+	if b.String() != want {
+		t.Errorf("unexpected CSV. got: %s, want: %s", b.String(), want)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var b bytes.Buffer
+	if err := Write(&b, Report{Year: 2018, Notes: []NoteIncome{}}, "json"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `{
+  "Year": 2018,
+  "Notes": [],
+  "Total": {
+    "Interest": 0,
+    "LateFees": 0,
+    "ServiceFees": 0,
+    "PrincipalLost": 0,
+    "DebtSaleProceeds": 0
+  }
+}
+`
+	if b.String() != want {
+		t.Errorf("unexpected JSON. got: %s, want: %s", b.String(), want)
+	}
+}
+
+func TestWriteUnknownFormat(t *testing.T) {
+	var b bytes.Buffer
+	if err := Write(&b, report2016, "xlsx"); err == nil {
+		t.Errorf("expected error for unknown format")
+	}
+}